@@ -0,0 +1,870 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: netflowstats/v1/netflow_stats.proto
+
+// NetflowStats exposes ipt_NETFLOW statistics parsed from the
+// ipt_netflow_snmp stat file.
+
+package netflowstatsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{0}
+}
+
+type WatchStatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Interval between snapshots, from 1s to 10m. Defaults to 5s.
+	Interval      *durationpb.Duration `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchStatsRequest) Reset() {
+	*x = WatchStatsRequest{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatsRequest) ProtoMessage() {}
+
+func (x *WatchStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatsRequest.ProtoReflect.Descriptor instead.
+func (*WatchStatsRequest) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *WatchStatsRequest) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+type ListSocketsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSocketsRequest) Reset() {
+	*x = ListSocketsRequest{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSocketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSocketsRequest) ProtoMessage() {}
+
+func (x *ListSocketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSocketsRequest.ProtoReflect.Descriptor instead.
+func (*ListSocketsRequest) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{2}
+}
+
+type ListSocketsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sockets       []*NFSockEntry         `protobuf:"bytes,1,rep,name=sockets,proto3" json:"sockets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSocketsResponse) Reset() {
+	*x = ListSocketsResponse{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSocketsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSocketsResponse) ProtoMessage() {}
+
+func (x *ListSocketsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSocketsResponse.ProtoReflect.Descriptor instead.
+func (*ListSocketsResponse) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListSocketsResponse) GetSockets() []*NFSockEntry {
+	if x != nil {
+		return x.Sockets
+	}
+	return nil
+}
+
+type StatsSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ReadTime      *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=read_time,json=readTime,proto3" json:"read_time,omitempty"`
+	Statistics    *Statistics            `protobuf:"bytes,2,opt,name=statistics,proto3" json:"statistics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsSnapshot) Reset() {
+	*x = StatsSnapshot{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsSnapshot) ProtoMessage() {}
+
+func (x *StatsSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsSnapshot.ProtoReflect.Descriptor instead.
+func (*StatsSnapshot) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StatsSnapshot) GetReadTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReadTime
+	}
+	return nil
+}
+
+func (x *StatsSnapshot) GetStatistics() *Statistics {
+	if x != nil {
+		return x.Statistics
+	}
+	return nil
+}
+
+// Statistics mirrors the global metrics of the stat file.
+type Statistics struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InBitRate     uint64                 `protobuf:"varint,1,opt,name=in_bit_rate,json=inBitRate,proto3" json:"in_bit_rate,omitempty"`
+	InPacketRate  uint64                 `protobuf:"varint,2,opt,name=in_packet_rate,json=inPacketRate,proto3" json:"in_packet_rate,omitempty"`
+	InFlows       uint64                 `protobuf:"varint,3,opt,name=in_flows,json=inFlows,proto3" json:"in_flows,omitempty"`
+	InPackets     uint64                 `protobuf:"varint,4,opt,name=in_packets,json=inPackets,proto3" json:"in_packets,omitempty"`
+	InBytes       uint64                 `protobuf:"varint,5,opt,name=in_bytes,json=inBytes,proto3" json:"in_bytes,omitempty"`
+	HashMetric    float64                `protobuf:"fixed64,6,opt,name=hash_metric,json=hashMetric,proto3" json:"hash_metric,omitempty"`
+	HashMemory    uint64                 `protobuf:"varint,7,opt,name=hash_memory,json=hashMemory,proto3" json:"hash_memory,omitempty"`
+	HashFlows     uint64                 `protobuf:"varint,8,opt,name=hash_flows,json=hashFlows,proto3" json:"hash_flows,omitempty"`
+	HashPackets   uint64                 `protobuf:"varint,9,opt,name=hash_packets,json=hashPackets,proto3" json:"hash_packets,omitempty"`
+	HashBytes     uint64                 `protobuf:"varint,10,opt,name=hash_bytes,json=hashBytes,proto3" json:"hash_bytes,omitempty"`
+	DropPackets   uint64                 `protobuf:"varint,11,opt,name=drop_packets,json=dropPackets,proto3" json:"drop_packets,omitempty"`
+	DropBytes     uint64                 `protobuf:"varint,12,opt,name=drop_bytes,json=dropBytes,proto3" json:"drop_bytes,omitempty"`
+	OutByteRate   uint64                 `protobuf:"varint,13,opt,name=out_byte_rate,json=outByteRate,proto3" json:"out_byte_rate,omitempty"`
+	OutFlows      uint64                 `protobuf:"varint,14,opt,name=out_flows,json=outFlows,proto3" json:"out_flows,omitempty"`
+	OutPackets    uint64                 `protobuf:"varint,15,opt,name=out_packets,json=outPackets,proto3" json:"out_packets,omitempty"`
+	OutBytes      uint64                 `protobuf:"varint,16,opt,name=out_bytes,json=outBytes,proto3" json:"out_bytes,omitempty"`
+	LostFlows     uint64                 `protobuf:"varint,17,opt,name=lost_flows,json=lostFlows,proto3" json:"lost_flows,omitempty"`
+	LostPackets   uint64                 `protobuf:"varint,18,opt,name=lost_packets,json=lostPackets,proto3" json:"lost_packets,omitempty"`
+	LostBytes     uint64                 `protobuf:"varint,19,opt,name=lost_bytes,json=lostBytes,proto3" json:"lost_bytes,omitempty"`
+	ErrTotal      uint64                 `protobuf:"varint,20,opt,name=err_total,json=errTotal,proto3" json:"err_total,omitempty"`
+	SndbufPeak    uint64                 `protobuf:"varint,21,opt,name=sndbuf_peak,json=sndbufPeak,proto3" json:"sndbuf_peak,omitempty"`
+	Cpus          []*CPUStat             `protobuf:"bytes,22,rep,name=cpus,proto3" json:"cpus,omitempty"`
+	Sockets       []*NFSockEntry         `protobuf:"bytes,23,rep,name=sockets,proto3" json:"sockets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Statistics) Reset() {
+	*x = Statistics{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Statistics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Statistics) ProtoMessage() {}
+
+func (x *Statistics) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Statistics.ProtoReflect.Descriptor instead.
+func (*Statistics) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Statistics) GetInBitRate() uint64 {
+	if x != nil {
+		return x.InBitRate
+	}
+	return 0
+}
+
+func (x *Statistics) GetInPacketRate() uint64 {
+	if x != nil {
+		return x.InPacketRate
+	}
+	return 0
+}
+
+func (x *Statistics) GetInFlows() uint64 {
+	if x != nil {
+		return x.InFlows
+	}
+	return 0
+}
+
+func (x *Statistics) GetInPackets() uint64 {
+	if x != nil {
+		return x.InPackets
+	}
+	return 0
+}
+
+func (x *Statistics) GetInBytes() uint64 {
+	if x != nil {
+		return x.InBytes
+	}
+	return 0
+}
+
+func (x *Statistics) GetHashMetric() float64 {
+	if x != nil {
+		return x.HashMetric
+	}
+	return 0
+}
+
+func (x *Statistics) GetHashMemory() uint64 {
+	if x != nil {
+		return x.HashMemory
+	}
+	return 0
+}
+
+func (x *Statistics) GetHashFlows() uint64 {
+	if x != nil {
+		return x.HashFlows
+	}
+	return 0
+}
+
+func (x *Statistics) GetHashPackets() uint64 {
+	if x != nil {
+		return x.HashPackets
+	}
+	return 0
+}
+
+func (x *Statistics) GetHashBytes() uint64 {
+	if x != nil {
+		return x.HashBytes
+	}
+	return 0
+}
+
+func (x *Statistics) GetDropPackets() uint64 {
+	if x != nil {
+		return x.DropPackets
+	}
+	return 0
+}
+
+func (x *Statistics) GetDropBytes() uint64 {
+	if x != nil {
+		return x.DropBytes
+	}
+	return 0
+}
+
+func (x *Statistics) GetOutByteRate() uint64 {
+	if x != nil {
+		return x.OutByteRate
+	}
+	return 0
+}
+
+func (x *Statistics) GetOutFlows() uint64 {
+	if x != nil {
+		return x.OutFlows
+	}
+	return 0
+}
+
+func (x *Statistics) GetOutPackets() uint64 {
+	if x != nil {
+		return x.OutPackets
+	}
+	return 0
+}
+
+func (x *Statistics) GetOutBytes() uint64 {
+	if x != nil {
+		return x.OutBytes
+	}
+	return 0
+}
+
+func (x *Statistics) GetLostFlows() uint64 {
+	if x != nil {
+		return x.LostFlows
+	}
+	return 0
+}
+
+func (x *Statistics) GetLostPackets() uint64 {
+	if x != nil {
+		return x.LostPackets
+	}
+	return 0
+}
+
+func (x *Statistics) GetLostBytes() uint64 {
+	if x != nil {
+		return x.LostBytes
+	}
+	return 0
+}
+
+func (x *Statistics) GetErrTotal() uint64 {
+	if x != nil {
+		return x.ErrTotal
+	}
+	return 0
+}
+
+func (x *Statistics) GetSndbufPeak() uint64 {
+	if x != nil {
+		return x.SndbufPeak
+	}
+	return 0
+}
+
+func (x *Statistics) GetCpus() []*CPUStat {
+	if x != nil {
+		return x.Cpus
+	}
+	return nil
+}
+
+func (x *Statistics) GetSockets() []*NFSockEntry {
+	if x != nil {
+		return x.Sockets
+	}
+	return nil
+}
+
+// CPUStat mirrors a cpuN line of the stat file.
+type CPUStat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cpu           string                 `protobuf:"bytes,1,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	InPacketRate  uint64                 `protobuf:"varint,2,opt,name=in_packet_rate,json=inPacketRate,proto3" json:"in_packet_rate,omitempty"`
+	InFlows       uint64                 `protobuf:"varint,3,opt,name=in_flows,json=inFlows,proto3" json:"in_flows,omitempty"`
+	InPackets     uint64                 `protobuf:"varint,4,opt,name=in_packets,json=inPackets,proto3" json:"in_packets,omitempty"`
+	InBytes       uint64                 `protobuf:"varint,5,opt,name=in_bytes,json=inBytes,proto3" json:"in_bytes,omitempty"`
+	HashMetric    float64                `protobuf:"fixed64,6,opt,name=hash_metric,json=hashMetric,proto3" json:"hash_metric,omitempty"`
+	DropPackets   uint64                 `protobuf:"varint,7,opt,name=drop_packets,json=dropPackets,proto3" json:"drop_packets,omitempty"`
+	DropBytes     uint64                 `protobuf:"varint,8,opt,name=drop_bytes,json=dropBytes,proto3" json:"drop_bytes,omitempty"`
+	ErrTrunc      uint64                 `protobuf:"varint,9,opt,name=err_trunc,json=errTrunc,proto3" json:"err_trunc,omitempty"`
+	ErrFrag       uint64                 `protobuf:"varint,10,opt,name=err_frag,json=errFrag,proto3" json:"err_frag,omitempty"`
+	ErrAlloc      uint64                 `protobuf:"varint,11,opt,name=err_alloc,json=errAlloc,proto3" json:"err_alloc,omitempty"`
+	ErrMaxflows   uint64                 `protobuf:"varint,12,opt,name=err_maxflows,json=errMaxflows,proto3" json:"err_maxflows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CPUStat) Reset() {
+	*x = CPUStat{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CPUStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CPUStat) ProtoMessage() {}
+
+func (x *CPUStat) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CPUStat.ProtoReflect.Descriptor instead.
+func (*CPUStat) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CPUStat) GetCpu() string {
+	if x != nil {
+		return x.Cpu
+	}
+	return ""
+}
+
+func (x *CPUStat) GetInPacketRate() uint64 {
+	if x != nil {
+		return x.InPacketRate
+	}
+	return 0
+}
+
+func (x *CPUStat) GetInFlows() uint64 {
+	if x != nil {
+		return x.InFlows
+	}
+	return 0
+}
+
+func (x *CPUStat) GetInPackets() uint64 {
+	if x != nil {
+		return x.InPackets
+	}
+	return 0
+}
+
+func (x *CPUStat) GetInBytes() uint64 {
+	if x != nil {
+		return x.InBytes
+	}
+	return 0
+}
+
+func (x *CPUStat) GetHashMetric() float64 {
+	if x != nil {
+		return x.HashMetric
+	}
+	return 0
+}
+
+func (x *CPUStat) GetDropPackets() uint64 {
+	if x != nil {
+		return x.DropPackets
+	}
+	return 0
+}
+
+func (x *CPUStat) GetDropBytes() uint64 {
+	if x != nil {
+		return x.DropBytes
+	}
+	return 0
+}
+
+func (x *CPUStat) GetErrTrunc() uint64 {
+	if x != nil {
+		return x.ErrTrunc
+	}
+	return 0
+}
+
+func (x *CPUStat) GetErrFrag() uint64 {
+	if x != nil {
+		return x.ErrFrag
+	}
+	return 0
+}
+
+func (x *CPUStat) GetErrAlloc() uint64 {
+	if x != nil {
+		return x.ErrAlloc
+	}
+	return 0
+}
+
+func (x *CPUStat) GetErrMaxflows() uint64 {
+	if x != nil {
+		return x.ErrMaxflows
+	}
+	return 0
+}
+
+// NFSockEntry mirrors a sockN line of the stat file.
+type NFSockEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Destination   string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	Active        uint32                 `protobuf:"varint,3,opt,name=active,proto3" json:"active,omitempty"`
+	ErrConnect    uint32                 `protobuf:"varint,4,opt,name=err_connect,json=errConnect,proto3" json:"err_connect,omitempty"`
+	ErrFull       uint32                 `protobuf:"varint,5,opt,name=err_full,json=errFull,proto3" json:"err_full,omitempty"`
+	ErrCberr      uint32                 `protobuf:"varint,6,opt,name=err_cberr,json=errCberr,proto3" json:"err_cberr,omitempty"`
+	ErrOther      uint32                 `protobuf:"varint,7,opt,name=err_other,json=errOther,proto3" json:"err_other,omitempty"`
+	Sndbuf        uint32                 `protobuf:"varint,8,opt,name=sndbuf,proto3" json:"sndbuf,omitempty"`
+	SndbufFill    uint32                 `protobuf:"varint,9,opt,name=sndbuf_fill,json=sndbufFill,proto3" json:"sndbuf_fill,omitempty"`
+	SndbufPeak    uint32                 `protobuf:"varint,10,opt,name=sndbuf_peak,json=sndbufPeak,proto3" json:"sndbuf_peak,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NFSockEntry) Reset() {
+	*x = NFSockEntry{}
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NFSockEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NFSockEntry) ProtoMessage() {}
+
+func (x *NFSockEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_netflowstats_v1_netflow_stats_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NFSockEntry.ProtoReflect.Descriptor instead.
+func (*NFSockEntry) Descriptor() ([]byte, []int) {
+	return file_netflowstats_v1_netflow_stats_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *NFSockEntry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NFSockEntry) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *NFSockEntry) GetActive() uint32 {
+	if x != nil {
+		return x.Active
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetErrConnect() uint32 {
+	if x != nil {
+		return x.ErrConnect
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetErrFull() uint32 {
+	if x != nil {
+		return x.ErrFull
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetErrCberr() uint32 {
+	if x != nil {
+		return x.ErrCberr
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetErrOther() uint32 {
+	if x != nil {
+		return x.ErrOther
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetSndbuf() uint32 {
+	if x != nil {
+		return x.Sndbuf
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetSndbufFill() uint32 {
+	if x != nil {
+		return x.SndbufFill
+	}
+	return 0
+}
+
+func (x *NFSockEntry) GetSndbufPeak() uint32 {
+	if x != nil {
+		return x.SndbufPeak
+	}
+	return 0
+}
+
+var File_netflowstats_v1_netflow_stats_proto protoreflect.FileDescriptor
+
+const file_netflowstats_v1_netflow_stats_proto_rawDesc = "" +
+	"\n" +
+	"#netflowstats/v1/netflow_stats.proto\x12\x0fnetflowstats.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x11\n" +
+	"\x0fGetStatsRequest\"J\n" +
+	"\x11WatchStatsRequest\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\"\x14\n" +
+	"\x12ListSocketsRequest\"M\n" +
+	"\x13ListSocketsResponse\x126\n" +
+	"\asockets\x18\x01 \x03(\v2\x1c.netflowstats.v1.NFSockEntryR\asockets\"\x85\x01\n" +
+	"\rStatsSnapshot\x127\n" +
+	"\tread_time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\breadTime\x12;\n" +
+	"\n" +
+	"statistics\x18\x02 \x01(\v2\x1b.netflowstats.v1.StatisticsR\n" +
+	"statistics\"\x90\x06\n" +
+	"\n" +
+	"Statistics\x12\x1e\n" +
+	"\vin_bit_rate\x18\x01 \x01(\x04R\tinBitRate\x12$\n" +
+	"\x0ein_packet_rate\x18\x02 \x01(\x04R\finPacketRate\x12\x19\n" +
+	"\bin_flows\x18\x03 \x01(\x04R\ainFlows\x12\x1d\n" +
+	"\n" +
+	"in_packets\x18\x04 \x01(\x04R\tinPackets\x12\x19\n" +
+	"\bin_bytes\x18\x05 \x01(\x04R\ainBytes\x12\x1f\n" +
+	"\vhash_metric\x18\x06 \x01(\x01R\n" +
+	"hashMetric\x12\x1f\n" +
+	"\vhash_memory\x18\a \x01(\x04R\n" +
+	"hashMemory\x12\x1d\n" +
+	"\n" +
+	"hash_flows\x18\b \x01(\x04R\thashFlows\x12!\n" +
+	"\fhash_packets\x18\t \x01(\x04R\vhashPackets\x12\x1d\n" +
+	"\n" +
+	"hash_bytes\x18\n" +
+	" \x01(\x04R\thashBytes\x12!\n" +
+	"\fdrop_packets\x18\v \x01(\x04R\vdropPackets\x12\x1d\n" +
+	"\n" +
+	"drop_bytes\x18\f \x01(\x04R\tdropBytes\x12\"\n" +
+	"\rout_byte_rate\x18\r \x01(\x04R\voutByteRate\x12\x1b\n" +
+	"\tout_flows\x18\x0e \x01(\x04R\boutFlows\x12\x1f\n" +
+	"\vout_packets\x18\x0f \x01(\x04R\n" +
+	"outPackets\x12\x1b\n" +
+	"\tout_bytes\x18\x10 \x01(\x04R\boutBytes\x12\x1d\n" +
+	"\n" +
+	"lost_flows\x18\x11 \x01(\x04R\tlostFlows\x12!\n" +
+	"\flost_packets\x18\x12 \x01(\x04R\vlostPackets\x12\x1d\n" +
+	"\n" +
+	"lost_bytes\x18\x13 \x01(\x04R\tlostBytes\x12\x1b\n" +
+	"\terr_total\x18\x14 \x01(\x04R\berrTotal\x12\x1f\n" +
+	"\vsndbuf_peak\x18\x15 \x01(\x04R\n" +
+	"sndbufPeak\x12,\n" +
+	"\x04cpus\x18\x16 \x03(\v2\x18.netflowstats.v1.CPUStatR\x04cpus\x126\n" +
+	"\asockets\x18\x17 \x03(\v2\x1c.netflowstats.v1.NFSockEntryR\asockets\"\xf1\x02\n" +
+	"\aCPUStat\x12\x10\n" +
+	"\x03cpu\x18\x01 \x01(\tR\x03cpu\x12$\n" +
+	"\x0ein_packet_rate\x18\x02 \x01(\x04R\finPacketRate\x12\x19\n" +
+	"\bin_flows\x18\x03 \x01(\x04R\ainFlows\x12\x1d\n" +
+	"\n" +
+	"in_packets\x18\x04 \x01(\x04R\tinPackets\x12\x19\n" +
+	"\bin_bytes\x18\x05 \x01(\x04R\ainBytes\x12\x1f\n" +
+	"\vhash_metric\x18\x06 \x01(\x01R\n" +
+	"hashMetric\x12!\n" +
+	"\fdrop_packets\x18\a \x01(\x04R\vdropPackets\x12\x1d\n" +
+	"\n" +
+	"drop_bytes\x18\b \x01(\x04R\tdropBytes\x12\x1b\n" +
+	"\terr_trunc\x18\t \x01(\x04R\berrTrunc\x12\x19\n" +
+	"\berr_frag\x18\n" +
+	" \x01(\x04R\aerrFrag\x12\x1b\n" +
+	"\terr_alloc\x18\v \x01(\x04R\berrAlloc\x12!\n" +
+	"\ferr_maxflows\x18\f \x01(\x04R\verrMaxflows\"\xab\x02\n" +
+	"\vNFSockEntry\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x16\n" +
+	"\x06active\x18\x03 \x01(\rR\x06active\x12\x1f\n" +
+	"\verr_connect\x18\x04 \x01(\rR\n" +
+	"errConnect\x12\x19\n" +
+	"\berr_full\x18\x05 \x01(\rR\aerrFull\x12\x1b\n" +
+	"\terr_cberr\x18\x06 \x01(\rR\berrCberr\x12\x1b\n" +
+	"\terr_other\x18\a \x01(\rR\berrOther\x12\x16\n" +
+	"\x06sndbuf\x18\b \x01(\rR\x06sndbuf\x12\x1f\n" +
+	"\vsndbuf_fill\x18\t \x01(\rR\n" +
+	"sndbufFill\x12\x1f\n" +
+	"\vsndbuf_peak\x18\n" +
+	" \x01(\rR\n" +
+	"sndbufPeak2\x8a\x02\n" +
+	"\fNetflowStats\x12L\n" +
+	"\bGetStats\x12 .netflowstats.v1.GetStatsRequest\x1a\x1e.netflowstats.v1.StatsSnapshot\x12R\n" +
+	"\n" +
+	"WatchStats\x12\".netflowstats.v1.WatchStatsRequest\x1a\x1e.netflowstats.v1.StatsSnapshot0\x01\x12X\n" +
+	"\vListSockets\x12#.netflowstats.v1.ListSocketsRequest\x1a$.netflowstats.v1.ListSocketsResponseBNZLgithub.com/mythvcode/ipt-netflow-exporter/api/netflowstats/v1;netflowstatsv1b\x06proto3"
+
+var (
+	file_netflowstats_v1_netflow_stats_proto_rawDescOnce sync.Once
+	file_netflowstats_v1_netflow_stats_proto_rawDescData []byte
+)
+
+func file_netflowstats_v1_netflow_stats_proto_rawDescGZIP() []byte {
+	file_netflowstats_v1_netflow_stats_proto_rawDescOnce.Do(func() {
+		file_netflowstats_v1_netflow_stats_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_netflowstats_v1_netflow_stats_proto_rawDesc), len(file_netflowstats_v1_netflow_stats_proto_rawDesc)))
+	})
+	return file_netflowstats_v1_netflow_stats_proto_rawDescData
+}
+
+var file_netflowstats_v1_netflow_stats_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_netflowstats_v1_netflow_stats_proto_goTypes = []any{
+	(*GetStatsRequest)(nil),       // 0: netflowstats.v1.GetStatsRequest
+	(*WatchStatsRequest)(nil),     // 1: netflowstats.v1.WatchStatsRequest
+	(*ListSocketsRequest)(nil),    // 2: netflowstats.v1.ListSocketsRequest
+	(*ListSocketsResponse)(nil),   // 3: netflowstats.v1.ListSocketsResponse
+	(*StatsSnapshot)(nil),         // 4: netflowstats.v1.StatsSnapshot
+	(*Statistics)(nil),            // 5: netflowstats.v1.Statistics
+	(*CPUStat)(nil),               // 6: netflowstats.v1.CPUStat
+	(*NFSockEntry)(nil),           // 7: netflowstats.v1.NFSockEntry
+	(*durationpb.Duration)(nil),   // 8: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+}
+var file_netflowstats_v1_netflow_stats_proto_depIdxs = []int32{
+	8, // 0: netflowstats.v1.WatchStatsRequest.interval:type_name -> google.protobuf.Duration
+	7, // 1: netflowstats.v1.ListSocketsResponse.sockets:type_name -> netflowstats.v1.NFSockEntry
+	9, // 2: netflowstats.v1.StatsSnapshot.read_time:type_name -> google.protobuf.Timestamp
+	5, // 3: netflowstats.v1.StatsSnapshot.statistics:type_name -> netflowstats.v1.Statistics
+	6, // 4: netflowstats.v1.Statistics.cpus:type_name -> netflowstats.v1.CPUStat
+	7, // 5: netflowstats.v1.Statistics.sockets:type_name -> netflowstats.v1.NFSockEntry
+	0, // 6: netflowstats.v1.NetflowStats.GetStats:input_type -> netflowstats.v1.GetStatsRequest
+	1, // 7: netflowstats.v1.NetflowStats.WatchStats:input_type -> netflowstats.v1.WatchStatsRequest
+	2, // 8: netflowstats.v1.NetflowStats.ListSockets:input_type -> netflowstats.v1.ListSocketsRequest
+	4, // 9: netflowstats.v1.NetflowStats.GetStats:output_type -> netflowstats.v1.StatsSnapshot
+	4, // 10: netflowstats.v1.NetflowStats.WatchStats:output_type -> netflowstats.v1.StatsSnapshot
+	3, // 11: netflowstats.v1.NetflowStats.ListSockets:output_type -> netflowstats.v1.ListSocketsResponse
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_netflowstats_v1_netflow_stats_proto_init() }
+func file_netflowstats_v1_netflow_stats_proto_init() {
+	if File_netflowstats_v1_netflow_stats_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_netflowstats_v1_netflow_stats_proto_rawDesc), len(file_netflowstats_v1_netflow_stats_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_netflowstats_v1_netflow_stats_proto_goTypes,
+		DependencyIndexes: file_netflowstats_v1_netflow_stats_proto_depIdxs,
+		MessageInfos:      file_netflowstats_v1_netflow_stats_proto_msgTypes,
+	}.Build()
+	File_netflowstats_v1_netflow_stats_proto = out.File
+	file_netflowstats_v1_netflow_stats_proto_goTypes = nil
+	file_netflowstats_v1_netflow_stats_proto_depIdxs = nil
+}
@@ -0,0 +1,214 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: netflowstats/v1/netflow_stats.proto
+
+// NetflowStats exposes ipt_NETFLOW statistics parsed from the
+// ipt_netflow_snmp stat file.
+
+package netflowstatsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NetflowStats_GetStats_FullMethodName    = "/netflowstats.v1.NetflowStats/GetStats"
+	NetflowStats_WatchStats_FullMethodName  = "/netflowstats.v1.NetflowStats/WatchStats"
+	NetflowStats_ListSockets_FullMethodName = "/netflowstats.v1.NetflowStats/ListSockets"
+)
+
+// NetflowStatsClient is the client API for NetflowStats service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NetflowStatsClient interface {
+	// GetStats reads the stat file. Fails with UNAVAILABLE when the file
+	// cannot be read or parsed.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*StatsSnapshot, error)
+	// WatchStats sends a snapshot every interval until the client cancels.
+	// The stream ends with UNAVAILABLE when the stat file cannot be read.
+	WatchStats(ctx context.Context, in *WatchStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatsSnapshot], error)
+	// ListSockets returns export sockets of the module.
+	ListSockets(ctx context.Context, in *ListSocketsRequest, opts ...grpc.CallOption) (*ListSocketsResponse, error)
+}
+
+type netflowStatsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNetflowStatsClient(cc grpc.ClientConnInterface) NetflowStatsClient {
+	return &netflowStatsClient{cc}
+}
+
+func (c *netflowStatsClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*StatsSnapshot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsSnapshot)
+	err := c.cc.Invoke(ctx, NetflowStats_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *netflowStatsClient) WatchStats(ctx context.Context, in *WatchStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatsSnapshot], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NetflowStats_ServiceDesc.Streams[0], NetflowStats_WatchStats_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStatsRequest, StatsSnapshot]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NetflowStats_WatchStatsClient = grpc.ServerStreamingClient[StatsSnapshot]
+
+func (c *netflowStatsClient) ListSockets(ctx context.Context, in *ListSocketsRequest, opts ...grpc.CallOption) (*ListSocketsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSocketsResponse)
+	err := c.cc.Invoke(ctx, NetflowStats_ListSockets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NetflowStatsServer is the server API for NetflowStats service.
+// All implementations must embed UnimplementedNetflowStatsServer
+// for forward compatibility.
+type NetflowStatsServer interface {
+	// GetStats reads the stat file. Fails with UNAVAILABLE when the file
+	// cannot be read or parsed.
+	GetStats(context.Context, *GetStatsRequest) (*StatsSnapshot, error)
+	// WatchStats sends a snapshot every interval until the client cancels.
+	// The stream ends with UNAVAILABLE when the stat file cannot be read.
+	WatchStats(*WatchStatsRequest, grpc.ServerStreamingServer[StatsSnapshot]) error
+	// ListSockets returns export sockets of the module.
+	ListSockets(context.Context, *ListSocketsRequest) (*ListSocketsResponse, error)
+	mustEmbedUnimplementedNetflowStatsServer()
+}
+
+// UnimplementedNetflowStatsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNetflowStatsServer struct{}
+
+func (UnimplementedNetflowStatsServer) GetStats(context.Context, *GetStatsRequest) (*StatsSnapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedNetflowStatsServer) WatchStats(*WatchStatsRequest, grpc.ServerStreamingServer[StatsSnapshot]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStats not implemented")
+}
+func (UnimplementedNetflowStatsServer) ListSockets(context.Context, *ListSocketsRequest) (*ListSocketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSockets not implemented")
+}
+func (UnimplementedNetflowStatsServer) mustEmbedUnimplementedNetflowStatsServer() {}
+func (UnimplementedNetflowStatsServer) testEmbeddedByValue()                      {}
+
+// UnsafeNetflowStatsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NetflowStatsServer will
+// result in compilation errors.
+type UnsafeNetflowStatsServer interface {
+	mustEmbedUnimplementedNetflowStatsServer()
+}
+
+func RegisterNetflowStatsServer(s grpc.ServiceRegistrar, srv NetflowStatsServer) {
+	// If the following call pancis, it indicates UnimplementedNetflowStatsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NetflowStats_ServiceDesc, srv)
+}
+
+func _NetflowStats_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetflowStatsServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetflowStats_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetflowStatsServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetflowStats_WatchStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NetflowStatsServer).WatchStats(m, &grpc.GenericServerStream[WatchStatsRequest, StatsSnapshot]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NetflowStats_WatchStatsServer = grpc.ServerStreamingServer[StatsSnapshot]
+
+func _NetflowStats_ListSockets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSocketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetflowStatsServer).ListSockets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetflowStats_ListSockets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetflowStatsServer).ListSockets(ctx, req.(*ListSocketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// NetflowStats_ServiceDesc is the grpc.ServiceDesc for NetflowStats service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NetflowStats_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "netflowstats.v1.NetflowStats",
+	HandlerType: (*NetflowStatsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStats",
+			Handler:    _NetflowStats_GetStats_Handler,
+		},
+		{
+			MethodName: "ListSockets",
+			Handler:    _NetflowStats_ListSockets_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStats",
+			Handler:       _NetflowStats_WatchStats_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "netflowstats/v1/netflow_stats.proto",
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/check"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// runCheck is a Nagios plugin: it prints a status line with performance data
+// and exits with 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN).
+func runCheck(args []string) int {
+	fs, loader := configFlagSet("check")
+	interval := fs.Duration("interval", 0, "Read the stat file twice this long apart and check rates instead of totals")
+	opts := check.Options{}
+	thresholds := []struct {
+		name      string
+		help      string
+		threshold *check.Threshold
+		warning   string
+		critical  string
+	}{
+		{"loss", "lost flows", &opts.Loss, "", ""},
+		{"drops", "dropped packets", &opts.Drops, "", ""},
+		{"socket-errors", "socket errors", &opts.SocketErrors, "", ""},
+		{"inactive-sockets", "number of inactive sockets", &opts.InactiveSockets, "", "0"},
+		{"sndbuf-fill", "highest socket send buffer fill in percent", &opts.SndbufFill, "80", "95"},
+		{"hash-metric", "hash metric", &opts.HashMetric, "1.5", ""},
+	}
+	for _, threshold := range thresholds {
+		// defaults are valid ranges
+		_ = threshold.threshold.Warning.Set(threshold.warning)
+		_ = threshold.threshold.Critical.Set(threshold.critical)
+		fs.Var(&threshold.threshold.Warning, threshold.name+"-warning", "Warning range of "+threshold.help)
+		fs.Var(&threshold.threshold.Critical, threshold.name+"-critical", "Critical range of "+threshold.help)
+	}
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(check.Unknown(err))
+
+		return check.StatusUnknown
+	}
+	if fs.NArg() > 0 {
+		fmt.Println(check.Unknown(fmt.Errorf("unexpected argument %s", fs.Arg(0))))
+
+		return check.StatusUnknown
+	}
+	if *interval < 0 {
+		fmt.Println(check.Unknown(errors.New("interval must not be negative")))
+
+		return check.StatusUnknown
+	}
+	cfg, _, err := loader.Load()
+	if err != nil {
+		fmt.Println(check.Unknown(fmt.Errorf("error read config: %w", err)))
+
+		return check.StatusUnknown
+	}
+
+	collector := statparser.New(cfg.Exporter.IPTNetFlowStatFile)
+	read := func() (statparser.Statistics, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), statReadTimeout)
+		defer cancel()
+
+		return collector.CollectAndMarshal(ctx)
+	}
+	var before *statparser.Statistics
+	if *interval > 0 {
+		stat, err := read()
+		if err != nil {
+			fmt.Println(check.Unknown(err))
+
+			return check.StatusUnknown
+		}
+		before = &stat
+		time.Sleep(*interval)
+	}
+	after, err := read()
+	if err != nil {
+		fmt.Println(check.Unknown(err))
+
+		return check.StatusUnknown
+	}
+	result := check.Check(before, &after, *interval, opts)
+	fmt.Println(result.String())
+
+	return result.Status
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// command is a CLI subcommand. The exporter server runs when no subcommand
+// is given.
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]command{
+	"check": {
+		usage: "check [flags]: Nagios plugin checking loss, drops, socket errors, inactive sockets, send buffer fill and hash metric, -interval checks rates",
+		run:   runCheck,
+	},
+	"config": {
+		usage: "config print|validate|schema [flags]: show effective config values and their sources, report every config problem or print config JSON Schema",
+		run:   runConfig,
+	},
+	"diff": {
+		usage: "diff before.txt after.txt [flags]: show deltas and rates between two stat file captures, counters which went backwards, added or removed CPUs and sockets and CPU share shifts",
+		run:   runDiff,
+	},
+	"generate": {
+		usage: "generate rules|dashboard [flags]: print Prometheus alerting rules or a Grafana dashboard matching the metrics of this build",
+		run:   runGenerate,
+	},
+	"report": {
+		usage: "report <directory|archive.tar.gz> [flags]: write a Markdown or HTML report of stat file captures with loss, socket errors, saturation, drops and CPU imbalance",
+		run:   runReport,
+	},
+	"top": {
+		usage: "top [flags]: show a refreshing view of stat file rates, q quits, p pauses, c and s change CPU and socket sort",
+		run:   runTop,
+	},
+	"zabbix": {
+		usage: "zabbix discovery cpu|socket | get <key> | send --server host[:port] [flags]: print Zabbix low-level discovery or an item value, or push all items with the sender protocol",
+		run:   runZabbix,
+	},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags]\n       %s <command> [args]\n\nCommands:\n", os.Args[0], os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintf(out, "\nConfig precedence: defaults < config file < environment variables < flags.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// parseArgs parses args with flags of fs before and after positional
+// arguments and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	positional := []string{}
+	for fs.NArg() > 0 {
+		positional = append(positional, fs.Arg(0))
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return positional, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+)
+
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: config print|validate|schema [flags]")
+
+		return 2
+	}
+	switch args[0] {
+	case "print":
+		return runConfigPrint(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "schema":
+		return runConfigSchema()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config command %s\n", args[0])
+
+		return 2
+	}
+}
+
+// configFlagSet returns a flag set with the config file flag and flags for
+// every config setting.
+func configFlagSet(name string) (*flag.FlagSet, *config.Loader) {
+	loader := &config.Loader{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&loader.File, "config", "", "Path to config file")
+	fs.BoolVar(&loader.Strict, "config.strict", false, "Fail on unknown config file keys instead of warning")
+	loader.Flags = config.RegisterFlags(fs)
+
+	return fs, loader
+}
+
+func runConfigPrint(args []string) int {
+	fs, loader := configFlagSet("config print")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	settings, err := loader.Settings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error read config: %s\n", err.Error())
+
+		return 1
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SETTING\tVALUE\tSOURCE\tENV\tFLAG")
+	for _, setting := range settings {
+		env, flagName := "-", "-"
+		if setting.Flag != "" {
+			env, flagName = setting.Env, "--"+setting.Flag
+		}
+		fmt.Fprintf(writer, "%s\t%q\t%s\t%s\t%s\n", setting.Path, setting.Value, setting.Source, env, flagName)
+	}
+	if err := writer.Flush(); err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+type validateResult struct {
+	Valid    bool             `json:"valid"`
+	Problems []config.Problem `json:"problems"`
+}
+
+// runConfigValidate reports every config problem. Exit code is 1 when the
+// config has errors, warnings alone do not fail validation.
+func runConfigValidate(args []string) int {
+	fs, loader := configFlagSet("config validate")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	result := validateResult{Valid: true, Problems: []config.Problem{}}
+	if settingsErr := loadForValidate(loader, &result); settingsErr != nil {
+		result.Valid = false
+		result.Problems = append(result.Problems, config.Problem{Field: "config", Severity: config.SeverityError, Message: settingsErr.Error()})
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return 1
+		}
+	case "text":
+		for _, problem := range result.Problems {
+			fmt.Printf("%s\t%s\n", problem.Severity, problem.String())
+		}
+		if result.Valid {
+			fmt.Println("config is valid")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+
+		return 2
+	}
+	if !result.Valid {
+		return 1
+	}
+
+	return 0
+}
+
+func loadForValidate(loader *config.Loader, result *validateResult) error {
+	_, problems, err := loader.Problems()
+	if err != nil {
+		return err
+	}
+	result.Problems = append(result.Problems, problems...)
+	for _, problem := range result.Problems {
+		if problem.Severity == config.SeverityError {
+			result.Valid = false
+		}
+	}
+
+	return nil
+}
+
+func runConfigSchema() int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config.Schema()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encode schema: %s\n", err.Error())
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statdiff"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+const statReadTimeout = 10 * time.Second
+
+// runDiff compares two captures of the stat file. The interval for rates is
+// the difference of file modification times unless given, which is marked in
+// the output.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	interval := fs.Duration("interval", 0, "Time between captures, default is the difference of file modification times")
+	format := fs.String("format", "table", "Output format: table or json")
+	shareThreshold := fs.Float64("share-threshold", 10, "Report CPUs whose share of packets between the captures moved by this many percentage points (0 disables)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: diff before.txt after.txt [--interval 30s] [--format table|json]")
+
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+
+		return 2
+	}
+	if *interval < 0 {
+		fmt.Fprintln(os.Stderr, "Interval must not be negative")
+
+		return 2
+	}
+	before, err := readStatFile(files[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+
+		return 1
+	}
+	after, err := readStatFile(files[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+
+		return 1
+	}
+	opts := statdiff.Options{Interval: *interval, ShareThreshold: *shareThreshold}
+	if opts.Interval == 0 {
+		opts.Interval, opts.IntervalFromModTime = modTimeInterval(files[0], files[1]), true
+	}
+
+	result := statdiff.Diff(&before, &after, opts)
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return 1
+		}
+
+		return 0
+	}
+	if err := result.WriteTable(os.Stdout); err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+func readStatFile(file string) (statparser.Statistics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), statReadTimeout)
+	defer cancel()
+	stat, err := statparser.New(file).CollectAndMarshal(ctx)
+	if err != nil {
+		return stat, fmt.Errorf("error read stat file %s: %w", file, err)
+	}
+
+	return stat, nil
+}
+
+// modTimeInterval returns the time between modification of the files, zero
+// when it is unknown.
+func modTimeInterval(before, after string) time.Duration {
+	beforeInfo, err := os.Stat(before)
+	if err != nil {
+		return 0
+	}
+	afterInfo, err := os.Stat(after)
+	if err != nil {
+		return 0
+	}
+
+	return max(0, afterInfo.ModTime().Sub(beforeInfo.ModTime()))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/exporter"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/generate"
+)
+
+// reservedAlertLabels are set by generated alerts or by Prometheus.
+var reservedAlertLabels = []string{"severity", "alertname"}
+
+// runGenerate prints Prometheus alerting rules or a Grafana dashboard for
+// the metrics of this exporter build.
+func runGenerate(args []string) int {
+	if len(args) == 0 || (args[0] != "rules" && args[0] != "dashboard") {
+		fmt.Fprintln(os.Stderr, "Usage: generate rules|dashboard [--selector 'job=\"netflow\"'] [--label team=network] [--output file]")
+
+		return 2
+	}
+	kind := args[0]
+	fs := flag.NewFlagSet("generate "+kind, flag.ContinueOnError)
+	opts := generate.Options{Namespace: exporter.MetricsNamespace, Labels: map[string]string{}}
+	fs.StringVar(&opts.Selector, "selector", "", `Label matchers added to every query, e.g. job="netflow"`)
+	fs.StringVar(&opts.Title, "title", "", "Dashboard title")
+	fs.Func("label", "Label added to every alert as name=value, may be repeated", func(value string) error {
+		name, labelValue, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("label %q must be name=value", value)
+		}
+		if slices.Contains(reservedAlertLabels, name) {
+			return fmt.Errorf("label %s is set by the alerts and cannot be overridden", name)
+		}
+		opts.Labels[name] = labelValue
+
+		return nil
+	})
+	output := fs.String("output", "", "Output file, default is stdout")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected argument %s\n", fs.Arg(0))
+
+		return 2
+	}
+
+	families, err := exporter.MetricFamilies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error collect metric families: %s\n", err.Error())
+
+		return 1
+	}
+	build := generate.Rules
+	if kind == "dashboard" {
+		build = generate.Dashboard
+	}
+	data, err := build(families, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+
+		return 1
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o644) //nolint:gosec
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error write %s: %s\n", kind, err.Error())
+
+		return 1
+	}
+
+	return 0
+}
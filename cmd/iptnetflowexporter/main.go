@@ -1,59 +1,216 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/exporter"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/sdnotify"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
 )
 
-var cfgPath string
+var (
+	cfgPath      string
+	strictConfig bool
+	flagValues   map[string]string
+)
 
 func init() {
 	flag.StringVar(&cfgPath, "config", "", "Path to config file")
+	flag.BoolVar(&strictConfig, "config.strict", false, "Fail on unknown config file keys instead of warning")
+	flagValues = config.RegisterFlags(flag.CommandLine)
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	flag.Usage = usage
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1)
 	flag.Parse()
-	cfg, err := config.ReadConfig(cfgPath)
+	loader := config.Loader{File: cfgPath, Flags: flagValues, Strict: strictConfig}
+	cfg, warnings, err := loader.Load()
 	if err != nil {
 		if cfgPath != "" {
-			logger.Default().Errorf("Error read config file from file %s: %s", cfgPath, err.Error())
+			logger.Default().ErrorErr("Error read config file", err, slog.String(logger.FileKey, cfgPath))
 		} else {
-			logger.Default().Errorf("Error read config: %s", err.Error())
+			logger.Default().ErrorErr("Error read config", err)
 		}
 		os.Exit(1)
 	}
 
-	if err := logger.Init(cfg.Logger.File, cfg.Logger.Level, cfg.Logger.Format); err != nil {
-		logger.Default().Errorf("error init logger %s", err.Error())
+	if err := logger.Init(cfg.Logger); err != nil {
+		logger.Default().ErrorErr("Error init logger", err)
 		os.Exit(1)
 	}
+	logWarnings(warnings)
+	os.Exit(run(loader, cfg, sigs))
+}
+
+func run(loader config.Loader, cfg config.Config, sigs <-chan os.Signal) int {
+	log := logger.GetLogger()
 	stat := statparser.New(cfg.Exporter.IPTNetFlowStatFile)
-	exporter, err := exporter.New(cfg.Exporter, stat)
+	server, err := exporter.New(cfg.Exporter, stat)
 	if err != nil {
-		logger.GetLogger().Errorf("error init exporter %s", err.Error())
+		log.ErrorErr("Error init exporter", err)
+
+		return 1
+	}
+	reloader := newReloader(loader, cfg, server, stat)
+	debugServer, err := startDebugServer(cfg.Exporter, reloader)
+	if err != nil {
+		log.ErrorErr("Error start debug server", err)
+
+		return 1
+	}
+	grpcServer, err := startGRPCServer(cfg.Exporter, reloader)
+	if err != nil {
+		log.ErrorErr("Error start gRPC server", err)
+
+		return 1
+	}
+	if err := server.Listen(); err != nil {
+		log.ErrorErr("Error start exporter", err)
+
+		return 1
 	}
-	started := make(chan error)
+	served := make(chan error, 1)
 	go func() {
-		started <- exporter.Start()
+		served <- server.Serve()
 	}()
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go reloader.watch(backgroundCtx, time.Duration(cfg.Exporter.ConfigWatchInterval)*time.Second)
+
+	notifier := sdnotify.New()
+	go notifier.Run(backgroundCtx, func() error {
+		ctx, cancel := context.WithTimeout(backgroundCtx, time.Duration(reloader.config().Exporter.RequestTimeout)*time.Second)
+		defer cancel()
+		_, err := reloader.statCollector().CollectAndMarshal(ctx)
+
+		return err
+	})
+
+	exitCode := wait(sigs, served, reloader)
+
+	stopBackground()
+	notifier.Stopping()
+	timeout := time.Duration(reloader.config().Exporter.ShutdownTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		exitCode = 1
+	}
+	if debugServer != nil {
+		if err := debugServer.Shutdown(ctx); err != nil {
+			exitCode = 1
+		}
+	}
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(ctx); err != nil {
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+// startDebugServer serves debug endpoints when a debug listen address is
+// configured. Errors after start are logged only, the exporter keeps running.
+func startDebugServer(cfg config.Exporter, reloader *reloader) (*exporter.DebugServer, error) {
+	if cfg.DebugListenAddress == "" {
+		return nil, nil //nolint:nilnil
+	}
+	debugServer := exporter.NewDebugServer(cfg, func() (statparser.Snapshot, bool) {
+		return reloader.statCollector().LastSnapshot()
+	})
+	if err := debugServer.Listen(); err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := debugServer.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.GetLogger().ErrorErr("Error serve debug server", err)
+		}
+	}()
+
+	return debugServer, nil
+}
+
+// startGRPCServer serves the gRPC API when a gRPC listen address is
+// configured. Errors after start are logged only, the exporter keeps running.
+func startGRPCServer(cfg config.Exporter, reloader *reloader) (*exporter.GRPCServer, error) {
+	if cfg.GRPCListenAddress == "" {
+		return nil, nil //nolint:nilnil
+	}
+	grpcServer, err := exporter.NewGRPCServer(cfg, exporter.StatReaderFunc(func(ctx context.Context) (statparser.Statistics, error) {
+		return reloader.statCollector().CollectAndMarshal(ctx)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if err := grpcServer.Listen(); err != nil {
+		return nil, err
+	}
 	go func() {
-		if err := <-started; err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.GetLogger().Errorf("Error start exporter: %s", err.Error())
-			os.Exit(1)
+		if err := grpcServer.Serve(); err != nil {
+			logger.GetLogger().ErrorErr("Error serve gRPC server", err)
 		}
 	}()
 
-	defer exporter.Stop()
-	<-sigs
+	return grpcServer, nil
+}
+
+// wait blocks until a stop signal or a server error, reloading the config
+// on SIGHUP and reopening the log file on SIGUSR1. It returns the process
+// exit code.
+func wait(sigs <-chan os.Signal, served <-chan error, reloader *reloader) int {
+	log := logger.GetLogger()
+	for {
+		select {
+		case sig := <-sigs:
+			switch sig {
+			case syscall.SIGHUP:
+				log.Infof("Received signal %s, reloading config", sig.String())
+				reloader.reload()
+
+				continue
+			case syscall.SIGUSR1:
+				if err := logger.Reopen(); err != nil {
+					log.ErrorErr("Error reopen log file", err)
+				}
+
+				continue
+			}
+			log.Infof("Received signal %s, shutting down", sig.String())
+
+			return 0
+		case err := <-served:
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.ErrorErr("Error serve exporter", err)
+
+				return 1
+			}
+
+			return 0
+		}
+	}
+}
+
+func logWarnings(warnings []config.Problem) {
+	for _, warning := range warnings {
+		logger.GetLogger().Warningf("Config warning: %s", warning.String())
+	}
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/exporter"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// reloader re-reads the config on SIGHUP or config file change and applies
+// it to the logger and API server. On any error the previous config is kept.
+type reloader struct {
+	loader config.Loader
+	server *exporter.APIServer
+	log    *logger.Logger
+	mu     sync.Mutex
+	cfg    atomic.Pointer[config.Config]
+	stat   atomic.Pointer[statparser.StatCollector]
+}
+
+func newReloader(loader config.Loader, cfg config.Config, server *exporter.APIServer, stat *statparser.StatCollector) *reloader {
+	r := &reloader{
+		loader: loader,
+		server: server,
+		log:    logger.GetLogger().With(slog.String(logger.Component, "ConfigReloader")),
+	}
+	r.cfg.Store(&cfg)
+	r.stat.Store(stat)
+
+	return r
+}
+
+func (r *reloader) config() config.Config {
+	return *r.cfg.Load()
+}
+
+func (r *reloader) statCollector() *statparser.StatCollector {
+	return r.stat.Load()
+}
+
+func (r *reloader) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.apply()
+	r.server.ReportReload(err)
+	if err != nil {
+		r.log.ErrorErr("Error reload config, keeping previous config", err)
+
+		return
+	}
+	r.log.Infof("Config reloaded")
+}
+
+func (r *reloader) apply() error {
+	cfg, warnings, err := r.loader.Load()
+	if err != nil {
+		return err
+	}
+	// logger is the most likely to fail (log file), so it goes first
+	if err := logger.Init(cfg.Logger); err != nil {
+		return err
+	}
+	logWarnings(warnings)
+	stat := statparser.New(cfg.Exporter.IPTNetFlowStatFile)
+	if err := r.server.Reload(cfg.Exporter, stat); err != nil {
+		prev := r.config()
+		if err := logger.Init(prev.Logger); err != nil {
+			r.log.ErrorErr("Error restore previous logger", err)
+		}
+
+		return err
+	}
+	r.stat.Store(stat)
+	r.cfg.Store(&cfg)
+
+	return nil
+}
+
+// watch reloads the config when the config file content changes. Content is
+// compared instead of mtime so that symlink swaps (e.g. Kubernetes ConfigMaps)
+// are detected as well.
+func (r *reloader) watch(ctx context.Context, interval time.Duration) {
+	if r.loader.File == "" || interval <= 0 {
+		return
+	}
+	lastSum := r.fileSum()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sum := r.fileSum()
+		if sum == nil || bytes.Equal(sum, lastSum) {
+			continue
+		}
+		lastSum = sum
+		r.log.Infof("Config file %s changed, reloading", r.loader.File)
+		r.reload()
+	}
+}
+
+func (r *reloader) fileSum() []byte {
+	content, err := os.ReadFile(filepath.Clean(r.loader.File))
+	if err != nil {
+		r.log.WarningErr("Error read config file", err, slog.String(logger.FileKey, r.loader.File))
+
+		return nil
+	}
+	sum := sha256.Sum256(content)
+
+	return sum[:]
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/report"
+)
+
+// runReport writes a health report of stat file captures in a directory or
+// a tar archive.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	defaults := report.DefaultOptions()
+	format := fs.String("format", "markdown", "Output format: markdown or html")
+	output := fs.String("output", "", "Output file, default is stdout")
+	opts := report.Options{}
+	fs.Float64Var(&opts.SndbufFill, "sndbuf-fill", defaults.SndbufFill, "Socket send buffer fill reported as saturation, percent")
+	fs.Float64Var(&opts.HashMetric, "hash-metric", defaults.HashMetric, "Hash metric reported as degradation")
+	fs.Float64Var(&opts.ImbalanceRatio, "imbalance-ratio", defaults.ImbalanceRatio, "Share of packets of the busiest CPU to an even share reported as imbalance")
+	sources, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(sources) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: report <directory|archive.tar.gz> [--format markdown|html] [--output file]")
+
+		return 2
+	}
+	if *format != "markdown" && *format != "html" {
+		fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+
+		return 2
+	}
+
+	samples, skipped, err := report.Load(sources[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+
+		return 1
+	}
+	if len(samples) == 0 {
+		fmt.Fprintf(os.Stderr, "No stat file captures found in %s\n", sources[0])
+
+		return 1
+	}
+	result := report.Build(filepath.Base(sources[0]), samples, skipped, opts)
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error create report file: %s\n", err.Error())
+
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	write := result.WriteMarkdown
+	if *format == "html" {
+		write = result.WriteHTML
+	}
+	if err := write(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error write report: %s\n", err.Error())
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/top"
+	"golang.org/x/term"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+// runTop shows a refreshing view of the stat file until q is pressed, or
+// prints -n frames without keys when stdin is not a terminal.
+func runTop(args []string) int {
+	fs, loader := configFlagSet("top")
+	interval := fs.Duration("interval", time.Second, "Refresh interval")
+	frames := fs.Int("n", 0, "Number of frames to show, 0 shows frames until q is pressed")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Interval must be above zero")
+
+		return 2
+	}
+	cfg, _, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error read config: %s\n", err.Error())
+
+		return 1
+	}
+	stdoutTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	view := &top.View{
+		File:     cfg.Exporter.IPTNetFlowStatFile,
+		Interval: *interval,
+		Color:    os.Getenv("NO_COLOR") == "" && stdoutTerminal,
+		// frames are only separated when stdout is not a terminal
+		FrameStart: "\n",
+	}
+	if stdoutTerminal {
+		view.FrameStart = clearScreen
+	}
+	collector := statparser.New(cfg.Exporter.IPTNetFlowStatFile)
+	readTimeout := time.Duration(cfg.Exporter.RequestTimeout) * time.Second
+
+	keys := make(chan byte)
+	out := io.Writer(os.Stdout)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error set terminal raw mode: %s\n", err.Error())
+
+			return 1
+		}
+		defer term.Restore(int(os.Stdin.Fd()), state) //nolint:errcheck
+		out = rawWriter{os.Stdout}
+		go readKeys(keys)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for frame := 1; ; frame++ {
+		if !view.Paused {
+			ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+			stat, err := collector.CollectAndMarshal(ctx)
+			cancel()
+			if err != nil {
+				view.SetError(err)
+			} else {
+				view.Update(top.Sample{Time: time.Now(), Stat: stat})
+			}
+		}
+		if err := view.Render(out); err != nil {
+			return 1
+		}
+		if *frames > 0 && frame >= *frames {
+			return 0
+		}
+		if quit, err := waitTick(ticker, keys, out, view); quit || err != nil {
+			return exitCode(err)
+		}
+	}
+}
+
+// waitTick handles key presses until the next tick, the view is redrawn at
+// once with new settings. It returns true when a quit key was pressed.
+func waitTick(ticker *time.Ticker, keys <-chan byte, out io.Writer, view *top.View) (bool, error) {
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+
+				continue
+			}
+			if view.HandleKey(key) {
+				return true, nil
+			}
+			if err := view.Render(out); err != nil {
+				return false, err
+			}
+		case <-ticker.C:
+			return false, nil
+		}
+	}
+}
+
+func exitCode(err error) int {
+	if err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// readKeys sends key presses from stdin until it is closed.
+func readKeys(keys chan<- byte) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := os.Stdin.Read(buf); err != nil {
+			close(keys)
+
+			return
+		}
+		keys <- buf[0]
+	}
+}
+
+// rawWriter returns the carriage on new lines, as the terminal in raw mode
+// does not.
+type rawWriter struct {
+	out io.Writer
+}
+
+func (w rawWriter) Write(content []byte) (int, error) {
+	if _, err := w.out.Write(bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+
+	return len(content), nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/zabbix"
+)
+
+const zabbixUsage = "Usage: zabbix discovery cpu|socket | zabbix get <key> | zabbix send --server host[:port] [--host name]"
+
+// runZabbix prints low-level discovery data or an item value for Zabbix
+// agent user parameters, or pushes all items to a Zabbix server.
+func runZabbix(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, zabbixUsage)
+
+		return 2
+	}
+	action := args[0]
+	fs, loader := configFlagSet("zabbix " + action)
+	server := fs.String("server", "", "Zabbix server or proxy receiving values, host[:port], for send")
+	host := fs.String("host", "", "Host name of the values in Zabbix for send, default is the hostname")
+	params, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return 2
+	}
+	switch {
+	case action == "discovery" && len(params) == 1,
+		action == "get" && len(params) == 1,
+		action == "send" && len(params) == 0 && *server != "":
+	default:
+		fmt.Fprintln(os.Stderr, zabbixUsage)
+
+		return 2
+	}
+	cfg, _, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error read config: %s\n", err.Error())
+
+		return 1
+	}
+	stat, err := readStatFile(cfg.Exporter.IPTNetFlowStatFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+
+		return 1
+	}
+
+	switch action {
+	case "discovery":
+		data, err := zabbix.Discovery(&stat, params[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+
+			return 2
+		}
+		fmt.Println(string(data))
+	case "get":
+		value, err := zabbix.Lookup(&stat, params[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+
+			return 1
+		}
+		fmt.Println(value)
+	default:
+		return sendZabbix(&stat, *server, *host)
+	}
+
+	return 0
+}
+
+func sendZabbix(stat *statparser.Statistics, server, host string) int {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, zabbix.DefaultPort)
+	}
+	if host == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error get hostname: %s\n", err.Error())
+
+			return 1
+		}
+		host = hostname
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statReadTimeout)
+	defer cancel()
+	response, err := zabbix.Send(ctx, server, host, zabbix.Items(stat), time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+
+		return 1
+	}
+	fmt.Println(response.Info)
+
+	return 0
+}
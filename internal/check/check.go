@@ -0,0 +1,267 @@
+// Package check evaluates reads of the ipt_NETFLOW stat file against
+// warning and critical thresholds for Nagios compatible monitoring.
+package check
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statfmt"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// Plugin exit codes.
+const (
+	StatusOK       = 0
+	StatusWarning  = 1
+	StatusCritical = 2
+	StatusUnknown  = 3
+)
+
+const serviceName = "IPT_NETFLOW"
+
+var statusNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// Range is a Nagios threshold range. A value outside of Start and End alerts,
+// or inside when Inside is set. An empty range never alerts.
+type Range struct {
+	Start  float64
+	End    float64
+	Inside bool
+	raw    string
+}
+
+// ParseRange parses a range in the Nagios plugin format: 10 (0 to 10), 10:
+// (10 or more), ~:10 (10 or less), 10:20 and @10:20 (alert inside).
+func ParseRange(value string) (Range, error) {
+	parsed := Range{raw: value}
+	if value == "" {
+		return parsed, nil
+	}
+	if strings.HasPrefix(value, "@") {
+		parsed.Inside = true
+		value = value[1:]
+	}
+	if value == "" {
+		return Range{}, fmt.Errorf("error incorrect range %s: empty range", parsed.raw)
+	}
+	start, end, hasStart := strings.Cut(value, ":")
+	if !hasStart {
+		start, end = "0", start
+	}
+	var err error
+	switch start {
+	case "~":
+		parsed.Start = math.Inf(-1)
+	case "":
+		parsed.Start = 0
+	default:
+		if parsed.Start, err = strconv.ParseFloat(start, 64); err != nil {
+			return Range{}, fmt.Errorf("error incorrect range %s: %w", parsed.raw, err)
+		}
+	}
+	if end == "" {
+		parsed.End = math.Inf(1)
+	} else if parsed.End, err = strconv.ParseFloat(end, 64); err != nil {
+		return Range{}, fmt.Errorf("error incorrect range %s: %w", parsed.raw, err)
+	}
+	if parsed.Start > parsed.End {
+		return Range{}, fmt.Errorf("error incorrect range %s: start is above end", parsed.raw)
+	}
+
+	return parsed, nil
+}
+
+// Set implements flag.Value.
+func (r *Range) Set(value string) error {
+	parsed, err := ParseRange(value)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+
+	return nil
+}
+
+// String returns the range as given.
+func (r *Range) String() string {
+	return r.raw
+}
+
+// Alert reports whether value is outside the range, inside for @ ranges.
+func (r *Range) Alert(value float64) bool {
+	if r.raw == "" {
+		return false
+	}
+	inside := value >= r.Start && value <= r.End
+
+	return inside == r.Inside
+}
+
+// Threshold holds warning and critical ranges of a value.
+type Threshold struct {
+	Warning  Range
+	Critical Range
+}
+
+func (t *Threshold) status(value float64) int {
+	switch {
+	case t.Critical.Alert(value):
+		return StatusCritical
+	case t.Warning.Alert(value):
+		return StatusWarning
+	default:
+		return StatusOK
+	}
+}
+
+// Options are thresholds of checked values. Loss, drops and socket errors
+// are per second rates when there are two reads and totals since the module
+// was loaded otherwise.
+type Options struct {
+	Loss            Threshold
+	Drops           Threshold
+	SocketErrors    Threshold
+	InactiveSockets Threshold
+	SndbufFill      Threshold
+	HashMetric      Threshold
+}
+
+// Value is a checked value with its status.
+type Value struct {
+	Label     string
+	Text      string
+	Value     float64
+	Unit      string
+	Max       float64
+	Status    int
+	Threshold *Threshold
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Status int
+	Values []Value
+}
+
+// Check evaluates after, and rates since before when it is not nil.
+func Check(before *statparser.Statistics, after *statparser.Statistics, interval time.Duration, opts Options) Result {
+	result := Result{}
+	counter := func(label, text string, value func(stat *statparser.Statistics) float64, threshold *Threshold) {
+		current := value(after)
+		if before == nil {
+			result.add(Value{Label: label, Text: text + " " + formatValue(current), Value: current, Unit: "c", Threshold: threshold})
+
+			return
+		}
+		delta := current - value(before)
+		if delta < 0 {
+			// counters were reset by a module reload
+			delta = current
+		}
+		rate := delta / interval.Seconds()
+		result.add(Value{Label: label + "_rate", Text: text + " " + formatValue(rate) + "/s", Value: rate, Threshold: threshold})
+	}
+
+	counter("lost_flows", "lost flows", func(stat *statparser.Statistics) float64 {
+		return float64(stat.LostFlows)
+	}, &opts.Loss)
+	counter("drop_packets", "dropped packets", func(stat *statparser.Statistics) float64 {
+		return float64(stat.DropPackets)
+	}, &opts.Drops)
+	counter("socket_errors", "socket errors", socketErrors, &opts.SocketErrors)
+
+	inactive := 0
+	for _, socket := range after.SockStatList {
+		if socket.SockActive == 0 {
+			inactive++
+		}
+	}
+	result.add(Value{
+		Label:     "inactive_sockets",
+		Text:      fmt.Sprintf("inactive sockets %d of %d", inactive, len(after.SockStatList)),
+		Value:     float64(inactive),
+		Max:       float64(len(after.SockStatList)),
+		Threshold: &opts.InactiveSockets,
+	})
+
+	fill, fullest := 0.0, ""
+	for _, socket := range after.SockStatList {
+		if socket.SockSndbuf == 0 {
+			continue
+		}
+		if socketFill := statfmt.SndbufFill(&socket); fullest == "" || socketFill > fill {
+			fill, fullest = socketFill, socket.SockName
+		}
+	}
+	fillText := "sndbuf fill " + formatValue(fill) + "%"
+	if fullest != "" {
+		fillText += " on " + fullest
+	}
+	result.add(Value{Label: "sndbuf_fill", Text: fillText, Value: fill, Unit: "%", Max: 100, Threshold: &opts.SndbufFill})
+	result.add(Value{
+		Label:     "hash_metric",
+		Text:      "hash metric " + formatValue(after.HashMetric),
+		Value:     after.HashMetric,
+		Threshold: &opts.HashMetric,
+	})
+
+	return result
+}
+
+// socketErrors sums errors of all sockets.
+func socketErrors(stat *statparser.Statistics) float64 {
+	total := uint64(0)
+	for _, socket := range stat.SockStatList {
+		total += statfmt.SocketErrors(&socket)
+	}
+
+	return float64(total)
+}
+
+func (r *Result) add(value Value) {
+	value.Status = value.Threshold.status(value.Value)
+	r.Status = max(r.Status, value.Status)
+	r.Values = append(r.Values, value)
+}
+
+// Unknown returns the status line of a check which could not run.
+func Unknown(err error) string {
+	return serviceName + " UNKNOWN - " + err.Error()
+}
+
+// String returns the plugin output: status, values with problems first and
+// performance data.
+func (r *Result) String() string {
+	texts := []string{}
+	for _, status := range []int{StatusCritical, StatusWarning, StatusOK} {
+		for _, value := range r.Values {
+			if value.Status != status {
+				continue
+			}
+			if status == StatusOK {
+				texts = append(texts, value.Text)
+			} else {
+				texts = append(texts, value.Text+" ("+statusNames[status]+")")
+			}
+		}
+	}
+	perfdata := make([]string, 0, len(r.Values))
+	for _, value := range r.Values {
+		maxValue := ""
+		if value.Max > 0 {
+			maxValue = formatValue(value.Max)
+		}
+		perfdata = append(perfdata, fmt.Sprintf("%s=%s%s;%s;%s;0;%s", value.Label, formatValue(value.Value), value.Unit,
+			value.Threshold.Warning.String(), value.Threshold.Critical.String(), maxValue))
+	}
+
+	return fmt.Sprintf("%s %s - %s | %s", serviceName, statusNames[r.Status], strings.Join(texts, ", "), strings.Join(perfdata, " "))
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
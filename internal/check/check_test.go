@@ -0,0 +1,123 @@
+package check
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, test := range []struct {
+		value   string
+		alert   []float64
+		noAlert []float64
+	}{
+		{"", nil, []float64{-1, 0, 1e9}},
+		{"10", []float64{-1, 10.5}, []float64{0, 10}},
+		{"10:", []float64{9.9}, []float64{10, 1e9}},
+		{"~:10", []float64{11}, []float64{-1e9, 10}},
+		{"10:20", []float64{9, 21}, []float64{10, 20}},
+		{"@10:20", []float64{10, 15, 20}, []float64{9, 21}},
+		{"0", []float64{0.1}, []float64{0}},
+	} {
+		parsed, err := ParseRange(test.value)
+		require.NoError(t, err, test.value)
+		require.Equal(t, test.value, parsed.String())
+		for _, value := range test.alert {
+			require.True(t, parsed.Alert(value), "%s %v", test.value, value)
+		}
+		for _, value := range test.noAlert {
+			require.False(t, parsed.Alert(value), "%s %v", test.value, value)
+		}
+	}
+	parsed, err := ParseRange("~:")
+	require.NoError(t, err)
+	require.Equal(t, math.Inf(-1), parsed.Start)
+	require.Equal(t, math.Inf(1), parsed.End)
+	for _, value := range []string{"a", "20:10", "1:b", "@"} {
+		_, err := ParseRange(value)
+		require.Error(t, err, value)
+	}
+}
+
+func threshold(t *testing.T, warning, critical string) Threshold {
+	t.Helper()
+	threshold := Threshold{}
+	require.NoError(t, threshold.Warning.Set(warning))
+	require.NoError(t, threshold.Critical.Set(critical))
+
+	return threshold
+}
+
+func testStat() statparser.Statistics {
+	return statparser.Statistics{
+		LostFlows:   100,
+		DropPackets: 10,
+		HashMetric:  1.2,
+		SockStatList: []statparser.NFSockEntry{
+			{SockName: "sock0", SockActive: 1, SockSndbuf: 1000, SockSndbufFill: 100, SockErrFull: 5},
+			{SockName: "sock1", SockActive: 1, SockSndbuf: 1000, SockSndbufFill: 850, SockErrCberr: 1, SockErrConnect: 7},
+		},
+	}
+}
+
+func TestCheckTotals(t *testing.T) {
+	opts := Options{
+		InactiveSockets: threshold(t, "", "0"),
+		SndbufFill:      threshold(t, "80", "95"),
+		HashMetric:      threshold(t, "1.5", ""),
+	}
+	stat := testStat()
+	result := Check(nil, &stat, 0, opts)
+	require.Equal(t, StatusWarning, result.Status)
+	require.Equal(t, "IPT_NETFLOW WARNING - sndbuf fill 85% on sock1 (WARNING), lost flows 100, dropped packets 10, "+
+		"socket errors 13, inactive sockets 0 of 2, hash metric 1.2 | lost_flows=100c;;;0; drop_packets=10c;;;0; "+
+		"socket_errors=13c;;;0; inactive_sockets=0;;0;0;2 sndbuf_fill=85%;80;95;0;100 hash_metric=1.2;1.5;;0;", result.String())
+
+	stat.SockStatList[0].SockActive = 0
+	result = Check(nil, &stat, 0, opts)
+	require.Equal(t, StatusCritical, result.Status)
+	require.Contains(t, result.String(), "CRITICAL - inactive sockets 1 of 2 (CRITICAL), sndbuf fill 85% on sock1 (WARNING)")
+
+	// fill may exceed the buffer size and is capped
+	stat.SockStatList[0].SockSndbufFill = 1500
+	result = Check(nil, &stat, 0, opts)
+	require.Contains(t, result.String(), "sndbuf fill 100% on sock0 (CRITICAL)")
+	require.Contains(t, result.String(), "sndbuf_fill=100%;80;95;0;100")
+}
+
+func TestCheckRates(t *testing.T) {
+	opts := Options{
+		Loss:         threshold(t, "", "0"),
+		Drops:        threshold(t, "1", "10"),
+		SocketErrors: threshold(t, "0", ""),
+	}
+	before := testStat()
+	after := testStat()
+	after.DropPackets += 20
+	result := Check(&before, &after, 10*time.Second, opts)
+	require.Equal(t, StatusWarning, result.Status)
+	require.Equal(t, "drop_packets_rate", result.Values[1].Label)
+	require.InDelta(t, 2, result.Values[1].Value, 0)
+	require.Contains(t, result.String(), "WARNING - dropped packets 2/s (WARNING), lost flows 0/s, socket errors 0/s")
+
+	after.LostFlows += 5
+	after.SockStatList[0].SockErrFull++
+	result = Check(&before, &after, 10*time.Second, opts)
+	require.Equal(t, StatusCritical, result.Status)
+	require.Contains(t, result.String(), "lost flows 0.5/s (CRITICAL), dropped packets 2/s (WARNING), socket errors 0.1/s (WARNING)")
+	require.Contains(t, result.String(), "lost_flows_rate=0.5;;0;0;")
+
+	// module reload resets counters
+	after.LostFlows = 3
+	result = Check(&before, &after, 10*time.Second, opts)
+	require.InDelta(t, 0.3, result.Values[0].Value, 1e-9)
+}
+
+func TestUnknown(t *testing.T) {
+	require.Equal(t, "IPT_NETFLOW UNKNOWN - no stat file", Unknown(errors.New("no stat file")))
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scopes of alert metrics. CPU and socket metrics are evaluated for every CPU
+// and socket.
+const (
+	AlertScopeGlobal = "global"
+	AlertScopeCPU    = "cpu"
+	AlertScopeSocket = "socket"
+)
+
+// AlertRule is a threshold alert evaluated by the exporter.
+type AlertRule struct {
+	Name     string `description:"Alert name, unique among rules"                                         yaml:"name"`
+	Expr     string `description:"Condition, e.g. rate(lost_flows) > 0 for 2m or socket_active == 0"      yaml:"expr"`
+	For      string `description:"Duration the condition must hold before the alert fires, e.g. 2m"       yaml:"for,omitempty"`
+	Severity string `description:"Severity label of the alert, e.g. warning or critical"                   yaml:"severity,omitempty"`
+	Summary  string `description:"Summary annotation of the alert"                                        yaml:"summary,omitempty"`
+}
+
+// AlertMetric is a stat field alert expressions refer to by its metric name
+// without namespace. Percent metrics are Field of PercentOf in percent.
+type AlertMetric struct {
+	Scope     string
+	Field     string
+	PercentOf string
+}
+
+// AlertMetrics lists metrics of alert expressions with fields of
+// statparser.Statistics, CPUStat and NFSockEntry.
+var AlertMetrics = map[string]AlertMetric{
+	"in_bit_rate":                 {Scope: AlertScopeGlobal, Field: "InBitRate"},
+	"in_packet_rate":              {Scope: AlertScopeGlobal, Field: "InPacketRate"},
+	"in_flows":                    {Scope: AlertScopeGlobal, Field: "InFlows"},
+	"in_packets":                  {Scope: AlertScopeGlobal, Field: "InPackets"},
+	"in_bytes":                    {Scope: AlertScopeGlobal, Field: "InBytes"},
+	"hash_metrics":                {Scope: AlertScopeGlobal, Field: "HashMetric"},
+	"hash_memory":                 {Scope: AlertScopeGlobal, Field: "HashMemory"},
+	"hash_flows":                  {Scope: AlertScopeGlobal, Field: "HashFlows"},
+	"hash_packets":                {Scope: AlertScopeGlobal, Field: "HashPackets"},
+	"hash_bytes":                  {Scope: AlertScopeGlobal, Field: "HashBytes"},
+	"drop_packets":                {Scope: AlertScopeGlobal, Field: "DropPackets"},
+	"drop_bytes":                  {Scope: AlertScopeGlobal, Field: "DropBytes"},
+	"out_byte_rate":               {Scope: AlertScopeGlobal, Field: "OutByteRate"},
+	"out_flows":                   {Scope: AlertScopeGlobal, Field: "OutFlows"},
+	"out_packets":                 {Scope: AlertScopeGlobal, Field: "OutPackets"},
+	"out_bytes":                   {Scope: AlertScopeGlobal, Field: "OutBytes"},
+	"lost_flows":                  {Scope: AlertScopeGlobal, Field: "LostFlows"},
+	"lost_packets":                {Scope: AlertScopeGlobal, Field: "LostPackets"},
+	"lost_bytes":                  {Scope: AlertScopeGlobal, Field: "LostBytes"},
+	"lost_total":                  {Scope: AlertScopeGlobal, Field: "ErrTotal"},
+	"sndbuf_peak":                 {Scope: AlertScopeGlobal, Field: "SndbufPeak"},
+	"cpu_in_packet_rate":          {Scope: AlertScopeCPU, Field: "CPUInPacketRate"},
+	"cpu_in_flows":                {Scope: AlertScopeCPU, Field: "CPUInFlows"},
+	"cpu_in_packets":              {Scope: AlertScopeCPU, Field: "CPUInPackets"},
+	"cpu_in_bytes":                {Scope: AlertScopeCPU, Field: "CPUInBytes"},
+	"cpu_hash_metric":             {Scope: AlertScopeCPU, Field: "CPUHashMetric"},
+	"cpu_drop_packets":            {Scope: AlertScopeCPU, Field: "CPUDropPackets"},
+	"cpu_drop_bytes":              {Scope: AlertScopeCPU, Field: "CPUuDropBytes"},
+	"cpu_err_trunc":               {Scope: AlertScopeCPU, Field: "CPUErrTrunc"},
+	"cpu_err_flag":                {Scope: AlertScopeCPU, Field: "CPUErrFrag"},
+	"cpu_err_alloc":               {Scope: AlertScopeCPU, Field: "CPUErrAlloc"},
+	"cpu_err_max_flows":           {Scope: AlertScopeCPU, Field: "CPUErrMaxflows"},
+	"socket_active":               {Scope: AlertScopeSocket, Field: "SockActive"},
+	"socket_error_connect":        {Scope: AlertScopeSocket, Field: "SockErrConnect"},
+	"socket_error_full":           {Scope: AlertScopeSocket, Field: "SockErrFull"},
+	"socket_error_cberr":          {Scope: AlertScopeSocket, Field: "SockErrCberr"},
+	"socket_error_other":          {Scope: AlertScopeSocket, Field: "SockErrOther"},
+	"socket_snd_buf":              {Scope: AlertScopeSocket, Field: "SockSndbuf"},
+	"socket_snd_buf_fill":         {Scope: AlertScopeSocket, Field: "SockSndbufFill"},
+	"socket_snd_buf_peak":         {Scope: AlertScopeSocket, Field: "SockSndbufPeak"},
+	"socket_snd_buf_fill_percent": {Scope: AlertScopeSocket, Field: "SockSndbufFill", PercentOf: "SockSndbuf"},
+}
+
+var alertOperators = []string{">", ">=", "<", "<=", "==", "!="}
+
+// alertExpr matches metric or rate(metric), an operator, a threshold with
+// optional percent sign and optional for duration.
+var alertExpr = regexp.MustCompile(`^\s*(?:rate\(\s*(\w+)\s*\)|(\w+))\s*(>=|<=|==|!=|>|<)\s*([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)\s*(%?)(?:\s+for\s+(\S+))?\s*$`)
+
+// AlertExpr is a parsed alert rule condition. Rate is the per second rate
+// between evaluations instead of the current value.
+type AlertExpr struct {
+	Metric    string
+	Rate      bool
+	Operator  string
+	Threshold float64
+	For       time.Duration
+}
+
+// Match compares value with the threshold.
+func (e AlertExpr) Match(value float64) bool {
+	switch e.Operator {
+	case ">":
+		return value > e.Threshold
+	case ">=":
+		return value >= e.Threshold
+	case "<":
+		return value < e.Threshold
+	case "<=":
+		return value <= e.Threshold
+	case "==":
+		return value == e.Threshold
+	default:
+		return value != e.Threshold
+	}
+}
+
+// ParseAlertExpr parses a condition like rate(lost_flows) > 0 for 2m,
+// socket_active == 0 or socket_snd_buf_fill_percent > 90%.
+func ParseAlertExpr(expr string) (AlertExpr, error) {
+	match := alertExpr.FindStringSubmatch(expr)
+	if match == nil {
+		return AlertExpr{}, fmt.Errorf("error incorrect alert expression %q: must be metric or rate(metric), one of %s and a number",
+			expr, strings.Join(alertOperators, " "))
+	}
+	parsed := AlertExpr{Metric: match[2], Rate: match[1] != "", Operator: match[3]}
+	if parsed.Rate {
+		parsed.Metric = match[1]
+	}
+	metric, ok := AlertMetrics[parsed.Metric]
+	if !ok {
+		return AlertExpr{}, fmt.Errorf("error incorrect alert expression %q: unknown metric %s", expr, parsed.Metric)
+	}
+	if match[5] == "%" && metric.PercentOf == "" {
+		return AlertExpr{}, fmt.Errorf("error incorrect alert expression %q: metric %s is not a percent", expr, parsed.Metric)
+	}
+	threshold, err := strconv.ParseFloat(match[4], 64)
+	if err != nil {
+		return AlertExpr{}, fmt.Errorf("error incorrect alert expression %q: %w", expr, err)
+	}
+	parsed.Threshold = threshold
+	if match[6] != "" {
+		if parsed.For, err = parseAlertFor(match[6]); err != nil {
+			return AlertExpr{}, fmt.Errorf("error incorrect alert expression %q: %w", expr, err)
+		}
+	}
+
+	return parsed, nil
+}
+
+// Parse returns the rule condition with the for duration of the rule.
+func (r AlertRule) Parse() (AlertExpr, error) {
+	expr, err := ParseAlertExpr(r.Expr)
+	if err != nil {
+		return expr, err
+	}
+	if r.For == "" {
+		return expr, nil
+	}
+	if expr.For != 0 {
+		return expr, errors.New("error for is set in both expr and for")
+	}
+	expr.For, err = parseAlertFor(r.For)
+
+	return expr, err
+}
+
+func parseAlertFor(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("error incorrect for duration %s: %w", value, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("error incorrect for duration %s: must not be negative", value)
+	}
+
+	return duration, nil
+}
+
+func validateAlertRules(cfg *Config) error {
+	errs := make([]error, 0, len(cfg.Exporter.AlertRules))
+	names := make([]string, 0, len(cfg.Exporter.AlertRules))
+	for i, rule := range cfg.Exporter.AlertRules {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("error incorrect alert rule %d: name is required", i))
+
+			continue
+		}
+		if slices.Contains(names, rule.Name) {
+			errs = append(errs, fmt.Errorf("error incorrect alert rule %s: duplicate name", rule.Name))
+		}
+		names = append(names, rule.Name)
+		if _, err := rule.Parse(); err != nil {
+			errs = append(errs, fmt.Errorf("error incorrect alert rule %s: %w", rule.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -2,33 +2,124 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/creasty/defaults"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/sethvargo/go-envconfig"
 	"gopkg.in/yaml.v3"
 )
 
+// UnixSocketPrefix marks listen addresses which are unix socket paths.
+const UnixSocketPrefix = "unix:"
+
+// Log sink types.
+const (
+	LogSinkStdout   = "stdout"
+	LogSinkStderr   = "stderr"
+	LogSinkFile     = "file"
+	LogSinkSyslog   = "syslog"
+	LogSinkJournald = "journald"
+)
+
+// Access log formats.
+const (
+	AccessLogStructured = "structured"
+	AccessLogCommon     = "common"
+	AccessLogCombined   = "combined"
+	AccessLogOff        = "off"
+)
+
 type Config struct {
 	Logger   Logger   `env:", prefix=EXPORTER_" yaml:"logger"`
 	Exporter Exporter `env:", prefix=EXPORTER_" yaml:"exporter"`
 }
 
 type Logger struct {
-	Format string `default:"json"  env:"LOG_FORMAT" yaml:"format"`
-	Level  string `default:"debug" env:"LOG_LEVEL"  yaml:"level"`
-	File   string `default:""      env:"LOG_FILE"   yaml:"file"`
+	Format          string   `default:"json"  description:"Log format: json, text, logfmt or console"                             env:"LOG_FORMAT"           yaml:"format"`
+	Level           string   `default:"debug" description:"Log level: debug, info, warning or error"                              env:"LOG_LEVEL"            yaml:"level"`
+	File            string   `default:""      description:"Log file path, stdout when empty"                                      env:"LOG_FILE"             yaml:"file"`
+	MaxSize         int      `default:"0"     description:"Rotate log file when it exceeds size in megabytes (0 disables)"        env:"LOG_MAX_SIZE"         yaml:"max_size"`
+	MaxFileAge      int      `default:"0"     description:"Rotate log file when it is older than hours (0 disables)"              env:"LOG_MAX_FILE_AGE"     yaml:"max_file_age"`
+	MaxBackups      int      `default:"0"     description:"Number of rotated log files to keep (0 keeps all)"                     env:"LOG_MAX_BACKUPS"      yaml:"max_backups"`
+	MaxBackupAge    int      `default:"0"     description:"Remove rotated log files older than days (0 keeps all)"                env:"LOG_MAX_BACKUP_AGE"   yaml:"max_backup_age"`
+	Compress        bool     `default:"false" description:"Compress rotated log files with gzip"                                  env:"LOG_COMPRESS"         yaml:"compress"`
+	ComponentLevels []string `default:"[]"    description:"Log levels per component as component=level, e.g. StatCollector=debug" env:"LOG_COMPONENT_LEVELS" yaml:"component_levels"`
+	// Sinks are configured in the config file only.
+	Sinks []LogSink `description:"Log sinks, each with its own format and level, replace file when set" yaml:"sinks"`
+}
+
+// LogSink is a log destination. Empty format and level are inherited from
+// the logger section, file sinks use the logger rotation settings.
+type LogSink struct {
+	Type    string `description:"Sink type: stdout, stderr, file, syslog or journald"                   yaml:"type,omitempty"`
+	Format  string `description:"Log format: json, text, logfmt or console"                             yaml:"format,omitempty"`
+	Level   string `description:"Log level: debug, info, warning or error"                              yaml:"level,omitempty"`
+	File    string `description:"Log file path for file sink"                                           yaml:"file,omitempty"`
+	Network string `description:"Syslog network: unix, unixgram or udp, local syslog socket when empty" yaml:"network,omitempty"`
+	Address string `description:"Syslog address or journald socket path, defaults to local sockets"     yaml:"address,omitempty"`
+	Tag     string `description:"Syslog tag and journald identifier, defaults to ipt-netflow-exporter"  yaml:"tag,omitempty"`
 }
 
 type Exporter struct {
-	ServerAddress        string `default:"localhost"                       env:"HOST"                   yaml:"server_address"`
-	ServerPort           int    `default:"8080"                            env:"PORT"                   yaml:"server_port"`
-	RequestTimeout       int    `default:"10"                              env:"REQUEST_TIMEOUT"        yaml:"request_timeout"`
-	TelemetryPath        string `default:"/metrics"                        env:"TELEMETRY_PATH"         yaml:"telemetry_path"`
-	IPTNetFlowStatFile   string `default:"/proc/net/stat/ipt_netflow_snmp" env:"IPT_NETFLOW_STAT"       yaml:"ipt_netflow_stat"`
-	EnableRuntimeMetrics bool   `default:"false"                           env:"ENABLE_RUNTIME_METRICS" yaml:"enable_runtime_metrics"`
+	ServerAddress           string   `default:"localhost"                       description:"Address to listen on"                                                               env:"HOST"                      yaml:"server_address"`
+	ServerPort              int      `default:"8080"                            description:"Port to listen on"                                                                  env:"PORT"                      yaml:"server_port"`
+	ListenAddresses         []string `default:"[]"                              description:"Listen addresses (host:port or unix:/path), overrides server address and port"      env:"LISTEN_ADDRESSES"          yaml:"listen_addresses"`
+	UnixSocketMode          string   `default:"0660"                            description:"Permissions of unix socket listeners"                                               env:"UNIX_SOCKET_MODE"          yaml:"unix_socket_mode"`
+	SystemdSocket           bool     `default:"false"                           description:"Use sockets passed by systemd socket activation"                                    env:"SYSTEMD_SOCKET"            yaml:"systemd_socket"`
+	RequestTimeout          int      `default:"10"                              description:"HTTP request timeout in seconds"                                                    env:"REQUEST_TIMEOUT"           yaml:"request_timeout"`
+	ScrapeTimeoutOffset     string   `default:"500ms"                           description:"Subtracted from Prometheus scrape timeout to get the stat read deadline"            env:"SCRAPE_TIMEOUT_OFFSET"     yaml:"scrape_timeout_offset"`
+	ShutdownTimeout         int      `default:"10"                              description:"Seconds to wait for in-flight requests on shutdown"                                 env:"SHUTDOWN_TIMEOUT"          yaml:"shutdown_timeout"`
+	TelemetryPath           string   `default:"/metrics"                        description:"Path under which to expose metrics"                                                 env:"TELEMETRY_PATH"            yaml:"telemetry_path"`
+	IPTNetFlowStatFile      string   `default:"/proc/net/stat/ipt_netflow_snmp" description:"Path to ipt_netflow_snmp stat file"                                                 env:"IPT_NETFLOW_STAT"          yaml:"ipt_netflow_stat"`
+	EnableRuntimeMetrics    bool     `default:"false"                           description:"Export Go runtime metrics"                                                          env:"ENABLE_RUNTIME_METRICS"    yaml:"enable_runtime_metrics"`
+	WebConfigFile           string   `default:""                                description:"Path to web config file with TLS and basic auth settings"                           env:"WEB_CONFIG_FILE"           yaml:"web_config_file"`
+	ConfigWatchInterval     int      `default:"0"                               description:"Reload config on file change, check interval in seconds (0 disables)"               env:"CONFIG_WATCH_INTERVAL"     yaml:"config_watch_interval"`
+	LogLevelEndpoint        bool     `default:"false"                           description:"Serve /-/log-level endpoint to change log levels at runtime"                        env:"LOG_LEVEL_ENDPOINT"        yaml:"log_level_endpoint"`
+	AccessLogFormat         string   `default:"structured"                      description:"Access log format: structured, common, combined or off"                             env:"ACCESS_LOG_FORMAT"         yaml:"access_log_format"`
+	AccessLogSampling       int      `default:"1"                               description:"Log every Nth successful request, failed requests are always logged"                env:"ACCESS_LOG_SAMPLING"       yaml:"access_log_sampling"`
+	MaxConcurrentScrapes    int      `default:"0"                               description:"Maximum concurrent scrapes and API requests, 503 when exceeded (0 disables)"        env:"MAX_CONCURRENT_SCRAPES"    yaml:"max_concurrent_scrapes"`
+	ClientRateLimit         int      `default:"0"                               description:"Requests per minute allowed per client IP, 429 when exceeded (0 disables)"          env:"CLIENT_RATE_LIMIT"         yaml:"client_rate_limit"`
+	ClientRateBurst         int      `default:"5"                               description:"Requests a client IP may make at once above its rate"                               env:"CLIENT_RATE_BURST"         yaml:"client_rate_burst"`
+	StreamMaxSubscribers    int      `default:"10"                              description:"Maximum subscribers of /api/v1/stream, 503 when exceeded (0 disables the stream)"   env:"STREAM_MAX_SUBSCRIBERS"    yaml:"stream_max_subscribers"`
+	DebugListenAddress      string   `default:""                                description:"Address of the debug listener with pprof, host:port or unix:/path (empty disables)" env:"DEBUG_LISTEN_ADDRESS"      yaml:"debug_listen_address"`
+	GRPCListenAddress       string   `default:""                                description:"Address of the gRPC listener, host:port or unix:/path (empty disables)"             env:"GRPC_LISTEN_ADDRESS"       yaml:"grpc_listen_address"`
+	AlertEvaluationInterval int      `default:"15"                              description:"Alert rule evaluation interval in seconds"                                          env:"ALERT_EVALUATION_INTERVAL" yaml:"alert_evaluation_interval"`
+	AlertWebhookURLs        []string `default:"[]"                              description:"URLs receiving firing and resolved alerts as JSON"                                  env:"ALERT_WEBHOOK_URLS"        yaml:"alert_webhook_urls"`
+	AlertmanagerURLs        []string `default:"[]"                              description:"Alertmanager URLs receiving alerts with the v2 API, e.g. http://localhost:9093"     env:"ALERTMANAGER_URLS"         yaml:"alertmanager_urls"`
+	AlertResendInterval     int      `default:"60"                              description:"Seconds between repeated sends of firing alerts to Alertmanager"                    env:"ALERT_RESEND_INTERVAL"     yaml:"alert_resend_interval"`
+	// AlertRules are configured in the config file only.
+	AlertRules []AlertRule `description:"Alert rules evaluated by the exporter, alerting is off without rules" yaml:"alert_rules"`
+}
+
+// ScrapeTimeout returns the deadline for reading the stat file in a scrape.
+// scrapeTimeout is the Prometheus scrape timeout, zero when unknown. The
+// offset leaves time to send the response, unless it exceeds the timeout.
+func (e Exporter) ScrapeTimeout(scrapeTimeout time.Duration) time.Duration {
+	if scrapeTimeout <= 0 {
+		return time.Duration(e.RequestTimeout) * time.Second
+	}
+	if offset, err := time.ParseDuration(e.ScrapeTimeoutOffset); err == nil && offset < scrapeTimeout {
+		return scrapeTimeout - offset
+	}
+
+	return scrapeTimeout
+}
+
+// Listeners returns listen_addresses, or server_address:server_port when
+// no list is configured.
+func (e Exporter) Listeners() []string {
+	if len(e.ListenAddresses) > 0 {
+		return e.ListenAddresses
+	}
+
+	return []string{net.JoinHostPort(e.ServerAddress, strconv.Itoa(e.ServerPort))}
 }
 
 func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -49,28 +140,32 @@ func ReadEnv(cfg Config) (Config, error) {
 	return cfg, err
 }
 
+// ReadConfig reads and validates config from defaults, file and environment.
 func ReadConfig(file string) (Config, error) {
-	var err error
-	var cfg Config
-	if file == "" {
-		cfg, err = ReadEnv(getDefault())
-	} else {
-		cfg, err = loadFromFile(file)
-	}
-	if err != nil {
-		return cfg, err
-	}
+	cfg, _, err := Loader{File: file}.Load()
 
-	return ValidateConfig(cfg)
+	return cfg, err
 }
 
-func loadFromFile(file string) (Config, error) {
-	configBytes, err := os.ReadFile(filepath.Clean(file))
+// ReadWebConfig reads TLS and basic auth settings of an exporter-toolkit web
+// config file with the same TLS defaults, relative paths are resolved against
+// the file directory.
+func ReadWebConfig(file string) (*web.Config, error) {
+	content, err := os.ReadFile(filepath.Clean(file))
 	if err != nil {
-		return Config{}, fmt.Errorf("unable to read config: %w", err)
+		return nil, fmt.Errorf("error read web config file %s: %w", file, err)
+	}
+	webConfig := &web.Config{TLSConfig: web.TLSConfig{
+		MinVersion:               tls.VersionTLS12,
+		MaxVersion:               tls.VersionTLS13,
+		PreferServerCipherSuites: true,
+	}}
+	if err := yaml.Unmarshal(content, webConfig); err != nil {
+		return nil, fmt.Errorf("error parse web config file %s: %w", file, err)
 	}
+	webConfig.TLSConfig.SetDirectory(filepath.Dir(file))
 
-	return loadFromBytes(configBytes)
+	return webConfig, nil
 }
 
 func getDefault() Config {
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"encoding/json"
+	"flag"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -78,6 +82,10 @@ func setEnvVars(t *testing.T) {
 			"EXPORTER_IPT_NETFLOW_STAT",
 			"env_file_stat",
 		},
+		{
+			"EXPORTER_LISTEN_ADDRESSES",
+			"[::]:9100,unix:/run/exporter.sock",
+		},
 	}
 	for _, env := range envVars {
 		t.Setenv(env.envName, env.value)
@@ -112,6 +120,7 @@ func TestLoadFromEnv(t *testing.T) {
 	require.Equal(t, "1.2.3.4", cfg.Exporter.ServerAddress)
 	require.Equal(t, 12345, cfg.Exporter.ServerPort)
 	require.Equal(t, "/test_path", cfg.Exporter.TelemetryPath)
+	require.Equal(t, []string{"[::]:9100", "unix:/run/exporter.sock"}, cfg.Exporter.ListenAddresses)
 }
 
 func TestLoadFromFile(t *testing.T) {
@@ -142,7 +151,7 @@ func TestValidators(t *testing.T) {
 
 				return
 			}(),
-			error: "error incorrect log format not_exist",
+			error: "logger.format: error incorrect log format not_exist",
 		},
 		{
 			cfg: func() (cfg Config) {
@@ -151,7 +160,7 @@ func TestValidators(t *testing.T) {
 
 				return
 			}(),
-			error: "error incorrect log level error_log_level",
+			error: "logger.level: error incorrect log level error_log_level",
 		},
 		{
 			cfg: func() (cfg Config) {
@@ -160,7 +169,7 @@ func TestValidators(t *testing.T) {
 
 				return
 			}(),
-			error: "error incorrect ip address error_address",
+			error: "exporter.server_address: error incorrect ip address error_address",
 		},
 		{
 			cfg: func() (cfg Config) {
@@ -169,7 +178,160 @@ func TestValidators(t *testing.T) {
 
 				return
 			}(),
-			error: "error incorrect port number 1234123121",
+			error: "exporter.server_port: error incorrect port number 1234123121",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.ListenAddresses = []string{"[::]:9100", "localhost"}
+
+				return
+			}(),
+			error: "exporter.listen_addresses: error incorrect listen address localhost: address localhost: missing port in address",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.ListenAddresses = []string{"bad_host:9100"}
+
+				return
+			}(),
+			error: "exporter.listen_addresses: error incorrect listen address bad_host:9100: invalid host bad_host",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.ListenAddresses = []string{"0.0.0.0:70000"}
+
+				return
+			}(),
+			error: "exporter.listen_addresses: error incorrect listen address 0.0.0.0:70000: invalid port 70000",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.ListenAddresses = []string{"unix:"}
+
+				return
+			}(),
+			error: "exporter.listen_addresses: error incorrect listen address unix:: empty unix socket path",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.UnixSocketMode = "0999"
+
+				return
+			}(),
+			error: "exporter.unix_socket_mode: error incorrect unix socket mode 0999",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.UnixSocketMode = "4777"
+
+				return
+			}(),
+			error: "exporter.unix_socket_mode: error incorrect unix socket mode 4777: must be at most 0777",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Logger.MaxSize = -1
+				cfg.Logger.MaxBackupAge = -2
+
+				return
+			}(),
+			error: "logger.max_size: error incorrect log max size -1: must not be negative; logger.max_backup_age: error incorrect log max backup age -2: must not be negative",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.ShutdownTimeout = 0
+
+				return
+			}(),
+			error: "exporter.shutdown_timeout: error incorrect shutdown timeout 0: must be positive",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Logger.Sinks = []LogSink{{Type: LogSinkStderr}, {Type: "kafka"}, {Type: LogSinkFile, Level: "warning"}}
+
+				return
+			}(),
+			error: `logger.sinks: error incorrect log sink 1: unknown type "kafka"; logger.sinks: error incorrect log sink 2: file is required for file sink`,
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Logger.Sinks = []LogSink{{Type: LogSinkSyslog, Network: "tcp", Address: "localhost:514"}}
+
+				return
+			}(),
+			error: "logger.sinks: error incorrect log sink 0: incorrect syslog network tcp",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Logger.ComponentLevels = []string{"StatCollector=debug", "exporter-api-server"}
+
+				return
+			}(),
+			error: "logger.component_levels: error incorrect component level exporter-api-server: must be component=level",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.AccessLogFormat = "apache"
+				cfg.Exporter.AccessLogSampling = 0
+
+				return
+			}(),
+			error: "exporter.access_log_format: error incorrect access log format apache; exporter.access_log_sampling: error incorrect access log sampling 0: must be at least 1",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.ClientRateLimit = -1
+				cfg.Exporter.ClientRateBurst = 0
+
+				return
+			}(),
+			error: "exporter.client_rate_limit: error incorrect client rate limit -1: must not be negative; exporter.client_rate_burst: error incorrect client rate burst 0: must be at least 1",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.AlertWebhookURLs = []string{"http://localhost:8000/hook", "localhost:8000"}
+				cfg.Exporter.AlertResendInterval = 0
+
+				return
+			}(),
+			error: "exporter.alert_webhook_urls: error incorrect URL localhost:8000: must be http or https URL with host; exporter.alert_resend_interval: error incorrect alert resend interval 0: must be positive",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.AlertmanagerURLs = []string{"http://localhost:9093", "unix:///run/alertmanager.sock"}
+
+				return
+			}(),
+			error: "exporter.alertmanager_urls: error incorrect URL unix:///run/alertmanager.sock: must be http or https URL with host",
+		},
+		{
+			cfg: func() (cfg Config) {
+				cfg = getDefault()
+				cfg.Exporter.AlertRules = []AlertRule{
+					{Name: "loss", Expr: "rate(lost_flows) > 0 for 2m"},
+					{Name: "loss", Expr: "lost_flows > 0", For: "1m"},
+					{Expr: "socket_active == 0"},
+					{Name: "both_for", Expr: "socket_active == 0 for 1m", For: "1m"},
+				}
+
+				return
+			}(),
+			error: "exporter.alert_rules: error incorrect alert rule loss: duplicate name; exporter.alert_rules: error incorrect alert rule 2: name is required; exporter.alert_rules: error incorrect alert rule both_for: error for is set in both expr and for",
 		},
 	}
 
@@ -179,3 +341,285 @@ func TestValidators(t *testing.T) {
 		require.Equal(t, tCase.error, err.Error())
 	}
 }
+
+func TestListeners(t *testing.T) {
+	cfg := getDefault()
+	require.Equal(t, []string{"localhost:8080"}, cfg.Exporter.Listeners())
+
+	cfg.Exporter.ServerAddress = "::1"
+	require.Equal(t, []string{"[::1]:8080"}, cfg.Exporter.Listeners())
+
+	cfg.Exporter.ServerAddress = "exporter.example.com"
+	_, err := ValidateConfig(cfg)
+	require.NoError(t, err)
+
+	cfg.Exporter.ListenAddresses = []string{":9100", "[::1]:9100", "exporter.example.com:9100", "unix:/run/exporter.sock"}
+	_, err = ValidateConfig(cfg)
+	require.NoError(t, err)
+	require.Equal(t, cfg.Exporter.ListenAddresses, cfg.Exporter.Listeners())
+}
+
+func TestValidateWebConfig(t *testing.T) {
+	webConfig := filepath.Join(t.TempDir(), "web-config.yml")
+	cfg := getDefault()
+	cfg.Exporter.WebConfigFile = webConfig
+
+	_, err := ValidateConfig(cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exporter.web_config_file: error incorrect web config file "+webConfig)
+
+	require.NoError(t, os.WriteFile(webConfig, []byte("tls_server_config:\n  cert_file: not_exist.crt\n  key_file: not_exist.key\n"), 0o600))
+	_, err = ValidateConfig(cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not_exist.crt")
+
+	require.NoError(t, os.WriteFile(webConfig, []byte("basic_auth_users:\n  user: plain_password\n"), 0o600))
+	_, err = ValidateConfig(cfg)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(webConfig, []byte("basic_auth_users:\n  user: $2a$04$eklVOtMOjIeFmOk9ruOCie.OjTQoTqpl06BFeqJPhS77HVCZR6OHe\n"), 0o600))
+	_, err = ValidateConfig(cfg)
+	require.NoError(t, err)
+}
+
+func TestValidateLogLevelEndpoint(t *testing.T) {
+	webConfig := filepath.Join(t.TempDir(), "web-config.yml")
+	cfg := getDefault()
+	cfg.Exporter.LogLevelEndpoint = true
+
+	_, err := ValidateConfig(cfg)
+	require.EqualError(t, err, "exporter.log_level_endpoint: error log level endpoint requires web config file with basic auth users")
+
+	// TLS alone does not authenticate clients
+	cfg.Exporter.WebConfigFile = webConfig
+	require.NoError(t, os.WriteFile(webConfig, []byte("tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n"), 0o600))
+	_, err = ValidateConfig(cfg)
+	require.ErrorContains(t, err, "exporter.log_level_endpoint: error log level endpoint requires basic auth users in web config file "+webConfig)
+
+	require.NoError(t, os.WriteFile(webConfig, []byte("basic_auth_users:\n  user: $2a$04$eklVOtMOjIeFmOk9ruOCie.OjTQoTqpl06BFeqJPhS77HVCZR6OHe\n"), 0o600))
+	_, err = ValidateConfig(cfg)
+	require.NoError(t, err)
+}
+
+func TestLayeredConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0o600))
+	t.Setenv("EXPORTER_PORT", "2020")
+	t.Setenv("EXPORTER_LOG_LEVEL", "warning")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{
+		"--logger.level=error",
+		"--exporter.listen-addresses=:9100",
+		"--exporter.listen-addresses=unix:/run/exporter.sock",
+		"--exporter.enable-runtime-metrics=false",
+	}))
+	loader := Loader{File: configFile, Flags: flags}
+
+	settings, err := loader.Settings()
+	require.NoError(t, err)
+	sources := map[string]Source{}
+	values := map[string]string{}
+	for _, setting := range settings {
+		sources[setting.Path] = setting.Source
+		values[setting.Path] = setting.Value
+	}
+	require.Equal(t, SourceDefault, sources["exporter.shutdown_timeout"])
+	require.Equal(t, SourceFile, sources["exporter.telemetry_path"])
+	require.Equal(t, SourceEnv, sources["exporter.server_port"])
+	require.Equal(t, SourceFlag, sources["logger.level"])
+	require.Equal(t, SourceFlag, sources["exporter.enable_runtime_metrics"])
+	require.Equal(t, "2020", values["exporter.server_port"])
+	require.Equal(t, ":9100,unix:/run/exporter.sock", values["exporter.listen_addresses"])
+
+	cfg, _, _, err := loader.load()
+	require.NoError(t, err)
+	require.Equal(t, "error", cfg.Logger.Level)
+	require.Equal(t, "text", cfg.Logger.Format)
+	require.Equal(t, 2020, cfg.Exporter.ServerPort)
+	require.Equal(t, "/test_conf_path", cfg.Exporter.TelemetryPath)
+	require.False(t, cfg.Exporter.EnableRuntimeMetrics)
+	require.Equal(t, []string{":9100", "unix:/run/exporter.sock"}, cfg.Exporter.ListenAddresses)
+
+	_, _, err = Loader{Flags: map[string]string{"exporter.server_port": "port"}}.Load()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error incorrect flag value --exporter.server-port=port")
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := getDefault()
+	cfg.Logger.Level = "trace"
+	cfg.Logger.File = filepath.Join(t.TempDir(), "not_exist", "exporter.log")
+	cfg.Exporter.RequestTimeout = 0
+	cfg.Exporter.TelemetryPath = "metrics"
+	cfg.Exporter.ListenAddresses = []string{"localhost", ":0"}
+	cfg.Exporter.IPTNetFlowStatFile = filepath.Join(t.TempDir(), "not_exist")
+
+	problems := Validate(cfg)
+	fields := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		require.Equal(t, problem.Field == "exporter.ipt_netflow_stat", problem.Severity == SeverityWarning)
+		fields = append(fields, problem.Field)
+	}
+	require.Equal(t, []string{
+		"logger.level",
+		"logger.file",
+		"exporter.listen_addresses",
+		"exporter.listen_addresses",
+		"exporter.request_timeout",
+		"exporter.telemetry_path",
+		"exporter.ipt_netflow_stat",
+	}, fields)
+
+	_, err := ValidateConfig(cfg)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Problems, 6)
+
+	cfg = getDefault()
+	cfg.Exporter.TelemetryPath = "/"
+	_, err = ValidateConfig(cfg)
+	require.EqualError(t, err, "exporter.telemetry_path: error incorrect telemetry path /: collides with index page")
+
+	// warnings alone do not fail validation
+	cfg = getDefault()
+	cfg.Exporter.IPTNetFlowStatFile = filepath.Join(t.TempDir(), "not_exist")
+	_, err = ValidateConfig(cfg)
+	require.NoError(t, err)
+}
+
+func TestValidateLogFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not_exist")
+	cfg := getDefault()
+	cfg.Logger.File = filepath.Join(dir, "exporter.log")
+	cfg.Logger.Sinks = []LogSink{{Type: LogSinkStdout}, {Type: LogSinkFile, File: filepath.Join(dir, "errors.log")}}
+
+	_, err := ValidateConfig(cfg)
+	require.EqualError(t, err, "logger.file: error incorrect log file "+cfg.Logger.File+": "+dir+" is not writable: no such file or directory; "+
+		"logger.sinks: error incorrect log sink 1: incorrect file "+cfg.Logger.Sinks[1].File+": "+dir+" is not writable: no such file or directory")
+
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	_, err = ValidateConfig(cfg)
+	require.NoError(t, err)
+}
+
+func TestUnknownKeys(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+logger:
+  level: info
+exporter:
+  telemetry_pth: /test
+loger:
+  level: info
+`), 0o600))
+
+	loader := Loader{File: configFile}
+	cfg, problems, err := loader.Load()
+	require.NoError(t, err)
+	require.Equal(t, "info", cfg.Logger.Level)
+	require.Equal(t, "/metrics", cfg.Exporter.TelemetryPath)
+	require.Contains(t, problems, Problem{"exporter.telemetry_pth", SeverityWarning, "unknown config key at line 5"})
+	require.Contains(t, problems, Problem{"loger", SeverityWarning, "unknown config key at line 6"})
+
+	loader.Strict = true
+	_, _, err = loader.Load()
+	require.EqualError(t, err, "exporter.telemetry_pth: unknown config key at line 5; loger: unknown config key at line 6")
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := json.MarshalIndent(Schema(), "", "  ")
+	require.NoError(t, err)
+	expected, err := os.ReadFile("../../docs/config.schema.json")
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(schema), "docs/config.schema.json is outdated, regenerate it with `config schema`")
+
+	exporter := Schema().Properties["exporter"].Properties
+	require.Equal(t, "integer", exporter["server_port"].Type)
+	require.Equal(t, 8080, exporter["server_port"].Default)
+	require.Equal(t, "EXPORTER_PORT", exporter["server_port"].Env)
+	require.Equal(t, logLevels, Schema().Properties["logger"].Properties["level"].Enum)
+	sinks := Schema().Properties["logger"].Properties["sinks"]
+	require.Equal(t, "array", sinks.Type)
+	require.Equal(t, logSinkTypes, sinks.Items.Properties["type"].Enum)
+	require.Empty(t, sinks.Flag)
+}
+
+func TestLogSinks(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+logger:
+  sinks:
+    - type: stdout
+    - type: syslog
+      level: error
+      adress: /dev/log
+`), 0o600))
+
+	loader := Loader{File: configFile}
+	cfg, problems, err := loader.Load()
+	require.NoError(t, err)
+	require.Equal(t, []LogSink{{Type: LogSinkStdout}, {Type: LogSinkSyslog, Level: "error"}}, cfg.Logger.Sinks)
+	require.Contains(t, problems, Problem{"logger.sinks[1].adress", SeverityWarning, "unknown config key at line 7"})
+
+	settings, err := loader.Settings()
+	require.NoError(t, err)
+	for _, setting := range settings {
+		if setting.Path == "logger.sinks" {
+			require.Equal(t, SourceFile, setting.Source)
+			require.Equal(t, "[{type: stdout}, {type: syslog, level: error}]", setting.Value)
+			require.Empty(t, setting.Env)
+			require.Empty(t, setting.Flag)
+		}
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+exporter:
+  alert_rules:
+    - name: FlowLoss
+      expr: rate(lost_flows) > 0
+      for: 2m
+      severity: critical
+    - name: SendBufferFull
+      expr: socket_snd_buf_fill_percent > 90%
+`), 0o600))
+	cfg, _, err := Loader{File: configFile}.Load()
+	require.NoError(t, err)
+	require.Equal(t, []AlertRule{
+		{Name: "FlowLoss", Expr: "rate(lost_flows) > 0", For: "2m", Severity: "critical"},
+		{Name: "SendBufferFull", Expr: "socket_snd_buf_fill_percent > 90%"},
+	}, cfg.Exporter.AlertRules)
+	expr, err := cfg.Exporter.AlertRules[0].Parse()
+	require.NoError(t, err)
+	require.Equal(t, AlertExpr{Metric: "lost_flows", Rate: true, Operator: ">", Threshold: 0, For: 2 * time.Minute}, expr)
+}
+
+func TestParseAlertExpr(t *testing.T) {
+	expr, err := ParseAlertExpr("socket_active==0")
+	require.NoError(t, err)
+	require.Equal(t, AlertExpr{Metric: "socket_active", Operator: "==", Threshold: 0}, expr)
+	require.True(t, expr.Match(0))
+	require.False(t, expr.Match(1))
+
+	expr, err = ParseAlertExpr(" rate( cpu_err_alloc ) >= 1.5e1 for 30s ")
+	require.NoError(t, err)
+	require.Equal(t, AlertExpr{Metric: "cpu_err_alloc", Rate: true, Operator: ">=", Threshold: 15, For: 30 * time.Second}, expr)
+	require.True(t, expr.Match(15))
+
+	for expr, message := range map[string]string{
+		"lost_flows":                        "must be metric or rate(metric)",
+		"not_exist > 1":                     "unknown metric not_exist",
+		"lost_flows > 1%":                   "metric lost_flows is not a percent",
+		"lost_flows > 1 for 2 minutes":      "must be metric or rate(metric)",
+		"lost_flows > 1 for 2x":             "error incorrect for duration 2x",
+		"rate(lost_flows) > 0 and x > 1":    "must be metric or rate(metric)",
+		"socket_snd_buf_fill_percent => 90": "must be metric or rate(metric)",
+	} {
+		_, err := ParseAlertExpr(expr)
+		require.ErrorContains(t, err, message, expr)
+	}
+}
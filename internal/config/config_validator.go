@@ -1,30 +1,144 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/exporter-toolkit/web"
+	"golang.org/x/sys/unix"
 )
 
 type validateFunction func(c *Config) error
 
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a single validation finding for a config field.
+type Problem struct {
+	Field    string   `json:"field"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	return p.Field + ": " + p.Message
+}
+
+// ValidationError holds every error found in the config.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.String())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+type validator struct {
+	field    string
+	severity Severity
+	validate validateFunction
+}
+
 var logLevels = []string{"debug", "info", "warning", "error"}
 
-var logFormats = []string{"text", "json"}
+var logFormats = []string{"text", "json", "logfmt", "console"}
+
+var logSinkTypes = []string{LogSinkStdout, LogSinkStderr, LogSinkFile, LogSinkSyslog, LogSinkJournald}
+
+var accessLogFormats = []string{AccessLogStructured, AccessLogCommon, AccessLogCombined, AccessLogOff}
+
+var syslogNetworks = []string{"", "unix", "unixgram", "udp"}
+
+var isHostname = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.?$`).MatchString
+
+var validatorList = []validator{
+	{"logger.level", SeverityError, validateLogLevel},
+	{"logger.format", SeverityError, validateLogFormat},
+	{"logger.file", SeverityError, validateLogFile},
+	{"logger.max_size", SeverityError, validateLogMaxSize},
+	{"logger.max_file_age", SeverityError, validateLogMaxFileAge},
+	{"logger.max_backups", SeverityError, validateLogMaxBackups},
+	{"logger.max_backup_age", SeverityError, validateLogMaxBackupAge},
+	{"logger.component_levels", SeverityError, validateComponentLevels},
+	{"logger.sinks", SeverityError, validateLogSinks},
+	{"exporter.server_port", SeverityError, validatePort},
+	{"exporter.server_address", SeverityError, validateIP},
+	{"exporter.listen_addresses", SeverityError, validateListenAddresses},
+	{"exporter.unix_socket_mode", SeverityError, validateUnixSocketMode},
+	{"exporter.request_timeout", SeverityError, validateRequestTimeout},
+	{"exporter.scrape_timeout_offset", SeverityError, validateScrapeTimeoutOffset},
+	{"exporter.shutdown_timeout", SeverityError, validateShutdownTimeout},
+	{"exporter.telemetry_path", SeverityError, validateTelemetryPath},
+	{"exporter.ipt_netflow_stat", SeverityWarning, validateStatFile},
+	{"exporter.config_watch_interval", SeverityError, validateConfigWatchInterval},
+	{"exporter.web_config_file", SeverityError, validateWebConfig},
+	{"exporter.log_level_endpoint", SeverityError, validateLogLevelEndpoint},
+	{"exporter.access_log_format", SeverityError, validateAccessLogFormat},
+	{"exporter.access_log_sampling", SeverityError, validateAccessLogSampling},
+	{"exporter.max_concurrent_scrapes", SeverityError, validateMaxConcurrentScrapes},
+	{"exporter.client_rate_limit", SeverityError, validateClientRateLimit},
+	{"exporter.client_rate_burst", SeverityError, validateClientRateBurst},
+	{"exporter.stream_max_subscribers", SeverityError, validateStreamMaxSubscribers},
+	{"exporter.debug_listen_address", SeverityError, validateDebugListenAddress},
+	{"exporter.grpc_listen_address", SeverityError, validateGRPCListenAddress},
+	{"exporter.alert_evaluation_interval", SeverityError, validateAlertEvaluationInterval},
+	{"exporter.alert_webhook_urls", SeverityError, validateAlertWebhookURLs},
+	{"exporter.alertmanager_urls", SeverityError, validateAlertmanagerURLs},
+	{"exporter.alert_resend_interval", SeverityError, validateAlertResendInterval},
+	{"exporter.alert_rules", SeverityError, validateAlertRules},
+}
+
+// Validate runs every validator and returns all problems found. Errors
+// joined with errors.Join are reported as separate problems.
+func Validate(cfg Config) []Problem {
+	var problems []Problem
+	for _, v := range validatorList {
+		err := v.validate(&cfg)
+		if err == nil {
+			continue
+		}
+		errs := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint
+			errs = joined.Unwrap()
+		}
+		for _, err := range errs {
+			problems = append(problems, Problem{Field: v.field, Severity: v.severity, Message: err.Error()})
+		}
+	}
 
-var validatorList = []validateFunction{
-	validateLogLevel,
-	validatePort,
-	validateIP,
-	validateLogFormat,
+	return problems
 }
 
+// ValidateConfig returns a *ValidationError with all errors found in cfg.
+// Warnings do not fail validation, see Validate.
 func ValidateConfig(cfg Config) (Config, error) {
-	for _, vFunc := range validatorList {
-		if err := vFunc(&cfg); err != nil {
-			return cfg, err
+	var errs []Problem
+	for _, problem := range Validate(cfg) {
+		if problem.Severity == SeverityError {
+			errs = append(errs, problem)
 		}
 	}
+	if len(errs) > 0 {
+		return cfg, &ValidationError{Problems: errs}
+	}
 
 	return cfg, nil
 }
@@ -45,14 +159,126 @@ func validatePort(cfg *Config) error {
 	return nil
 }
 
+func validHost(host string) bool {
+	return net.ParseIP(host) != nil || isHostname(host)
+}
+
 func validateIP(cfg *Config) error {
-	if cfg.Exporter.ServerAddress != "localhost" && net.ParseIP(cfg.Exporter.ServerAddress) == nil {
+	if !validHost(cfg.Exporter.ServerAddress) {
 		return fmt.Errorf("error incorrect ip address %s", cfg.Exporter.ServerAddress)
 	}
 
 	return nil
 }
 
+func validateListenAddresses(cfg *Config) error {
+	errs := make([]error, 0, len(cfg.Exporter.ListenAddresses))
+	for _, address := range cfg.Exporter.ListenAddresses {
+		errs = append(errs, validateListenAddress(address))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateListenAddress(address string) error {
+	if path, ok := strings.CutPrefix(address, UnixSocketPrefix); ok {
+		if path == "" {
+			return fmt.Errorf("error incorrect listen address %s: empty unix socket path", address)
+		}
+
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("error incorrect listen address %s: %w", address, err)
+	}
+	if host != "" && !validHost(host) {
+		return fmt.Errorf("error incorrect listen address %s: invalid host %s", address, host)
+	}
+	if portNum, err := strconv.Atoi(port); err != nil || portNum < 1 || portNum > 65535 {
+		return fmt.Errorf("error incorrect listen address %s: invalid port %s", address, port)
+	}
+
+	return nil
+}
+
+func validateStreamMaxSubscribers(cfg *Config) error {
+	if cfg.Exporter.StreamMaxSubscribers < 0 {
+		return fmt.Errorf("error incorrect stream max subscribers %d: must not be negative", cfg.Exporter.StreamMaxSubscribers)
+	}
+
+	return nil
+}
+
+func validateDebugListenAddress(cfg *Config) error {
+	if cfg.Exporter.DebugListenAddress == "" {
+		return nil
+	}
+
+	return validateListenAddress(cfg.Exporter.DebugListenAddress)
+}
+
+func validateGRPCListenAddress(cfg *Config) error {
+	if cfg.Exporter.GRPCListenAddress == "" {
+		return nil
+	}
+
+	return validateListenAddress(cfg.Exporter.GRPCListenAddress)
+}
+
+func validateAlertEvaluationInterval(cfg *Config) error {
+	if cfg.Exporter.AlertEvaluationInterval <= 0 {
+		return fmt.Errorf("error incorrect alert evaluation interval %d: must be positive", cfg.Exporter.AlertEvaluationInterval)
+	}
+
+	return nil
+}
+
+func validateAlertResendInterval(cfg *Config) error {
+	if cfg.Exporter.AlertResendInterval <= 0 {
+		return fmt.Errorf("error incorrect alert resend interval %d: must be positive", cfg.Exporter.AlertResendInterval)
+	}
+
+	return nil
+}
+
+func validateAlertWebhookURLs(cfg *Config) error {
+	return validateHTTPURLs(cfg.Exporter.AlertWebhookURLs)
+}
+
+func validateAlertmanagerURLs(cfg *Config) error {
+	return validateHTTPURLs(cfg.Exporter.AlertmanagerURLs)
+}
+
+func validateHTTPURLs(urls []string) error {
+	errs := make([]error, 0, len(urls))
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error incorrect URL %s: %w", rawURL, err))
+
+			continue
+		}
+		if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("error incorrect URL %s: must be http or https URL with host", rawURL))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateUnixSocketMode(cfg *Config) error {
+	mode, err := strconv.ParseUint(cfg.Exporter.UnixSocketMode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("error incorrect unix socket mode %s", cfg.Exporter.UnixSocketMode)
+	}
+	if mode > 0o777 {
+		return fmt.Errorf("error incorrect unix socket mode %s: must be at most 0777", cfg.Exporter.UnixSocketMode)
+	}
+
+	return nil
+}
+
 func validateLogFormat(cfg *Config) error {
 	if !slices.Contains(logFormats, cfg.Logger.Format) {
 		return fmt.Errorf("error incorrect log format %s", cfg.Logger.Format)
@@ -60,3 +286,250 @@ func validateLogFormat(cfg *Config) error {
 
 	return nil
 }
+
+func validateLogMaxSize(cfg *Config) error {
+	if cfg.Logger.MaxSize < 0 {
+		return fmt.Errorf("error incorrect log max size %d: must not be negative", cfg.Logger.MaxSize)
+	}
+
+	return nil
+}
+
+func validateLogMaxFileAge(cfg *Config) error {
+	if cfg.Logger.MaxFileAge < 0 {
+		return fmt.Errorf("error incorrect log max file age %d: must not be negative", cfg.Logger.MaxFileAge)
+	}
+
+	return nil
+}
+
+func validateLogMaxBackups(cfg *Config) error {
+	if cfg.Logger.MaxBackups < 0 {
+		return fmt.Errorf("error incorrect log max backups %d: must not be negative", cfg.Logger.MaxBackups)
+	}
+
+	return nil
+}
+
+func validateLogMaxBackupAge(cfg *Config) error {
+	if cfg.Logger.MaxBackupAge < 0 {
+		return fmt.Errorf("error incorrect log max backup age %d: must not be negative", cfg.Logger.MaxBackupAge)
+	}
+
+	return nil
+}
+
+// ParseComponentLevels parses log levels per component given as
+// component=level.
+func ParseComponentLevels(values []string) (map[string]string, error) {
+	components := make(map[string]string, len(values))
+	for _, value := range values {
+		component, level, ok := strings.Cut(value, "=")
+		if !ok || component == "" {
+			return nil, fmt.Errorf("error incorrect component level %s: must be component=level", value)
+		}
+		if !slices.Contains(logLevels, level) {
+			return nil, fmt.Errorf("error incorrect component level %s: unknown level %s", value, level)
+		}
+		components[component] = level
+	}
+
+	return components, nil
+}
+
+func validateComponentLevels(cfg *Config) error {
+	_, err := ParseComponentLevels(cfg.Logger.ComponentLevels)
+
+	return err
+}
+
+func validateLogSinks(cfg *Config) error {
+	errs := make([]error, 0, len(cfg.Logger.Sinks))
+	for i, sink := range cfg.Logger.Sinks {
+		if err := validateLogSink(sink); err != nil {
+			errs = append(errs, fmt.Errorf("error incorrect log sink %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateLogSink(sink LogSink) error {
+	if !slices.Contains(logSinkTypes, sink.Type) {
+		return fmt.Errorf("unknown type %q", sink.Type)
+	}
+	if sink.Format != "" && !slices.Contains(logFormats, sink.Format) {
+		return fmt.Errorf("incorrect format %s", sink.Format)
+	}
+	if sink.Level != "" && !slices.Contains(logLevels, sink.Level) {
+		return fmt.Errorf("incorrect level %s", sink.Level)
+	}
+	if sink.Type == LogSinkFile && sink.File == "" {
+		return errors.New("file is required for file sink")
+	}
+	if sink.Type == LogSinkFile {
+		if err := checkWritable(sink.File); err != nil {
+			return fmt.Errorf("incorrect file %s: %w", sink.File, err)
+		}
+	}
+	if sink.Type == LogSinkSyslog && !slices.Contains(syslogNetworks, sink.Network) {
+		return fmt.Errorf("incorrect syslog network %s", sink.Network)
+	}
+	if sink.Type == LogSinkSyslog && sink.Network != "" && sink.Address == "" {
+		return fmt.Errorf("address is required for syslog network %s", sink.Network)
+	}
+
+	return nil
+}
+
+func validateRequestTimeout(cfg *Config) error {
+	if cfg.Exporter.RequestTimeout <= 0 {
+		return fmt.Errorf("error incorrect request timeout %d: must be positive", cfg.Exporter.RequestTimeout)
+	}
+
+	return nil
+}
+
+func validateScrapeTimeoutOffset(cfg *Config) error {
+	offset, err := time.ParseDuration(cfg.Exporter.ScrapeTimeoutOffset)
+	if err != nil {
+		return fmt.Errorf("error incorrect scrape timeout offset %s: %w", cfg.Exporter.ScrapeTimeoutOffset, err)
+	}
+	if offset < 0 {
+		return fmt.Errorf("error incorrect scrape timeout offset %s: must not be negative", cfg.Exporter.ScrapeTimeoutOffset)
+	}
+
+	return nil
+}
+
+func validateTelemetryPath(cfg *Config) error {
+	if !strings.HasPrefix(cfg.Exporter.TelemetryPath, "/") {
+		return fmt.Errorf("error incorrect telemetry path %s: must start with /", cfg.Exporter.TelemetryPath)
+	}
+	if cfg.Exporter.TelemetryPath == "/" {
+		return errors.New("error incorrect telemetry path /: collides with index page")
+	}
+
+	return nil
+}
+
+// validateStatFile is a warning: the exporter starts without the stat file,
+// e.g. when ipt_NETFLOW module is loaded after it.
+func validateStatFile(cfg *Config) error {
+	file, err := os.Open(cfg.Exporter.IPTNetFlowStatFile)
+	if err != nil {
+		return fmt.Errorf("stat file %s is not readable: %w", cfg.Exporter.IPTNetFlowStatFile, err)
+	}
+
+	return file.Close()
+}
+
+func validateLogFile(cfg *Config) error {
+	if cfg.Logger.File == "" {
+		return nil
+	}
+	if err := checkWritable(cfg.Logger.File); err != nil {
+		return fmt.Errorf("error incorrect log file %s: %w", cfg.Logger.File, err)
+	}
+
+	return nil
+}
+
+// checkWritable checks that file can be written, or created when it does
+// not exist.
+func checkWritable(file string) error {
+	path := filepath.Clean(file)
+	if _, err := os.Stat(path); err != nil {
+		// file will be created, so its directory must be writable
+		path = filepath.Dir(path)
+	}
+	if err := unix.Access(path, unix.W_OK); err != nil {
+		return fmt.Errorf("%s is not writable: %w", path, err)
+	}
+
+	return nil
+}
+
+func validateShutdownTimeout(cfg *Config) error {
+	if cfg.Exporter.ShutdownTimeout <= 0 {
+		return fmt.Errorf("error incorrect shutdown timeout %d: must be positive", cfg.Exporter.ShutdownTimeout)
+	}
+
+	return nil
+}
+
+func validateConfigWatchInterval(cfg *Config) error {
+	if cfg.Exporter.ConfigWatchInterval < 0 {
+		return fmt.Errorf("error incorrect config watch interval %d", cfg.Exporter.ConfigWatchInterval)
+	}
+
+	return nil
+}
+
+func validateWebConfig(cfg *Config) error {
+	if err := web.Validate(cfg.Exporter.WebConfigFile); err != nil {
+		return fmt.Errorf("error incorrect web config file %s: %w", cfg.Exporter.WebConfigFile, err)
+	}
+
+	return nil
+}
+
+// validateLogLevelEndpoint requires web config basic auth users for the log
+// level endpoint, otherwise anyone reaching the exporter may change log levels.
+func validateLogLevelEndpoint(cfg *Config) error {
+	if !cfg.Exporter.LogLevelEndpoint {
+		return nil
+	}
+	if cfg.Exporter.WebConfigFile == "" {
+		return errors.New("error log level endpoint requires web config file with basic auth users")
+	}
+	webConfig, err := ReadWebConfig(cfg.Exporter.WebConfigFile)
+	if err != nil {
+		return err
+	}
+	if len(webConfig.Users) == 0 {
+		return fmt.Errorf("error log level endpoint requires basic auth users in web config file %s", cfg.Exporter.WebConfigFile)
+	}
+
+	return nil
+}
+
+func validateAccessLogFormat(cfg *Config) error {
+	if !slices.Contains(accessLogFormats, cfg.Exporter.AccessLogFormat) {
+		return fmt.Errorf("error incorrect access log format %s", cfg.Exporter.AccessLogFormat)
+	}
+
+	return nil
+}
+
+func validateAccessLogSampling(cfg *Config) error {
+	if cfg.Exporter.AccessLogSampling < 1 {
+		return fmt.Errorf("error incorrect access log sampling %d: must be at least 1", cfg.Exporter.AccessLogSampling)
+	}
+
+	return nil
+}
+
+func validateMaxConcurrentScrapes(cfg *Config) error {
+	if cfg.Exporter.MaxConcurrentScrapes < 0 {
+		return fmt.Errorf("error incorrect max concurrent scrapes %d: must not be negative", cfg.Exporter.MaxConcurrentScrapes)
+	}
+
+	return nil
+}
+
+func validateClientRateLimit(cfg *Config) error {
+	if cfg.Exporter.ClientRateLimit < 0 {
+		return fmt.Errorf("error incorrect client rate limit %d: must not be negative", cfg.Exporter.ClientRateLimit)
+	}
+
+	return nil
+}
+
+func validateClientRateBurst(cfg *Config) error {
+	if cfg.Exporter.ClientRateBurst < 1 {
+		return fmt.Errorf("error incorrect client rate burst %d: must be at least 1", cfg.Exporter.ClientRateBurst)
+	}
+
+	return nil
+}
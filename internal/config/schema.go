@@ -0,0 +1,113 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// enumValues lists allowed values of settings validated against a fixed set.
+var enumValues = map[string][]string{
+	"logger.level":               logLevels,
+	"logger.format":              logFormats,
+	"logger.sinks[].type":        logSinkTypes,
+	"exporter.access_log_format": accessLogFormats,
+	"logger.sinks[].format":      logFormats,
+	"logger.sinks[].level":       logLevels,
+}
+
+// JSONSchema is a subset of JSON Schema used to describe the config file.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Env                  string                 `json:"x-env,omitempty"`
+	Flag                 string                 `json:"x-flag,omitempty"`
+}
+
+// Schema generates JSON Schema of the config file from Config struct tags.
+func Schema() *JSONSchema {
+	noAdditional := false
+	root := &JSONSchema{
+		Schema:               schemaDraft,
+		Title:                "ipt-netflow-exporter config",
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{},
+		AdditionalProperties: &noAdditional,
+	}
+	for _, setting := range settingsOf(&Config{}) {
+		sectionName, key, _ := strings.Cut(setting.Path, ".")
+		section, ok := root.Properties[sectionName]
+		if !ok {
+			section = &JSONSchema{
+				Type:                 "object",
+				Properties:           map[string]*JSONSchema{},
+				AdditionalProperties: &noAdditional,
+			}
+			root.Properties[sectionName] = section
+		}
+		section.Properties[key] = settingSchema(setting)
+	}
+
+	return root
+}
+
+func settingSchema(setting Setting) *JSONSchema {
+	schema := &JSONSchema{
+		Description: setting.Description,
+		Enum:        enumValues[setting.Path],
+		Env:         setting.Env,
+	}
+	if setting.Flag != "" {
+		schema.Flag = "--" + setting.Flag
+	}
+	switch setting.field.Kind() { //nolint
+	case reflect.Int:
+		schema.Type = "integer"
+		schema.Default, _ = strconv.Atoi(setting.Default)
+	case reflect.Bool:
+		schema.Type = "boolean"
+		schema.Default, _ = strconv.ParseBool(setting.Default)
+	case reflect.Slice:
+		schema.Type = "array"
+		schema.Items = &JSONSchema{Type: "string"}
+		if elem := setting.field.Type().Elem(); elem.Kind() == reflect.Struct {
+			schema.Items = objectSchema(setting.Path+"[]", elem)
+		}
+		schema.Default = []string{}
+	default:
+		schema.Type = "string"
+		schema.Default = setting.Default
+	}
+
+	return schema
+}
+
+// objectSchema describes list items with string fields, e.g. log sinks.
+func objectSchema(path string, object reflect.Type) *JSONSchema {
+	noAdditional := false
+	schema := &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{},
+		AdditionalProperties: &noAdditional,
+	}
+	for i := range object.NumField() {
+		field := object.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		schema.Properties[name] = &JSONSchema{
+			Description: field.Tag.Get("description"),
+			Type:        "string",
+			Enum:        enumValues[path+"."+name],
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source tells where the effective value of a setting came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Setting describes one config field: its names in every source, its
+// effective value and the source of that value.
+type Setting struct {
+	Path        string
+	Env         string
+	Flag        string
+	Default     string
+	Description string
+	Value       string
+	Source      Source
+	field       reflect.Value
+}
+
+// Loader reads the config merging sources in order of precedence:
+// defaults < config file < environment variables < command line flags.
+type Loader struct {
+	File string
+	// Flags holds command line values keyed by setting path, see RegisterFlags.
+	Flags map[string]string
+	// Strict makes unknown config file keys errors instead of warnings.
+	Strict bool
+}
+
+// Load reads and validates the config. Warnings are returned along with
+// the config, they do not fail loading.
+func (l Loader) Load() (Config, []Problem, error) {
+	cfg, problems, err := l.Problems()
+	if err != nil {
+		return cfg, nil, err
+	}
+	var errs, warnings []Problem
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			errs = append(errs, problem)
+		} else {
+			warnings = append(warnings, problem)
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, warnings, &ValidationError{Problems: errs}
+	}
+
+	return cfg, warnings, nil
+}
+
+// Problems reads the config and returns every problem found: unknown config
+// file keys and validation errors and warnings.
+func (l Loader) Problems() (Config, []Problem, error) {
+	cfg, _, unknownKeys, err := l.load()
+	if err != nil {
+		return cfg, nil, err
+	}
+	severity := SeverityWarning
+	if l.Strict {
+		severity = SeverityError
+	}
+	problems := make([]Problem, 0, len(unknownKeys))
+	for _, key := range unknownKeys {
+		problems = append(problems, Problem{
+			Field:    key.path,
+			Severity: severity,
+			Message:  fmt.Sprintf("unknown config key at line %d", key.line),
+		})
+	}
+
+	return cfg, append(problems, Validate(cfg)...), nil
+}
+
+// Settings reads the config without validation and returns every setting
+// with its effective value and source.
+func (l Loader) Settings() ([]Setting, error) {
+	_, settings, _, err := l.load()
+
+	return settings, err
+}
+
+func (l Loader) load() (Config, []Setting, []fileKey, error) {
+	cfg := getDefault()
+	fileKeys := map[string]bool{}
+	var unknownKeys []fileKey
+	if l.File != "" {
+		configBytes, err := os.ReadFile(filepath.Clean(l.File))
+		if err != nil {
+			return Config{}, nil, nil, fmt.Errorf("unable to read config: %w", err)
+		}
+		if cfg, err = loadFromBytes(configBytes); err != nil {
+			return Config{}, nil, nil, err
+		}
+		if fileKeys, unknownKeys, err = readFileKeys(configBytes); err != nil {
+			return Config{}, nil, nil, err
+		}
+	}
+	cfg, err := ReadEnv(cfg)
+	if err != nil {
+		return cfg, nil, nil, err
+	}
+
+	settings := settingsOf(&cfg)
+	for i := range settings {
+		setting := &settings[i]
+		if setting.Flag == "" {
+			// file only setting
+			if fileKeys[setting.Path] {
+				setting.Source = SourceFile
+			}
+		} else if raw, ok := l.Flags[setting.Path]; ok {
+			if err := setFieldValue(setting.field, raw); err != nil {
+				return cfg, nil, nil, fmt.Errorf("error incorrect flag value --%s=%s: %w", setting.Flag, raw, err)
+			}
+			setting.Source = SourceFlag
+		} else if _, ok := os.LookupEnv(setting.Env); ok {
+			setting.Source = SourceEnv
+		} else if fileKeys[setting.Path] {
+			setting.Source = SourceFile
+		}
+		setting.Value = formatFieldValue(setting.field)
+	}
+
+	return cfg, settings, unknownKeys, nil
+}
+
+// RegisterFlags adds a flag for every config setting to fs, e.g.
+// --exporter.server-port for exporter.server_port. Values of flags given on
+// the command line are stored in the returned map for Loader.Flags.
+func RegisterFlags(fs *flag.FlagSet) map[string]string {
+	values := map[string]string{}
+	for _, setting := range settingsOf(&Config{}) {
+		if setting.Flag == "" {
+			continue
+		}
+		path := setting.Path
+		isList := setting.field.Kind() == reflect.Slice
+		usage := fmt.Sprintf("%s (env %s, default %q)", setting.Description, setting.Env, setting.Default)
+		setValue := func(value string) error {
+			if prev, ok := values[path]; ok && isList {
+				value = prev + "," + value
+			}
+			values[path] = value
+
+			return nil
+		}
+		if setting.field.Kind() == reflect.Bool {
+			fs.BoolFunc(setting.Flag, usage, setValue)
+		} else {
+			fs.Func(setting.Flag, usage, setValue)
+		}
+	}
+
+	return values
+}
+
+// settingsOf walks config sections and returns settings bound to cfg fields.
+// Fields without env tag, e.g. logger.sinks, are set in the config file only
+// and have neither env variable nor flag.
+func settingsOf(cfg *Config) []Setting {
+	settings := make([]Setting, 0, 32)
+	cfgVal := reflect.ValueOf(cfg).Elem()
+	for i := range cfgVal.NumField() {
+		section := cfgVal.Type().Field(i)
+		envPrefix := strings.TrimPrefix(section.Tag.Get("env"), ", prefix=")
+		sectionVal := cfgVal.Field(i)
+		for j := range sectionVal.NumField() {
+			field := sectionVal.Type().Field(j)
+			path := section.Tag.Get("yaml") + "." + field.Tag.Get("yaml")
+			setting := Setting{
+				Path:        path,
+				Default:     field.Tag.Get("default"),
+				Description: field.Tag.Get("description"),
+				Source:      SourceDefault,
+				field:       sectionVal.Field(j),
+			}
+			if env := field.Tag.Get("env"); env != "" {
+				setting.Env = envPrefix + env
+				setting.Flag = strings.ReplaceAll(path, "_", "-")
+			}
+			settings = append(settings, setting)
+		}
+	}
+
+	return settings
+}
+
+func setFieldValue(field reflect.Value, raw string) error {
+	switch field.Kind() { //nolint
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case reflect.Slice:
+		var values []string
+		if raw != "" {
+			values = strings.Split(raw, ",")
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Kind().String())
+	}
+
+	return nil
+}
+
+func formatFieldValue(field reflect.Value) string {
+	if values, ok := field.Interface().([]string); ok {
+		return strings.Join(values, ",")
+	}
+	if field.Kind() == reflect.Slice {
+		// list of objects is printed in YAML flow style
+		var node yaml.Node
+		if err := node.Encode(field.Interface()); err != nil {
+			return fmt.Sprint(field.Interface())
+		}
+		node.Style = yaml.FlowStyle
+		out, err := yaml.Marshal(&node)
+		if err != nil {
+			return fmt.Sprint(field.Interface())
+		}
+
+		return strings.TrimSpace(string(out))
+	}
+
+	return fmt.Sprint(field.Interface())
+}
+
+type fileKey struct {
+	path string
+	line int
+}
+
+// readFileKeys returns paths of config settings set in the config file and
+// keys which do not match any setting, e.g. misspelled telemetry_pth.
+func readFileKeys(data []byte) (map[string]bool, []fileKey, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("unable to unmarshal config: %w", err)
+	}
+	known := map[string]bool{}
+	for _, setting := range settingsOf(&Config{}) {
+		section, _, _ := strings.Cut(setting.Path, ".")
+		known[section] = true
+		known[setting.Path] = true
+		// keys of list items, e.g. logger.sinks[].type
+		if setting.field.Kind() != reflect.Slice {
+			continue
+		}
+		if elem := setting.field.Type().Elem(); elem.Kind() == reflect.Struct {
+			for k := range elem.NumField() {
+				name, _, _ := strings.Cut(elem.Field(k).Tag.Get("yaml"), ",")
+				known[setting.Path+"[]."+name] = true
+			}
+		}
+	}
+
+	keys := map[string]bool{}
+	var unknown []fileKey
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return keys, unknown, nil
+	}
+	sections := root.Content[0].Content
+	for i := 0; i+1 < len(sections); i += 2 {
+		sectionName := sections[i].Value
+		if !known[sectionName] {
+			unknown = append(unknown, fileKey{sectionName, sections[i].Line})
+
+			continue
+		}
+		section := sections[i+1]
+		if section.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(section.Content); j += 2 {
+			path := sectionName + "." + section.Content[j].Value
+			if !known[path] {
+				unknown = append(unknown, fileKey{path, section.Content[j].Line})
+
+				continue
+			}
+			keys[path] = true
+			unknown = append(unknown, unknownItemKeys(path, section.Content[j+1], known)...)
+		}
+	}
+
+	return keys, unknown, nil
+}
+
+// unknownItemKeys returns keys of list items which do not match any field.
+func unknownItemKeys(path string, value *yaml.Node, known map[string]bool) []fileKey {
+	if value.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var unknown []fileKey
+	for i, item := range value.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			if !known[path+"[]."+item.Content[j].Value] {
+				unknown = append(unknown, fileKey{fmt.Sprintf("%s[%d].%s", path, i, item.Content[j].Value), item.Content[j].Line})
+			}
+		}
+	}
+
+	return unknown
+}
@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	accessLogComponent = "AccessLog"
+	clfTimeFormat      = "02/Jan/2006:15:04:05 -0700"
+)
+
+type httpMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newHTTPMetrics() *httpMetrics {
+	return &httpMetrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "http_requests_total",
+				Help:      "Total number of HTTP requests by handler, method and status code.",
+			},
+			[]string{"handler", "method", "code"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "http_request_duration_seconds",
+				Help:      "Duration of HTTP requests by handler and method.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"handler", "method"},
+		),
+	}
+}
+
+func (m *httpMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+func (m *httpMetrics) Collect(metricChan chan<- prometheus.Metric) {
+	m.requests.Collect(metricChan)
+	m.duration.Collect(metricChan)
+}
+
+// responseRecorder captures status code and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// accessLog logs completed requests of the handler set and records HTTP
+// metrics. With sampling N only every N-th successful request is logged,
+// requests which failed with 4xx or 5xx status are always logged.
+type accessLog struct {
+	format   string
+	sampling uint64
+	counter  atomic.Uint64
+	log      *logger.Logger
+	metrics  *httpMetrics
+}
+
+func newAccessLog(cfg config.Exporter, metrics *httpMetrics) *accessLog {
+	return &accessLog{
+		format:   cfg.AccessLogFormat,
+		sampling: uint64(max(cfg.AccessLogSampling, 1)),
+		log:      logger.GetLogger().With(slog.String(logger.Component, accessLogComponent)),
+		metrics:  metrics,
+	}
+}
+
+// wrap instruments a route, handler is the route name used in metric labels.
+func (a *accessLog) wrap(handler string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, req)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		duration := time.Since(start)
+
+		a.metrics.requests.WithLabelValues(handler, req.Method, strconv.Itoa(recorder.status)).Inc()
+		a.metrics.duration.WithLabelValues(handler, req.Method).Observe(duration.Seconds())
+		if a.sampled(recorder.status) {
+			a.write(handler, req, recorder, start, duration)
+		}
+	})
+}
+
+func (a *accessLog) sampled(status int) bool {
+	if a.format == config.AccessLogOff {
+		return false
+	}
+
+	return status >= http.StatusBadRequest || a.counter.Add(1)%a.sampling == 0
+}
+
+func (a *accessLog) write(handler string, req *http.Request, recorder *responseRecorder, start time.Time, duration time.Duration) {
+	switch a.format {
+	case config.AccessLogCommon, config.AccessLogCombined:
+		line := fmt.Sprintf("%s - %s [%s] %q %d %d",
+			remoteHost(req.RemoteAddr), dashIfEmpty(remoteUser(req)), start.Format(clfTimeFormat),
+			req.Method+" "+req.URL.RequestURI()+" "+req.Proto, recorder.status, recorder.bytes)
+		if a.format == config.AccessLogCombined {
+			line += fmt.Sprintf(" %q %q", req.Referer(), req.UserAgent())
+		}
+		a.log.Info(line)
+	default:
+		a.log.Info("http request",
+			slog.String("handler", handler),
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Int("bytes", recorder.bytes),
+			slog.Duration(logger.DurationKey, duration),
+			slog.String("addr", req.RemoteAddr),
+			slog.String("agent", req.UserAgent()),
+		)
+	}
+}
+
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return dashIfEmpty(addr)
+}
+
+func remoteUser(req *http.Request) string {
+	user, _, _ := req.BasicAuth()
+
+	return user
+}
+
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,569 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	alertStatePending   = "pending"
+	alertStateFiring    = "firing"
+	alertStatusResolved = "resolved"
+	// alertmanagerEndsAtFactor sets endsAt of firing alerts sent to
+	// Alertmanager that many resend intervals ahead, so that alerts resolve
+	// when the exporter stops sending them, as Prometheus does.
+	alertmanagerEndsAtFactor = 4
+	webhookPayloadVersion    = "1"
+)
+
+type alertRule struct {
+	config.AlertRule
+	expr config.AlertExpr
+}
+
+// alertInstance is a rule whose condition holds for the global values, a CPU
+// or a socket.
+type alertInstance struct {
+	rule     *alertRule
+	labels   map[string]string
+	value    float64
+	activeAt time.Time
+	firing   bool
+}
+
+// alertValue is the value of a rule metric for the global values, a CPU or
+// a socket, ok is false when a rate cannot be computed yet.
+type alertValue struct {
+	labels map[string]string
+	value  float64
+	ok     bool
+}
+
+// alertNotification is an alert in webhook payloads and, without value, in
+// Alertmanager v2 API requests.
+type alertNotification struct {
+	Status      string            `json:"status,omitempty"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// webhookPayload is the body of webhook requests, alerts which started
+// firing or resolved since the previous notification.
+type webhookPayload struct {
+	Version string              `json:"version"`
+	Status  string              `json:"status"`
+	Alerts  []alertNotification `json:"alerts"`
+}
+
+// alertEngine evaluates alert rules on a background read of the stat file
+// and sends firing and resolved alerts. It lives as long as the server,
+// rules are replaced on config reload keeping the state of unchanged rules.
+type alertEngine struct {
+	mu        sync.Mutex
+	config    config.Exporter
+	rules     []*alertRule
+	instances map[string]*alertInstance
+	prev      *streamSample
+	// lastResend is the last time all firing alerts were sent to Alertmanager.
+	lastResend time.Time
+	read       func(ctx context.Context) (statparser.Statistics, error)
+	client     *http.Client
+	log        *logger.Logger
+	closed     chan struct{}
+	startOnce  sync.Once
+	closeOnce  sync.Once
+
+	alertsDesc           *prometheus.Desc
+	evaluations          prometheus.Counter
+	evaluationFailures   prometheus.Counter
+	notifications        *prometheus.CounterVec
+	notificationFailures *prometheus.CounterVec
+}
+
+func newAlertEngine(cfg config.Exporter, read func(ctx context.Context) (statparser.Statistics, error)) *alertEngine {
+	engine := &alertEngine{
+		instances: map[string]*alertInstance{},
+		read:      read,
+		client:    &http.Client{},
+		log:       logger.GetLogger().With(slog.String(logger.Component, "alerts")),
+		closed:    make(chan struct{}),
+		alertsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "exporter", "alerts"),
+			"Number of pending and firing alerts by rule.",
+			[]string{"rule", "state"}, nil,
+		),
+		evaluations: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "alert_evaluations_total",
+				Help:      "Total number of alert rule evaluations.",
+			},
+		),
+		evaluationFailures: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "alert_evaluation_failures_total",
+				Help:      "Total number of alert rule evaluations skipped because the stat file could not be read.",
+			},
+		),
+		notifications: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "alert_notifications_total",
+				Help:      "Total number of alert notifications sent by receiver type.",
+			},
+			[]string{"receiver"},
+		),
+		notificationFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "alert_notification_failures_total",
+				Help:      "Total number of failed alert notifications by receiver type.",
+			},
+			[]string{"receiver"},
+		),
+	}
+	engine.update(cfg)
+
+	return engine
+}
+
+// update replaces rules and receivers. Pending and firing alerts of rules
+// with the same name and condition are kept, others are dropped without
+// notifications.
+func (e *alertEngine) update(cfg config.Exporter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.config = cfg
+	prevRules := map[string]*alertRule{}
+	for _, rule := range e.rules {
+		prevRules[rule.Name] = rule
+	}
+	e.rules = make([]*alertRule, 0, len(cfg.AlertRules))
+	kept := map[*alertRule]*alertRule{}
+	for _, ruleConfig := range cfg.AlertRules {
+		expr, err := ruleConfig.Parse()
+		if err != nil {
+			// rules are validated with the config
+			e.log.ErrorErr("Error parse alert rule", err, slog.String("rule", ruleConfig.Name))
+
+			continue
+		}
+		rule := &alertRule{AlertRule: ruleConfig, expr: expr}
+		if prev, ok := prevRules[rule.Name]; ok && prev.expr == rule.expr {
+			kept[prev] = rule
+		}
+		e.rules = append(e.rules, rule)
+	}
+	for key, instance := range e.instances {
+		rule, ok := kept[instance.rule]
+		if !ok {
+			delete(e.instances, key)
+
+			continue
+		}
+		instance.rule = rule
+	}
+}
+
+func (e *alertEngine) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.alertsDesc
+	e.evaluations.Describe(ch)
+	e.evaluationFailures.Describe(ch)
+	e.notifications.Describe(ch)
+	e.notificationFailures.Describe(ch)
+}
+
+func (e *alertEngine) Collect(metricChan chan<- prometheus.Metric) {
+	e.mu.Lock()
+	for _, rule := range e.rules {
+		pending, firing := 0, 0
+		for _, instance := range e.instances {
+			switch {
+			case instance.rule != rule:
+			case instance.firing:
+				firing++
+			default:
+				pending++
+			}
+		}
+		metricChan <- prometheus.MustNewConstMetric(e.alertsDesc, prometheus.GaugeValue, float64(pending), rule.Name, alertStatePending)
+		metricChan <- prometheus.MustNewConstMetric(e.alertsDesc, prometheus.GaugeValue, float64(firing), rule.Name, alertStateFiring)
+	}
+	e.mu.Unlock()
+	e.evaluations.Collect(metricChan)
+	e.evaluationFailures.Collect(metricChan)
+	e.notifications.Collect(metricChan)
+	e.notificationFailures.Collect(metricChan)
+}
+
+// run evaluates rules every evaluation interval until close is called.
+func (e *alertEngine) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-e.closed
+		cancel()
+	}()
+	for {
+		e.mu.Lock()
+		interval := time.Duration(e.config.AlertEvaluationInterval) * time.Second
+		e.mu.Unlock()
+		timer := time.NewTimer(interval)
+		select {
+		case <-e.closed:
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+		e.evaluateOnce(ctx)
+	}
+}
+
+// start runs rule evaluation in background, only the first call starts it.
+func (e *alertEngine) start() {
+	e.startOnce.Do(func() { go e.run() })
+}
+
+func (e *alertEngine) close() {
+	e.closeOnce.Do(func() { close(e.closed) })
+}
+
+// evaluateOnce reads the stat file, evaluates rules and sends changes.
+func (e *alertEngine) evaluateOnce(ctx context.Context) {
+	e.mu.Lock()
+	noRules := len(e.rules) == 0
+	e.mu.Unlock()
+	if noRules {
+		return
+	}
+	stat, err := e.read(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.evaluationFailures.Inc()
+			e.log.ErrorErr("Error read stat file for alert rules", err)
+		}
+
+		return
+	}
+	changes, alertmanagerAlerts := e.evaluate(time.Now(), stat)
+	e.send(ctx, changes, alertmanagerAlerts)
+}
+
+// evaluate updates alert states with a new read of the stat file. It returns
+// alerts which started firing or resolved, for webhooks, and alerts to send
+// to Alertmanager: all firing ones every resend interval or on changes.
+func (e *alertEngine) evaluate(now time.Time, stat statparser.Statistics) ([]alertNotification, []alertNotification) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.evaluations.Inc()
+	sample := streamSample{time: now, stat: stat}
+	changes := []alertNotification{}
+	seen := map[string]bool{}
+	for _, rule := range e.rules {
+		for _, value := range alertValues(rule.expr, e.prev, &sample) {
+			key := alertKey(rule.Name, value.labels)
+			seen[key] = true
+			instance, exists := e.instances[key]
+			if !value.ok {
+				// rate is unknown, e.g. after a counter reset, keep the state
+				continue
+			}
+			if !rule.expr.Match(value.value) {
+				if exists {
+					delete(e.instances, key)
+					if instance.firing {
+						changes = append(changes, e.resolved(instance, now))
+					}
+				}
+
+				continue
+			}
+			if !exists {
+				instance = &alertInstance{rule: rule, labels: value.labels, activeAt: now}
+				e.instances[key] = instance
+			}
+			instance.value = value.value
+			if !instance.firing && now.Sub(instance.activeAt) >= rule.expr.For {
+				instance.firing = true
+				e.log.Warning("Alert firing", slog.String("rule", rule.Name), slog.Any("labels", value.labels),
+					slog.Float64("value", value.value))
+				changes = append(changes, e.notification(instance, alertStateFiring, time.Time{}))
+			}
+		}
+	}
+	// CPUs and sockets which disappeared
+	for key, instance := range e.instances {
+		if !seen[key] {
+			delete(e.instances, key)
+			if instance.firing {
+				changes = append(changes, e.resolved(instance, now))
+			}
+		}
+	}
+	e.prev = &sample
+
+	resend := time.Duration(e.config.AlertResendInterval) * time.Second
+	if len(changes) == 0 && now.Sub(e.lastResend) < resend {
+		return changes, nil
+	}
+	e.lastResend = now
+	endsAt := now.Add(alertmanagerEndsAtFactor * max(resend, time.Duration(e.config.AlertEvaluationInterval)*time.Second))
+	alertmanagerAlerts := []alertNotification{}
+	for _, key := range slices.Sorted(maps.Keys(e.instances)) {
+		if instance := e.instances[key]; instance.firing {
+			alert := e.notification(instance, "", endsAt)
+			alertmanagerAlerts = append(alertmanagerAlerts, alert)
+		}
+	}
+	for _, change := range changes {
+		if change.Status == alertStatusResolved {
+			change.Status = ""
+			alertmanagerAlerts = append(alertmanagerAlerts, change)
+		}
+	}
+	if len(alertmanagerAlerts) == 0 {
+		return changes, nil
+	}
+
+	return changes, alertmanagerAlerts
+}
+
+func (e *alertEngine) resolved(instance *alertInstance, now time.Time) alertNotification {
+	e.log.Info("Alert resolved", slog.String("rule", instance.rule.Name), slog.Any("labels", instance.labels))
+
+	return e.notification(instance, alertStatusResolved, now)
+}
+
+func (e *alertEngine) notification(instance *alertInstance, status string, endsAt time.Time) alertNotification {
+	labels := map[string]string{"alertname": instance.rule.Name}
+	maps.Copy(labels, instance.labels)
+	if instance.rule.Severity != "" {
+		labels["severity"] = instance.rule.Severity
+	}
+	annotations := map[string]string{
+		"expr":  instance.rule.Expr,
+		"value": strconv.FormatFloat(instance.value, 'g', -1, 64),
+	}
+	if instance.rule.Summary != "" {
+		annotations["summary"] = instance.rule.Summary
+	}
+
+	return alertNotification{
+		Status:      status,
+		Labels:      labels,
+		Annotations: annotations,
+		StartsAt:    instance.activeAt,
+		EndsAt:      endsAt,
+	}
+}
+
+func alertKey(rule string, labels map[string]string) string {
+	key := rule
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		key += "\x00" + name + "=" + labels[name]
+	}
+
+	return key
+}
+
+// alertValues returns values of the rule metric for the global values, every
+// CPU or every socket. Rates are per second since the previous evaluation.
+func alertValues(expr config.AlertExpr, prev, current *streamSample) []alertValue {
+	metric := config.AlertMetrics[expr.Metric]
+	value := func(prevStruct, currentStruct reflect.Value) alertValue {
+		currentValue := alertFieldValue(currentStruct, metric)
+		if !expr.Rate {
+			return alertValue{value: currentValue, ok: true}
+		}
+		if !prevStruct.IsValid() {
+			return alertValue{}
+		}
+		prevValue := alertFieldValue(prevStruct, metric)
+		seconds := current.time.Sub(prev.time).Seconds()
+		if currentValue < prevValue || seconds <= 0 {
+			return alertValue{}
+		}
+
+		return alertValue{value: (currentValue - prevValue) / seconds, ok: true}
+	}
+
+	switch metric.Scope {
+	case config.AlertScopeCPU:
+		values := make([]alertValue, 0, len(current.stat.CPUStatList))
+		for _, cpu := range current.stat.CPUStatList {
+			prevCPU := reflect.Value{}
+			if prev != nil {
+				for _, candidate := range prev.stat.CPUStatList {
+					if candidate.CPU == cpu.CPU {
+						prevCPU = reflect.ValueOf(candidate)
+					}
+				}
+			}
+			cpuValue := value(prevCPU, reflect.ValueOf(cpu))
+			cpuValue.labels = map[string]string{"cpu": cpu.CPU}
+			values = append(values, cpuValue)
+		}
+
+		return values
+	case config.AlertScopeSocket:
+		values := make([]alertValue, 0, len(current.stat.SockStatList))
+		for _, socket := range current.stat.SockStatList {
+			prevSocket := reflect.Value{}
+			if prev != nil {
+				for _, candidate := range prev.stat.SockStatList {
+					if candidate.SockName == socket.SockName && candidate.SockDestination == socket.SockDestination {
+						prevSocket = reflect.ValueOf(candidate)
+					}
+				}
+			}
+			socketValue := value(prevSocket, reflect.ValueOf(socket))
+			socketValue.labels = map[string]string{"socket": socket.SockName, "destination": socket.SockDestination}
+			values = append(values, socketValue)
+		}
+
+		return values
+	default:
+		prevStat := reflect.Value{}
+		if prev != nil {
+			prevStat = reflect.ValueOf(prev.stat)
+		}
+		globalValue := value(prevStat, reflect.ValueOf(current.stat))
+		globalValue.labels = map[string]string{}
+
+		return []alertValue{globalValue}
+	}
+}
+
+func alertFieldValue(structValue reflect.Value, metric config.AlertMetric) float64 {
+	value := numericValue(structValue.FieldByName(metric.Field))
+	if metric.PercentOf == "" {
+		return value
+	}
+	total := numericValue(structValue.FieldByName(metric.PercentOf))
+	if total == 0 {
+		return 0
+	}
+
+	return value * 100 / total
+}
+
+func numericValue(field reflect.Value) float64 {
+	if field.CanFloat() {
+		return field.Float()
+	}
+
+	return float64(field.Uint())
+}
+
+// send posts changes to webhooks and alerts to Alertmanagers. Failed
+// notifications are not retried, Alertmanagers get firing alerts again with
+// the next resend.
+func (e *alertEngine) send(ctx context.Context, changes, alertmanagerAlerts []alertNotification) {
+	e.mu.Lock()
+	webhookURLs, alertmanagerURLs := e.config.AlertWebhookURLs, e.config.AlertmanagerURLs
+	timeout := time.Duration(e.config.RequestTimeout) * time.Second
+	e.mu.Unlock()
+	if len(changes) > 0 {
+		status := alertStatusResolved
+		for _, change := range changes {
+			if change.Status == alertStateFiring {
+				status = alertStateFiring
+			}
+		}
+		payload := webhookPayload{Version: webhookPayloadVersion, Status: status, Alerts: changes}
+		for _, webhookURL := range webhookURLs {
+			e.post(ctx, timeout, "webhook", webhookURL, payload)
+		}
+	}
+	if len(alertmanagerAlerts) > 0 {
+		for _, alertmanagerURL := range alertmanagerURLs {
+			alertsURL, err := alertmanagerAlertsURL(alertmanagerURL)
+			if err != nil {
+				e.postFailed("alertmanager", alertmanagerURL, err)
+
+				continue
+			}
+			e.post(ctx, timeout, "alertmanager", alertsURL, alertmanagerAlerts)
+		}
+	}
+}
+
+// post sends payload to url, the request is canceled after timeout.
+func (e *alertEngine) post(ctx context.Context, timeout time.Duration, receiver, url string, payload any) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := e.postJSON(ctx, url, payload)
+	if err != nil {
+		e.postFailed(receiver, url, err)
+
+		return
+	}
+	e.notifications.WithLabelValues(receiver).Inc()
+}
+
+func (e *alertEngine) postFailed(receiver, url string, err error) {
+	e.notificationFailures.WithLabelValues(receiver).Inc()
+	e.log.ErrorErr("Error send alert notification", err, slog.String("receiver", receiver), slog.String("url", url))
+}
+
+func (e *alertEngine) postJSON(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// alertmanagerAlertsURL returns the v2 API endpoint of an Alertmanager URL,
+// a path prefix of the URL is kept.
+func alertmanagerAlertsURL(baseURL string) (string, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("error incorrect Alertmanager URL %s: %w", baseURL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("error incorrect Alertmanager URL %s: must be http or https URL with host", baseURL)
+	}
+
+	return parsed.JoinPath("api", "v2", "alerts").String(), nil
+}
@@ -1,15 +1,23 @@
 package exporter
 
 import (
+	"context"
+	"errors"
+	"io/fs"
 	"log/slog"
+	"time"
 
 	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 const metricsNamespace = "ipt_netflow"
 
+// MetricsNamespace prefixes names of all exporter metrics.
+const MetricsNamespace = metricsNamespace
+
 type iptNetFlowMetric interface {
 	prometheus.Collector
 	Reset()
@@ -26,6 +34,19 @@ type IPTNetFlowTCollector struct {
 	commonMetrics *CommonMetrics
 	cpuMetrics    *CPUMetrics
 	sockMetrics   *SockMetrics
+	// scrapeErrors counts failed stat reads by reason, may be nil.
+	scrapeErrors *prometheus.CounterVec
+}
+
+// contextCollector collects metrics of one scrape, reading the stat file
+// with the scrape context.
+type contextCollector struct {
+	*IPTNetFlowTCollector
+	ctx context.Context //nolint:containedctx
+}
+
+func (c *contextCollector) Collect(metricChan chan<- prometheus.Metric) {
+	c.collect(c.ctx, metricChan)
 }
 
 func (i *IPTNetFlowTCollector) Name() string {
@@ -54,10 +75,23 @@ func (i *IPTNetFlowTCollector) collectorList() []iptNetFlowCollectors {
 	}
 }
 
+// withContext returns the collector for a scrape, which stops reading the
+// stat file when ctx is done.
+func (i *IPTNetFlowTCollector) withContext(ctx context.Context) prometheus.Collector {
+	return &contextCollector{IPTNetFlowTCollector: i, ctx: ctx}
+}
+
+// Collect collects metrics without deadline, scrapes use withContext.
 func (i *IPTNetFlowTCollector) Collect(metricChan chan<- prometheus.Metric) {
-	metrics, err := i.statParser.CollectAndMarshal()
+	i.collect(context.Background(), metricChan)
+}
+
+func (i *IPTNetFlowTCollector) collect(ctx context.Context, metricChan chan<- prometheus.Metric) {
+	start := time.Now()
+	metrics, err := i.statParser.CollectAndMarshal(ctx)
 	if err != nil {
-		i.log.Errorf("error collect metrics: %s", err.Error())
+		i.log.ErrorErr("error collect metrics", err, slog.Duration(logger.DurationKey, time.Since(start)))
+		i.countError(err)
 		// empty metrics
 		metrics = statparser.Statistics{}
 	}
@@ -77,3 +111,42 @@ func (i *IPTNetFlowTCollector) Describe(ch chan<- *prometheus.Desc) {
 		collector.Describe(ch)
 	}
 }
+
+func (i *IPTNetFlowTCollector) countError(err error) {
+	if i.scrapeErrors == nil {
+		return
+	}
+	reason := "error"
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		reason = "missing"
+	case errors.Is(err, context.DeadlineExceeded):
+		reason = "timeout"
+	case errors.Is(err, context.Canceled):
+		reason = "canceled"
+	}
+	i.scrapeErrors.WithLabelValues(reason).Inc()
+}
+
+// MetricFamilies returns ipt_NETFLOW metrics and scrape errors as exposed on
+// scrapes of a stat file with one CPU and one socket, so that generated rules
+// and dashboards follow names, types and labels of the collectors.
+func MetricFamilies() ([]*dto.MetricFamily, error) {
+	collector := newIPTNetFlowTCollector(StatReaderFunc(func(context.Context) (statparser.Statistics, error) {
+		return statparser.Statistics{
+			CPUStatList:  []statparser.CPUStat{{CPU: "cpu0"}},
+			SockStatList: []statparser.NFSockEntry{{SockName: "sock0", SockDestination: "127.0.0.1:2055"}},
+		}, nil
+	}))
+	scrapeErrors := newScrapeErrors()
+	scrapeErrors.WithLabelValues("error")
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		return nil, err
+	}
+	if err := registry.Register(scrapeErrors); err != nil {
+		return nil, err
+	}
+
+	return registry.Gather()
+}
@@ -0,0 +1,274 @@
+package exporter
+
+import (
+	"context"
+	_ "embed"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statfmt"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+const (
+	defaultDashboardRefresh = 5
+	// minRateInterval is the minimal time between reads rates are computed
+	// from, so that concurrent viewers do not get rates of a few milliseconds.
+	minRateInterval = time.Second
+	// cpuImbalanceRatio highlights CPUs whose share of packets is that many
+	// times above or below an even share.
+	cpuImbalanceRatio = 1.5
+)
+
+// moduleVersionFile holds the version of the loaded ipt_NETFLOW module.
+var moduleVersionFile = "/sys/module/ipt_NETFLOW/version"
+
+//go:embed templates/dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"human": statfmt.Humanize,
+}).Parse(dashboardHTML))
+
+// errorReporter is implemented by stat parsers which keep recent errors,
+// like statparser.StatCollector.
+type errorReporter interface {
+	RecentErrors() []statparser.ParseError
+}
+
+type statSample struct {
+	time time.Time
+	stat statparser.Statistics
+}
+
+// dashboard renders the status page. It keeps previous reads to compute
+// rates and lives as long as the server.
+type dashboard struct {
+	mu   sync.Mutex
+	prev statSample
+	last statSample
+}
+
+type dashboardValue struct {
+	Name  string
+	Value float64
+	Unit  string
+}
+
+type dashboardCounter struct {
+	Name    string
+	Value   uint64
+	Rate    float64
+	HasRate bool
+	Warning bool
+}
+
+type dashboardCPU struct {
+	statparser.CPUStat
+	Share      float64
+	Imbalanced bool
+}
+
+type dashboardSocket struct {
+	statparser.NFSockEntry
+	Fill    float64
+	Peak    float64
+	Errors  uint64
+	Warning bool
+}
+
+type dashboardPage struct {
+	TelemetryPath   string
+	StatFile        string
+	Refresh         int
+	ModuleVersion   string
+	ExporterVersion string
+	GoVersion       string
+	ReadTime        time.Time
+	ReadDuration    time.Duration
+	RateInterval    time.Duration
+	Error           string
+	Values          []dashboardValue
+	Counters        []dashboardCounter
+	CPUs            []dashboardCPU
+	Sockets         []dashboardSocket
+	Errors          []statparser.ParseError
+}
+
+var dashboardCounters = []struct {
+	name string
+	// warning highlights counters which indicate problems when growing
+	warning bool
+	value   func(stat *statparser.Statistics) uint64
+}{
+	{"In flows", false, func(stat *statparser.Statistics) uint64 { return stat.InFlows }},
+	{"In packets", false, func(stat *statparser.Statistics) uint64 { return stat.InPackets }},
+	{"In bytes", false, func(stat *statparser.Statistics) uint64 { return stat.InBytes }},
+	{"Out flows", false, func(stat *statparser.Statistics) uint64 { return stat.OutFlows }},
+	{"Out packets", false, func(stat *statparser.Statistics) uint64 { return stat.OutPackets }},
+	{"Out bytes", false, func(stat *statparser.Statistics) uint64 { return stat.OutBytes }},
+	{"Drop packets", true, func(stat *statparser.Statistics) uint64 { return stat.DropPackets }},
+	{"Drop bytes", true, func(stat *statparser.Statistics) uint64 { return stat.DropBytes }},
+	{"Lost flows", true, func(stat *statparser.Statistics) uint64 { return stat.LostFlows }},
+	{"Lost packets", true, func(stat *statparser.Statistics) uint64 { return stat.LostPackets }},
+	{"Lost bytes", true, func(stat *statparser.Statistics) uint64 { return stat.LostBytes }},
+	{"Errors", true, func(stat *statparser.Statistics) uint64 { return stat.ErrTotal }},
+}
+
+// indexPage renders the status page from a fresh read of the stat file.
+// It refreshes every few seconds, ?refresh=N changes the period and 0
+// disables it.
+func (s *APIServer) indexPage(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+
+		return
+	}
+	refresh := defaultDashboardRefresh
+	if value := req.URL.Query().Get("refresh"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "error incorrect refresh "+value, http.StatusBadRequest)
+
+			return
+		}
+		refresh = parsed
+	}
+	handlers := s.handlers.Load()
+	ctx, cancel := scrapeContext(req, handlers.config)
+	defer cancel()
+	page := s.dashboard.read(ctx, handlers.collector.statParser)
+	page.TelemetryPath = handlers.config.TelemetryPath
+	page.StatFile = handlers.config.IPTNetFlowStatFile
+	page.Refresh = refresh
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		s.log.ErrorErr("error handling index page", err)
+	}
+}
+
+func (d *dashboard) read(ctx context.Context, stat StatParser) dashboardPage {
+	page := dashboardPage{ModuleVersion: moduleVersion(), ReadTime: time.Now()}
+	if info, ok := readBuildInfo(); ok {
+		page.ExporterVersion = info.Version
+		if revision := info.Settings["vcs.revision"]; revision != "" {
+			page.ExporterVersion += " (" + revision[:min(len(revision), 12)] + ")"
+		}
+		page.GoVersion = info.GoVersion
+	}
+	if reporter, ok := stat.(errorReporter); ok {
+		page.Errors = reporter.RecentErrors()
+	}
+	current, err := stat.CollectAndMarshal(ctx)
+	page.ReadDuration = time.Since(page.ReadTime)
+	if err != nil {
+		page.Error = err.Error()
+
+		return page
+	}
+	sample := statSample{time: page.ReadTime, stat: current}
+	base, hasBase := d.base(sample)
+	if hasBase {
+		page.RateInterval = sample.time.Sub(base.time)
+	}
+	page.Values = dashboardValues(&current)
+	for _, counter := range dashboardCounters {
+		row := dashboardCounter{Name: counter.name, Value: counter.value(&current)}
+		if hasBase {
+			row.Rate, row.HasRate = statfmt.CounterRate(counter.value(&base.stat), row.Value, page.RateInterval)
+		}
+		row.Warning = counter.warning && row.HasRate && row.Rate > 0
+		page.Counters = append(page.Counters, row)
+	}
+	page.CPUs = dashboardCPUs(current.CPUStatList)
+	page.Sockets = dashboardSockets(current.SockStatList)
+
+	return page
+}
+
+// base stores sample and returns the previous one to compute rates from,
+// false when there is none old enough.
+func (d *dashboard) base(sample statSample) (statSample, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	base := d.last
+	if sample.time.Sub(d.last.time) < minRateInterval {
+		base = d.prev
+	} else {
+		d.prev, d.last = d.last, sample
+	}
+
+	return base, !base.time.IsZero()
+}
+
+func dashboardValues(stat *statparser.Statistics) []dashboardValue {
+	return []dashboardValue{
+		{"In bit rate", float64(stat.InBitRate), "bit/s"},
+		{"In packet rate", float64(stat.InPacketRate), "pkt/s"},
+		{"Out byte rate", float64(stat.OutByteRate), "B/s"},
+		{"Hash metric", stat.HashMetric, ""},
+		{"Hash memory", float64(stat.HashMemory), "B"},
+		{"Hash flows", float64(stat.HashFlows), ""},
+		{"Hash packets", float64(stat.HashPackets), ""},
+		{"Hash bytes", float64(stat.HashBytes), "B"},
+		{"Sndbuf peak", float64(stat.SndbufPeak), "B"},
+	}
+}
+
+// dashboardCPUs computes the share of packets of every CPU. The packet rate
+// of the module is used, or total packets when there is no traffic now.
+func dashboardCPUs(cpus []statparser.CPUStat) []dashboardCPU {
+	var totalRate, totalPackets uint64
+	for _, cpu := range cpus {
+		totalRate += cpu.CPUInPacketRate
+		totalPackets += cpu.CPUInPackets
+	}
+	evenShare := 1 / float64(max(len(cpus), 1))
+	rows := make([]dashboardCPU, 0, len(cpus))
+	for _, cpu := range cpus {
+		row := dashboardCPU{CPUStat: cpu}
+		switch {
+		case totalRate > 0:
+			row.Share = float64(cpu.CPUInPacketRate) / float64(totalRate)
+		case totalPackets > 0:
+			row.Share = float64(cpu.CPUInPackets) / float64(totalPackets)
+		}
+		row.Imbalanced = len(cpus) > 1 && (totalRate > 0 || totalPackets > 0) &&
+			(row.Share > evenShare*cpuImbalanceRatio || row.Share < evenShare/cpuImbalanceRatio)
+		row.Share *= 100
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func dashboardSockets(sockets []statparser.NFSockEntry) []dashboardSocket {
+	rows := make([]dashboardSocket, 0, len(sockets))
+	for _, socket := range sockets {
+		row := dashboardSocket{
+			NFSockEntry: socket,
+			Errors:      statfmt.SocketErrors(&socket),
+		}
+		row.Fill = statfmt.SndbufFill(&socket)
+		row.Peak = statfmt.SndbufPeak(&socket)
+		row.Warning = socket.SockActive == 0 || row.Fill >= statfmt.SndbufFillWarning
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func moduleVersion() string {
+	content, err := os.ReadFile(moduleVersionFile)
+	if err != nil {
+		return "unknown"
+	}
+
+	return strings.TrimSpace(string(content))
+}
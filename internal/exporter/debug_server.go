@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	runtimepprof "runtime/pprof"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/prometheus/exporter-toolkit/web"
+)
+
+// SnapshotFunc returns the last read of the stat file, false when the file
+// was not read yet.
+type SnapshotFunc func() (statparser.Snapshot, bool)
+
+// DebugServer serves pprof, goroutine dumps, the last stat file snapshot and
+// build information on its own listener, so that profiling is never exposed
+// on the metrics address. It uses the web config of the API server.
+type DebugServer struct {
+	server   *http.Server
+	log      *logger.Logger
+	config   config.Exporter
+	listener net.Listener
+	snapshot SnapshotFunc
+}
+
+// debugSnapshot adds the raw stat file content as text to the snapshot.
+type debugSnapshot struct {
+	statparser.Snapshot
+	Raw string `json:"raw"`
+}
+
+type buildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings"`
+	Deps      map[string]string `json:"deps"`
+}
+
+func NewDebugServer(cfg config.Exporter, snapshot SnapshotFunc) *DebugServer {
+	debugServer := &DebugServer{
+		log:      logger.GetLogger().With(slog.String(logger.Component, "debug-server")),
+		config:   cfg,
+		snapshot: snapshot,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", debugServer.indexPage)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", debugServer.goroutines)
+	mux.HandleFunc("/debug/snapshot", debugServer.snapshotHandler)
+	mux.HandleFunc("/debug/buildinfo", debugServer.buildInfo)
+
+	timeout := time.Duration(cfg.RequestTimeout) * time.Second
+	// no write timeout, CPU profiles and traces take longer than a request
+	debugServer.server = &http.Server{
+		Handler:     mux,
+		ReadTimeout: timeout,
+		IdleTimeout: timeout,
+	}
+
+	return debugServer
+}
+
+// Listen opens the debug listener.
+func (s *DebugServer) Listen() error {
+	s.log.Info("Starting debug server", slog.String("listen", s.config.DebugListenAddress))
+	listener, err := listenAddress(s.config.DebugListenAddress, s.config.UnixSocketMode)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	return nil
+}
+
+// Serve blocks serving connections on the listener opened by Listen.
+func (s *DebugServer) Serve() error {
+	return web.ServeMultiple([]net.Listener{s.listener}, s.server, &web.FlagConfig{WebConfigFile: &s.config.WebConfigFile}, s.log.Slog())
+}
+
+// Shutdown stops the debug server, closing connections left when ctx is done.
+func (s *DebugServer) Shutdown(ctx context.Context) error {
+	s.log.Info("Stopping debug server")
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.log.ErrorErr("Error graceful stop debug server", err)
+		if closeErr := s.server.Close(); closeErr != nil {
+			s.log.ErrorErr("Error stop debug server", closeErr)
+		}
+	}
+
+	return err
+}
+
+func (s *DebugServer) indexPage(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+
+		return
+	}
+	_, err := w.Write([]byte(`<html>
+<head><title>ipt-netflow Exporter debug</title></head>
+<body>
+<h1>ipt-netflow Exporter debug</h1>
+<p><a href='/debug/pprof/'>Profiles</a></p>
+<p><a href='/debug/goroutines'>Goroutine dump</a></p>
+<p><a href='/debug/snapshot'>Last stat file snapshot</a> (<a href='/debug/snapshot?format=raw'>raw</a>)</p>
+<p><a href='/debug/buildinfo'>Build information</a></p>
+</body>
+</html>`))
+	if err != nil {
+		s.log.ErrorErr("error handling debug index page", err)
+	}
+}
+
+// goroutines writes stacks of all goroutines in the panic format.
+func (s *DebugServer) goroutines(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := runtimepprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		s.log.ErrorErr("Error write goroutine dump", err)
+	}
+}
+
+// snapshotHandler returns the last read stat file with its parse result, or
+// only the file content with ?format=raw.
+func (s *DebugServer) snapshotHandler(w http.ResponseWriter, req *http.Request) {
+	snapshot, ok := s.snapshot()
+	if !ok {
+		http.Error(w, "stat file was not read yet", http.StatusNotFound)
+
+		return
+	}
+	if req.URL.Query().Get("format") == "raw" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err := w.Write(snapshot.Raw); err != nil {
+			s.log.ErrorErr("Error write stat file snapshot", err)
+		}
+
+		return
+	}
+	s.writeJSON(w, debugSnapshot{Snapshot: snapshot, Raw: string(snapshot.Raw)})
+}
+
+func (s *DebugServer) buildInfo(w http.ResponseWriter, _ *http.Request) {
+	result, ok := readBuildInfo()
+	if !ok {
+		http.Error(w, "build information is not available", http.StatusNotFound)
+
+		return
+	}
+	s.writeJSON(w, result)
+}
+
+func readBuildInfo() (buildInfo, bool) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return buildInfo{}, false
+	}
+	result := buildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+		Settings:  make(map[string]string, len(info.Settings)),
+		Deps:      make(map[string]string, len(info.Deps)),
+	}
+	for _, setting := range info.Settings {
+		result.Settings[setting.Key] = setting.Value
+	}
+	for _, dep := range info.Deps {
+		result.Deps[dep.Path] = dep.Version
+	}
+
+	return result, true
+}
+
+func (s *DebugServer) writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		s.log.ErrorErr("Error write debug response", err)
+	}
+}
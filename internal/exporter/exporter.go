@@ -1,11 +1,12 @@
 package exporter
 
 import (
-	"fmt"
+	"context"
 	slog "log/slog"
+	"net"
 	"net/http"
-	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
@@ -13,86 +14,162 @@ import (
 	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 )
 
 type StatParser interface {
-	CollectAndMarshal() (statparser.Statistics, error)
+	CollectAndMarshal(ctx context.Context) (statparser.Statistics, error)
 }
 
 type APIServer struct {
-	server *http.Server
-	log    *logger.Logger
-	config config.Exporter
+	server    *http.Server
+	log       *logger.Logger
+	config    config.Exporter
+	listeners []net.Listener
+	// registry holds metrics that live as long as the server, handlers are
+	// rebuilt on every config reload.
+	registry      *prometheus.Registry
+	reloadMetrics *reloadMetrics
+	httpMetrics   *httpMetrics
+	limiter       *limiter
+	scrapeErrors  *prometheus.CounterVec
+	dashboard     *dashboard
+	stream        *streamHub
+	alerts        *alertEngine
+	handlers      atomic.Pointer[handlerSet]
 }
 
 func New(cfg config.Exporter, stat StatParser) (*APIServer, error) {
 	apiServer := APIServer{
-		log:    logger.GetLogger().With(slog.String(logger.Component, "exporter-api-server")),
-		config: cfg,
+		log:           logger.GetLogger().With(slog.String(logger.Component, "exporter-api-server")),
+		config:        cfg,
+		registry:      prometheus.NewRegistry(),
+		reloadMetrics: newReloadMetrics(),
+		httpMetrics:   newHTTPMetrics(),
+		limiter:       newLimiter(cfg),
+		dashboard:     &dashboard{},
+		scrapeErrors:  newScrapeErrors(),
 	}
-	collector := newIPTNetFlowTCollector(stat)
-	if !collector.Initialized() {
-		return nil, fmt.Errorf("collector %s was not initialized", collector.Name())
+	if err := apiServer.registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, err
+	}
+	if err := apiServer.registry.Register(apiServer.reloadMetrics); err != nil {
+		return nil, err
+	}
+	if err := apiServer.registry.Register(apiServer.httpMetrics); err != nil {
+		return nil, err
+	}
+	if err := apiServer.registry.Register(apiServer.limiter); err != nil {
+		return nil, err
 	}
-	if err := prometheus.Register(collector); err != nil {
+	if err := apiServer.registry.Register(apiServer.scrapeErrors); err != nil {
+		return nil, err
+	}
+	apiServer.stream = newStreamHub(cfg, apiServer.readStat)
+	if err := apiServer.registry.Register(apiServer.stream); err != nil {
+		return nil, err
+	}
+	apiServer.alerts = newAlertEngine(cfg, apiServer.readStat)
+	if err := apiServer.registry.Register(apiServer.alerts); err != nil {
+		return nil, err
+	}
+	handlers, err := apiServer.newHandlerSet(cfg, stat)
+	if err != nil {
 		return nil, err
 	}
+	apiServer.handlers.Store(handlers)
+	apiServer.reloadMetrics.update(nil)
 
-	httpMux := http.NewServeMux()
 	timeout := time.Duration(cfg.RequestTimeout) * time.Second
-	address := strings.Join([]string{cfg.ServerAddress, strconv.Itoa(cfg.ServerPort)}, ":")
 	apiServer.server = &http.Server{
-		Addr:         address,
-		Handler:      httpMux,
+		Handler:      http.HandlerFunc(apiServer.serveHTTP),
 		ReadTimeout:  timeout,
 		WriteTimeout: timeout,
 		IdleTimeout:  timeout,
 	}
-	httpMux.HandleFunc("/", apiServer.indexPage)
-	httpMux.Handle(cfg.TelemetryPath, apiServer.middlewareLogging(promhttp.Handler()))
-	if !cfg.EnableRuntimeMetrics {
-		prometheus.Unregister(collectors.NewGoCollector())
-	}
 
 	return &apiServer, nil
 }
 
-func (s *APIServer) middlewareLogging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(respwr http.ResponseWriter, req *http.Request) {
-		s.log.With(
-			slog.String("addr", req.RemoteAddr),
-			slog.String("method", req.Method),
-			slog.String("agent", req.UserAgent()),
-		).Debugf("%s", req.URL.Path)
+func newScrapeErrors() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "exporter",
+			Name:      "scrape_errors_total",
+			Help:      "Total number of failed reads of the ipt_NETFLOW stat file by reason.",
+		},
+		[]string{"reason"},
+	)
+}
+
+// readStat reads the stat file with the stat parser of the current handlers,
+// for background readers not bound to a request.
+func (s *APIServer) readStat(ctx context.Context) (statparser.Statistics, error) {
+	handlers := s.handlers.Load()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(handlers.config.RequestTimeout)*time.Second)
+	defer cancel()
 
-		next.ServeHTTP(respwr, req)
-	})
+	return handlers.collector.statParser.CollectAndMarshal(ctx)
+}
+
+func (s *APIServer) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	s.handlers.Load().mux.ServeHTTP(w, req)
 }
 
 // StartAPIServer starts Exporter's HTTP server.
 func (s *APIServer) Start() error {
-	s.log.Infof("Starting exporter API server on %s", s.server.Addr)
+	if err := s.Listen(); err != nil {
+		return err
+	}
 
-	return s.server.ListenAndServe()
+	return s.Serve()
 }
 
-func (s *APIServer) Stop() {
-	s.log.Infof("Stopping exporter API server")
-	if err := s.server.Close(); err != nil {
-		s.log.Errorf("Error stop exporter")
+// Listen opens the server listeners, so that a caller knows the exporter
+// is reachable before Serve is called, and starts evaluation of alert rules.
+func (s *APIServer) Listen() error {
+	if s.config.SystemdSocket {
+		s.log.Info("Starting exporter API server on systemd activated sockets")
+	} else {
+		s.log.Info("Starting exporter API server", slog.String("listen", strings.Join(s.config.Listeners(), ", ")))
+	}
+	listeners, err := listen(s.config)
+	if err != nil {
+		return err
 	}
+	s.listeners = listeners
+	s.alerts.start()
+
+	return nil
+}
+
+// Serve blocks serving connections on listeners opened by Listen.
+func (s *APIServer) Serve() error {
+	return s.serve(s.listeners...)
 }
 
-func (s *APIServer) indexPage(w http.ResponseWriter, _ *http.Request) {
-	_, err := w.Write([]byte(`<html>
-<head><title>ipt-netflow Exporter</title></head>
-<body>
-<h1>ipt-netflow Exporter</h1>
-<p><a href='` + s.config.TelemetryPath + `'>Metrics</a></p>
-</body>
-</html>`))
+// serve accepts connections on all listeners with the same handler set. TLS
+// and basic auth are enabled according to the web config file, which is
+// re-read on every new TLS connection so renewed certificates are picked up
+// without a restart.
+func (s *APIServer) serve(listeners ...net.Listener) error {
+	return web.ServeMultiple(listeners, s.server, &web.FlagConfig{WebConfigFile: &s.config.WebConfigFile}, s.log.Slog())
+}
+
+// Shutdown stops accepting new connections and waits for in-flight
+// requests until ctx is done, then closes remaining connections.
+func (s *APIServer) Shutdown(ctx context.Context) error {
+	s.log.Info("Stopping exporter API server")
+	s.stream.close()
+	s.alerts.close()
+	err := s.server.Shutdown(ctx)
 	if err != nil {
-		s.log.Errorf("error handling index page: %s", err)
+		s.log.ErrorErr("Error graceful stop exporter", err)
+		if closeErr := s.server.Close(); closeErr != nil {
+			s.log.ErrorErr("Error stop exporter", closeErr)
+		}
 	}
+
+	return err
 }
@@ -1,27 +1,1178 @@
 package exporter
 
 import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	netflowstatsv1 "github.com/mythvcode/ipt-netflow-exporter/api/netflowstats/v1"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/exporter/mocks"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func TestNewExporter(t *testing.T) {
-	mock := mocks.NewMockStatParser(t)
+	statMock := mocks.NewMockStatParser(t)
 	cfg, err := config.ReadConfig("")
 	require.NoError(t, err)
-	_, err = New(cfg.Exporter, mock)
+	_, err = New(cfg.Exporter, statMock)
 	require.NoError(t, err)
 }
 
 func TestNewGetStats(t *testing.T) {
-	mock := mocks.NewMockStatParser(t)
-	collector := newIPTNetFlowTCollector(mock)
-	mock.EXPECT().CollectAndMarshal().Return(getTestStatistic(t), nil)
+	statMock := mocks.NewMockStatParser(t)
+	collector := newIPTNetFlowTCollector(statMock)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil)
 	err := testutil.CollectAndCompare(collector, strings.NewReader(getPromTestStat(t)))
 	require.NoError(t, err)
 }
+
+func TestBasicAuth(t *testing.T) {
+	webConfig := filepath.Join(t.TempDir(), "web-config.yml")
+	require.NoError(t, os.WriteFile(webConfig, []byte(
+		"basic_auth_users:\n  user: $2a$04$eklVOtMOjIeFmOk9ruOCie.OjTQoTqpl06BFeqJPhS77HVCZR6OHe\n"), 0o600))
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.WebConfigFile = webConfig
+
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Maybe()
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error)
+	go func() { served <- server.serve(listener) }()
+	t.Cleanup(func() {
+		require.NoError(t, server.Shutdown(context.Background()))
+		require.True(t, errors.Is(<-served, http.ErrServerClosed))
+	})
+
+	url := "http://" + listener.Addr().String() + cfg.Exporter.TelemetryPath
+	tCases := []struct {
+		user     string
+		password string
+		status   int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"user", "wrong", http.StatusUnauthorized},
+		{"user", "secret", http.StatusOK},
+	}
+	for _, tCase := range tCases {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if tCase.user != "" {
+			req.SetBasicAuth(tCase.user, tCase.password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, tCase.status, resp.StatusCode)
+	}
+}
+
+func TestMultipleListeners(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	socketPath := filepath.Join(t.TempDir(), "exporter.sock")
+	cfg.Exporter.ListenAddresses = []string{"127.0.0.1:0", config.UnixSocketPrefix + socketPath}
+	cfg.Exporter.UnixSocketMode = "0600"
+
+	listeners, err := listen(cfg.Exporter)
+	require.NoError(t, err)
+	require.Len(t, listeners, 2)
+	socketInfo, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), socketInfo.Mode().Perm())
+
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Maybe()
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+	served := make(chan error)
+	go func() { served <- server.serve(listeners...) }()
+	t.Cleanup(func() {
+		require.NoError(t, server.Shutdown(context.Background()))
+		require.True(t, errors.Is(<-served, http.ErrServerClosed))
+	})
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	for _, tCase := range []struct {
+		client *http.Client
+		url    string
+	}{
+		{http.DefaultClient, "http://" + listeners[0].Addr().String() + cfg.Exporter.TelemetryPath},
+		{unixClient, "http://unix" + cfg.Exporter.TelemetryPath},
+	} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tCase.url, nil)
+		require.NoError(t, err)
+		resp, err := tCase.client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestUnixSocketStaleFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "data.db")
+	require.NoError(t, os.WriteFile(filePath, []byte("data"), 0o600))
+	_, err := listenAddress(config.UnixSocketPrefix+filePath, "0600")
+	require.ErrorContains(t, err, "is not a socket")
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	// a socket left after unclean shutdown is replaced
+	socketPath := filepath.Join(dir, "exporter.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+	listener, err := listenAddress(config.UnixSocketPrefix+socketPath, "0600")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+}
+
+func TestShutdownDrainsScrapes(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).RunAndReturn(func(context.Context) (statparser.Statistics, error) {
+		close(started)
+		<-release
+
+		return getTestStatistic(t), nil
+	})
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error)
+	go func() { served <- server.serve(listener) }()
+
+	scraped := make(chan int)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + cfg.Exporter.TelemetryPath) //nolint:noctx
+		if err != nil {
+			scraped <- 0
+
+			return
+		}
+		resp.Body.Close()
+		scraped <- resp.StatusCode
+	}()
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- server.Shutdown(context.Background()) }()
+	require.True(t, errors.Is(<-served, http.ErrServerClosed))
+	select {
+	case <-stopped:
+		t.Fatal("shutdown returned before in-flight scrape finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	require.Equal(t, http.StatusOK, <-scraped)
+	require.NoError(t, <-stopped)
+}
+
+func scrape(t *testing.T, server *APIServer, path string) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	return recorder.Body.String()
+}
+
+func TestReload(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	oldStat := mocks.NewMockStatParser(t)
+	oldStat.EXPECT().CollectAndMarshal(mock.Anything).Return(statparser.Statistics{InFlows: 1}, nil).Once()
+	server, err := New(cfg.Exporter, oldStat)
+	require.NoError(t, err)
+	body := scrape(t, server, "/metrics")
+	require.Contains(t, body, "ipt_netflow_exporter_config_last_reload_successful 1")
+	require.NotContains(t, body, "go_goroutines")
+
+	newCfg := cfg.Exporter
+	newCfg.TelemetryPath = "/reloaded"
+	newCfg.EnableRuntimeMetrics = true
+	newStat := mocks.NewMockStatParser(t)
+	newStat.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Once()
+	require.NoError(t, server.Reload(newCfg, newStat))
+	server.ReportReload(nil)
+
+	recorder := httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	body = scrape(t, server, "/reloaded")
+	require.Contains(t, body, "ipt_netflow_socket_active{destination=\"localhost:1234\",socket=\"sock0\"} 1")
+	require.Contains(t, body, "go_goroutines")
+
+	server.ReportReload(errors.New("invalid config"))
+	newStat.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Once()
+	require.Contains(t, scrape(t, server, "/reloaded"), "ipt_netflow_exporter_config_last_reload_successful 0")
+}
+
+func TestLogLevelEndpoint(t *testing.T) {
+	webConfig := filepath.Join(t.TempDir(), "web-config.yml")
+	require.NoError(t, os.WriteFile(webConfig, []byte(
+		"basic_auth_users:\n  user: $2a$04$eklVOtMOjIeFmOk9ruOCie.OjTQoTqpl06BFeqJPhS77HVCZR6OHe\n"), 0o600))
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.WebConfigFile = webConfig
+	cfg.Exporter.LogLevelEndpoint = true
+	server, err := New(cfg.Exporter, mocks.NewMockStatParser(t))
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error)
+	go func() { served <- server.serve(listener) }()
+	t.Cleanup(func() {
+		require.NoError(t, logger.SetLevels(logger.Levels{Level: "info", Components: map[string]string{"StatCollector": ""}}))
+		require.NoError(t, server.Shutdown(context.Background()))
+		require.True(t, errors.Is(<-served, http.ErrServerClosed))
+	})
+
+	request := func(method, body string, auth bool) (int, string) {
+		req, err := http.NewRequestWithContext(context.Background(), method,
+			"http://"+listener.Addr().String()+logLevelPath, strings.NewReader(body))
+		require.NoError(t, err)
+		if auth {
+			req.SetBasicAuth("user", "secret")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, strings.TrimSpace(string(respBody))
+	}
+
+	status, _ := request(http.MethodPut, `{"level":"error"}`, false)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	status, body := request(http.MethodPut, `{"level":"warning","components":{"StatCollector":"debug"}}`, true)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"level":"warning","components":{"StatCollector":"debug"}}`, body)
+
+	status, body = request(http.MethodGet, "", true)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"level":"warning","components":{"StatCollector":"debug"}}`, body)
+
+	status, body = request(http.MethodPut, `{"level":"verbose"}`, true)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Contains(t, body, "error set log levels")
+
+	status, _ = request(http.MethodPost, "", true)
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+}
+
+func TestAccessLog(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	require.NoError(t, logger.Init(config.Logger{File: logFile, Level: "info", Format: "json"}))
+	t.Cleanup(logger.SetDefaultDiscardLogger)
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.AccessLogFormat = config.AccessLogCombined
+	cfg.Exporter.AccessLogSampling = 2
+	cfg.Exporter.LogLevelEndpoint = true
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Times(3)
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+
+	for range 3 {
+		scrape(t, server, "/")
+	}
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, logLevelPath, nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	server.serveHTTP(recorder, req)
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	require.InDelta(t, 3, testutil.ToFloat64(server.httpMetrics.requests.WithLabelValues("index", http.MethodGet, "200")), 0)
+	require.InDelta(t, 1, testutil.ToFloat64(server.httpMetrics.requests.WithLabelValues("log-level", http.MethodPost, "405")), 0)
+	require.Equal(t, 2, testutil.CollectAndCount(server.httpMetrics.duration))
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	// every second successful request and all failed ones are logged
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `"component":"AccessLog"`)
+	require.Regexp(t, `"msg":"192\.0\.2\.1 - - \[[^\]]+\] \\"GET / HTTP/1\.1\\" 200 \d+ \\"\\" \\"\\""`, lines[0])
+	require.Contains(t, lines[1], `\"POST /-/log-level HTTP/1.1\" 405 19 \"\" \"curl/8.0\"`)
+}
+
+func TestAccessLogStructured(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	require.NoError(t, logger.Init(config.Logger{File: logFile, Level: "info", Format: "json"}))
+	t.Cleanup(logger.SetDefaultDiscardLogger)
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Once()
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+	scrape(t, server, "/")
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Regexp(t, `"msg":"http request","component":"AccessLog","handler":"index","method":"GET","path":"/","status":200,"bytes":\d+,"duration":\d+,"addr":"192.0.2.1:1234","agent":""`, string(content))
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.MaxConcurrentScrapes = 1
+	started := make(chan struct{})
+	release := make(chan struct{})
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).RunAndReturn(func(context.Context) (statparser.Statistics, error) {
+		close(started)
+		<-release
+
+		return getTestStatistic(t), nil
+	}).Once()
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+
+	scraped := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		scraped <- recorder.Code
+	}()
+	<-started
+	recorder := httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	// index page reads the stat file as well
+	recorder = httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	close(release)
+	require.Equal(t, http.StatusOK, <-scraped)
+
+	require.InDelta(t, 1, testutil.ToFloat64(server.limiter.rejected.WithLabelValues("metrics", rejectConcurrency)), 0)
+	require.InDelta(t, 1, testutil.ToFloat64(server.limiter.rejected.WithLabelValues("index", rejectConcurrency)), 0)
+}
+
+func TestClientRateLimit(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.ClientRateLimit = 6
+	cfg.Exporter.ClientRateBurst = 2
+	limiter := newLimiter(cfg.Exporter)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	handler := limiter.wrap("metrics", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = remoteAddr
+		handler.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, request("192.0.2.1:1000").Code)
+	require.Equal(t, http.StatusOK, request("192.0.2.1:1001").Code)
+	rejected := request("192.0.2.1:1002")
+	require.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	require.Equal(t, "10", rejected.Header().Get("Retry-After"))
+	// other clients have own buckets
+	require.Equal(t, http.StatusOK, request("192.0.2.2:1000").Code)
+
+	now = now.Add(10 * time.Second)
+	require.Equal(t, http.StatusOK, request("192.0.2.1:1003").Code)
+	require.Equal(t, http.StatusTooManyRequests, request("192.0.2.1:1004").Code)
+	require.InDelta(t, 2, testutil.ToFloat64(limiter.rejected.WithLabelValues("metrics", rejectRateLimit)), 0)
+
+	// idle buckets are removed
+	now = now.Add(time.Hour)
+	require.Equal(t, http.StatusOK, request("192.0.2.1:1005").Code)
+	require.Len(t, limiter.buckets, 1)
+
+	cfg.Exporter.ClientRateLimit = 0
+	limiter.update(cfg.Exporter)
+	for range 5 {
+		require.Equal(t, http.StatusOK, request("192.0.2.1:1006").Code)
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.ScrapeTimeoutOffset = "1s"
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).RunAndReturn(func(ctx context.Context) (statparser.Statistics, error) {
+		<-ctx.Done()
+
+		return statparser.Statistics{}, ctx.Err()
+	}).Once()
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set(scrapeTimeoutHeader, "1.1")
+	server.serveHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Less(t, time.Since(start), time.Second)
+	require.InDelta(t, 1, testutil.ToFloat64(server.scrapeErrors.WithLabelValues("timeout")), 0)
+}
+
+func TestScrapeMissingFile(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).
+		Return(statparser.Statistics{}, &fs.PathError{Op: "open", Path: "/proc/net/stat/ipt_netflow", Err: fs.ErrNotExist}).Once()
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.InDelta(t, 1, testutil.ToFloat64(server.scrapeErrors.WithLabelValues("missing")), 0)
+	require.InDelta(t, 0, testutil.ToFloat64(server.scrapeErrors.WithLabelValues("error")), 0)
+}
+
+func TestScrapeContextTimeout(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	for header, expected := range map[string]time.Duration{
+		"":      10 * time.Second,
+		"bad":   10 * time.Second,
+		"5":     4500 * time.Millisecond,
+		"0.25":  250 * time.Millisecond,
+		"0.5":   500 * time.Millisecond,
+		"0.501": time.Millisecond,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			req.Header.Set(scrapeTimeoutHeader, header)
+		}
+		ctx, cancel := scrapeContext(req, cfg.Exporter)
+		deadline, ok := ctx.Deadline()
+		cancel()
+		require.True(t, ok)
+		require.InDelta(t, expected.Seconds(), time.Until(deadline).Seconds(), 0.1, header)
+	}
+}
+
+func TestDebugServer(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.DebugListenAddress = "localhost:0"
+	var snapshot *statparser.Snapshot
+	server := NewDebugServer(cfg.Exporter, func() (statparser.Snapshot, bool) {
+		if snapshot == nil {
+			return statparser.Snapshot{}, false
+		}
+
+		return *snapshot, true
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, get("/").Code)
+	require.Equal(t, http.StatusNotFound, get("/unknown").Code)
+	require.Equal(t, http.StatusOK, get("/debug/pprof/").Code)
+	require.Contains(t, get("/debug/goroutines").Body.String(), "goroutine ")
+	require.Equal(t, http.StatusNotFound, get("/debug/snapshot").Code)
+
+	snapshot = &statparser.Snapshot{File: "test_path", Raw: []byte("inBitRate 1\n"), Stat: statparser.Statistics{InBitRate: 1}}
+	recorder := get("/debug/snapshot")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"raw": "inBitRate 1\n"`)
+	require.Contains(t, recorder.Body.String(), `"InBitRate": 1`)
+	require.Equal(t, "inBitRate 1\n", get("/debug/snapshot?format=raw").Body.String())
+
+	recorder = get("/debug/buildinfo")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"go_version"`)
+
+	require.NoError(t, server.Listen())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve()
+	}()
+	resp, err := http.Get("http://" + server.listener.Addr().String() + "/debug/buildinfo")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, server.Shutdown(context.Background()))
+	require.ErrorIs(t, <-served, http.ErrServerClosed)
+}
+
+type errorReportingStat struct {
+	*mocks.MockStatParser
+	errors []statparser.ParseError
+}
+
+func (s errorReportingStat) RecentErrors() []statparser.ParseError {
+	return s.errors
+}
+
+func TestDashboard(t *testing.T) {
+	versionFile := filepath.Join(t.TempDir(), "version")
+	require.NoError(t, os.WriteFile(versionFile, []byte("2.6\n"), 0o600))
+	prevVersionFile := moduleVersionFile
+	moduleVersionFile = versionFile
+	t.Cleanup(func() { moduleVersionFile = prevVersionFile })
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	stat := getTestStatistic(t)
+	stat.CPUStatList = append(stat.CPUStatList, statparser.CPUStat{CPU: "cpu1", CPUInPacketRate: 20})
+	stat.SockStatList[0].SockSndbuf = 100
+	stat.SockStatList[0].SockSndbufFill = 95
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(stat, nil).Once()
+	server, err := New(cfg.Exporter, errorReportingStat{
+		MockStatParser: statMock,
+		errors:         []statparser.ParseError{{Time: time.Now(), Line: 3, Message: "error parse cpu0 stat"}},
+	})
+	require.NoError(t, err)
+	prevStat := stat
+	prevStat.LostFlows = 6
+	server.dashboard.last = statSample{time: time.Now().Add(-10 * time.Second), stat: prevStat}
+
+	recorder := httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?refresh=10", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	require.Contains(t, body, `<meta http-equiv="refresh" content="10">`)
+	require.Contains(t, body, "<tr><td>Module version</td><td>2.6</td></tr>")
+	require.Regexp(t, `<tr class="warning"><td>Lost flows</td><td>16</td><td>(1|1\.00|0\.99)</td></tr>`, body)
+	require.Contains(t, body, "<tr><td>In flows</td><td>3</td><td>0</td></tr>")
+	require.Contains(t, body, `<tr class="warning"><td>cpu1</td><td>95.2%</td>`)
+	require.Contains(t, body, `<span class="fill" style="width: 95%">`)
+	require.Contains(t, body, "error parse cpu0 stat")
+
+	recorder = httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?refresh=-1", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = httptest.NewRecorder()
+	server.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestDashboardSocketErrors(t *testing.T) {
+	rows := dashboardSockets([]statparser.NFSockEntry{{SockErrConnect: math.MaxUint32, SockErrFull: math.MaxUint32, SockErrCberr: 1, SockErrOther: 2}})
+	require.Equal(t, uint64(2*math.MaxUint32+3), rows[0].Errors)
+}
+
+func TestStream(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.StreamMaxSubscribers = 1
+	var reads atomic.Uint64
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).RunAndReturn(func(context.Context) (statparser.Statistics, error) {
+		stat := getTestStatistic(t)
+		stat.InFlows = 100 * reads.Add(1)
+
+		return stat, nil
+	})
+	server, err := New(cfg.Exporter, statMock)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error)
+	go func() { served <- server.serve(listener) }()
+	url := "http://" + listener.Addr().String() + streamPath
+
+	resp, err := http.Get(url + "?interval=1s")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	rejected, err := http.Get(url)
+	require.NoError(t, err)
+	rejected.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, rejected.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() streamEvent {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "event: stats\n", line)
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		var event streamEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "\n", line)
+
+		return event
+	}
+	first := readEvent()
+	require.Nil(t, first.Rates)
+	second := readEvent()
+	require.Greater(t, second.Stat.InFlows, first.Stat.InFlows)
+	require.InDelta(t, float64(second.Stat.InFlows-first.Stat.InFlows)/second.Interval, second.Rates["InFlows"], 0.001)
+	require.NotContains(t, second.Rates, "InBitRate")
+	require.NotContains(t, second.Rates, "HashMemory")
+	require.Contains(t, second.CPURates, "cpu0")
+	require.Contains(t, second.CPURates["cpu0"], "CPUInPackets")
+	require.NotContains(t, second.CPURates["cpu0"], "CPUInPacketRate")
+	require.Contains(t, second.SocketRates, "sock0")
+	require.Contains(t, second.SocketRates["sock0"], "SockErrFull")
+	require.NotContains(t, second.SocketRates["sock0"], "SockActive")
+	require.NotContains(t, second.SocketRates["sock0"], "SockSndbufFill")
+	require.InDelta(t, 1, testutil.ToFloat64(server.stream.subscribersGauge), 0)
+
+	// shutdown ends streams instead of waiting for them
+	require.NoError(t, server.Shutdown(context.Background()))
+	require.ErrorIs(t, <-served, http.ErrServerClosed)
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+}
+
+func TestStreamSlowSubscriber(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	hub := newStreamHub(cfg.Exporter, nil)
+	subscriber := &streamSubscriber{interval: time.Second, events: make(chan streamSample, 1)}
+	hub.subscribers[subscriber] = struct{}{}
+
+	now := time.Now()
+	hub.publish(streamSample{time: now, stat: statparser.Statistics{InFlows: 1}})
+	// not due yet
+	hub.publish(streamSample{time: now.Add(100 * time.Millisecond), stat: statparser.Statistics{InFlows: 2}})
+	hub.publish(streamSample{time: now.Add(time.Second), stat: statparser.Statistics{InFlows: 3}})
+	require.Len(t, subscriber.events, 1)
+	require.Equal(t, uint64(3), (<-subscriber.events).stat.InFlows)
+	require.InDelta(t, 1, testutil.ToFloat64(hub.dropped), 0)
+}
+
+func TestParseStreamInterval(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"":    defaultStreamInterval,
+		"2s":  2 * time.Second,
+		"1.5": 1500 * time.Millisecond,
+		"1m":  time.Minute,
+	} {
+		interval, err := parseStreamInterval(value)
+		require.NoError(t, err)
+		require.Equal(t, expected, interval)
+	}
+	for _, value := range []string{"bad", "100ms", "1h", "-1"} {
+		_, err := parseStreamInterval(value)
+		require.Error(t, err, value)
+	}
+}
+
+func newGRPCTestClient(t *testing.T, webConfigFile string, stat StatParser) (*GRPCServer, netflowstatsv1.NetflowStatsClient, *grpc.ClientConn) {
+	t.Helper()
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.WebConfigFile = webConfigFile
+	server, err := NewGRPCServer(cfg.Exporter, stat)
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	served := make(chan error, 1)
+	go func() { served <- server.serve(listener) }()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		require.NoError(t, server.Shutdown(context.Background()))
+		require.NoError(t, <-served)
+	})
+
+	return server, netflowstatsv1.NewNetflowStatsClient(conn), conn
+}
+
+func TestGRPCServer(t *testing.T) {
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Times(2)
+	_, client, conn := newGRPCTestClient(t, "", statMock)
+	ctx := context.Background()
+
+	snapshot, err := client.GetStats(ctx, &netflowstatsv1.GetStatsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), snapshot.GetStatistics().GetInFlows())
+	require.InDelta(t, 5.5, snapshot.GetStatistics().GetHashMetric(), 0)
+	require.Equal(t, "cpu0", snapshot.GetStatistics().GetCpus()[0].GetCpu())
+	require.Equal(t, uint64(11), snapshot.GetStatistics().GetCpus()[0].GetErrMaxflows())
+	require.WithinDuration(t, time.Now(), snapshot.GetReadTime().AsTime(), time.Minute)
+
+	sockets, err := client.ListSockets(ctx, &netflowstatsv1.ListSocketsRequest{})
+	require.NoError(t, err)
+	require.Len(t, sockets.GetSockets(), 1)
+	require.Equal(t, "localhost:1234", sockets.GetSockets()[0].GetDestination())
+	require.Equal(t, uint32(1), sockets.GetSockets()[0].GetActive())
+
+	healthResp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "netflowstats.v1.NetflowStats"})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, healthResp.GetStatus())
+
+	reflectionStream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, reflectionStream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	reflectionResp, err := reflectionStream.Recv()
+	require.NoError(t, err)
+	require.NoError(t, reflectionStream.CloseSend())
+	services := []string{}
+	for _, service := range reflectionResp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	require.Contains(t, services, "netflowstats.v1.NetflowStats")
+}
+
+func TestGRPCErrors(t *testing.T) {
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(statparser.Statistics{}, errors.New("no such file")).Once()
+	_, client, _ := newGRPCTestClient(t, "", statMock)
+
+	_, err := client.GetStats(context.Background(), &netflowstatsv1.GetStatsRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Contains(t, err.Error(), "no such file")
+
+	stream, err := client.WatchStats(context.Background(), &netflowstatsv1.WatchStatsRequest{Interval: durationpb.New(time.Millisecond)})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCWatchStats(t *testing.T) {
+	var reads atomic.Uint64
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).RunAndReturn(func(context.Context) (statparser.Statistics, error) {
+		return statparser.Statistics{InFlows: reads.Add(1)}, nil
+	})
+	server, client, _ := newGRPCTestClient(t, "", statMock)
+
+	stream, err := client.WatchStats(context.Background(), &netflowstatsv1.WatchStatsRequest{Interval: durationpb.New(time.Second)})
+	require.NoError(t, err)
+	for expected := range uint64(2) {
+		snapshot, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, expected+1, snapshot.GetStatistics().GetInFlows())
+	}
+
+	// shutdown ends watch streams instead of waiting for them
+	require.NoError(t, server.Shutdown(context.Background()))
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestGRPCCredentials(t *testing.T) {
+	creds, err := grpcCredentials("")
+	require.NoError(t, err)
+	require.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	webConfig := filepath.Join(t.TempDir(), "web-config.yml")
+	require.NoError(t, os.WriteFile(webConfig, []byte("basic_auth_users:\n  user: hash\n"), 0o600))
+	creds, err = grpcCredentials(webConfig)
+	require.NoError(t, err)
+	require.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	require.NoError(t, os.WriteFile(webConfig, []byte("tls_server_config:\n  cert_file: missing.crt\n  key_file: missing.key\n"), 0o600))
+	_, err = grpcCredentials(webConfig)
+	require.ErrorContains(t, err, "error load TLS config")
+}
+
+// writeTestCert writes a self-signed certificate of commonName with its key
+// to cert.pem and key.pem in dir.
+func writeTestCert(t *testing.T, dir, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestGRPCCertificateRotation(t *testing.T) {
+	dir := t.TempDir()
+	webConfig := filepath.Join(dir, "web-config.yml")
+	require.NoError(t, os.WriteFile(webConfig, []byte("tls_server_config:\n  cert_file: cert.pem\n  key_file: key.pem\n"), 0o600))
+	writeTestCert(t, dir, "first")
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.WebConfigFile = webConfig
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Times(2)
+	server, err := NewGRPCServer(cfg.Exporter, statMock)
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	served := make(chan error, 1)
+	go func() { served <- server.serve(listener) }()
+	defer func() {
+		require.NoError(t, server.Shutdown(context.Background()))
+		require.NoError(t, <-served)
+	}()
+
+	// every connection is a new handshake with the certificate in the web config
+	serverName := func() string {
+		var commonName string
+		conn, err := grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+				InsecureSkipVerify: true, //nolint:gosec
+				VerifyConnection: func(state tls.ConnectionState) error {
+					commonName = state.PeerCertificates[0].Subject.CommonName
+
+					return nil
+				},
+			})),
+		)
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = netflowstatsv1.NewNetflowStatsClient(conn).GetStats(context.Background(), &netflowstatsv1.GetStatsRequest{})
+		require.NoError(t, err)
+
+		return commonName
+	}
+	require.Equal(t, "first", serverName())
+	writeTestCert(t, dir, "second")
+	require.Equal(t, "second", serverName())
+}
+
+// basicAuthCredentials sends basic auth credentials with every call.
+type basicAuthCredentials struct {
+	user     string
+	password string
+}
+
+func (c basicAuthCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(c.user+":"+c.password)),
+	}, nil
+}
+
+func (c basicAuthCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func TestGRPCBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	webConfig := filepath.Join(t.TempDir(), "web-config.yml")
+	require.NoError(t, os.WriteFile(webConfig, []byte("basic_auth_users:\n  user: "+string(hash)+"\n"), 0o600))
+	statMock := mocks.NewMockStatParser(t)
+	statMock.EXPECT().CollectAndMarshal(mock.Anything).Return(getTestStatistic(t), nil).Times(4)
+	_, client, conn := newGRPCTestClient(t, webConfig, statMock)
+	ctx := context.Background()
+
+	_, err = client.GetStats(ctx, &netflowstatsv1.GetStatsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	stream, err := client.WatchStats(ctx, &netflowstatsv1.WatchStatsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	for _, creds := range []basicAuthCredentials{{"user", "wrong"}, {"unknown", "secret"}} {
+		_, err = client.GetStats(ctx, &netflowstatsv1.GetStatsRequest{}, grpc.PerRPCCredentials(creds))
+		require.Equal(t, codes.Unauthenticated, status.Code(err), creds.user)
+	}
+
+	creds := grpc.PerRPCCredentials(basicAuthCredentials{"user", "secret"})
+	for range 2 {
+		snapshot, err := client.GetStats(ctx, &netflowstatsv1.GetStatsRequest{}, creds)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), snapshot.GetStatistics().GetInFlows())
+	}
+	stream, err = client.WatchStats(ctx, &netflowstatsv1.WatchStatsRequest{}, creds)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// users are re-read from the web config file on every call
+	require.NoError(t, os.WriteFile(webConfig, []byte("basic_auth_users:\n  other: "+string(hash)+"\n"), 0o600))
+	_, err = client.GetStats(ctx, &netflowstatsv1.GetStatsRequest{}, creds)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetStats(ctx, &netflowstatsv1.GetStatsRequest{}, grpc.PerRPCCredentials(basicAuthCredentials{"other", "secret"}))
+	require.NoError(t, err)
+}
+
+func TestAlertEngine(t *testing.T) {
+	var mu sync.Mutex
+	webhooks := []webhookPayload{}
+	alertmanager := [][]alertNotification{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch req.URL.Path {
+		case "/hook":
+			var payload webhookPayload
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+			webhooks = append(webhooks, payload)
+		case "/am/api/v2/alerts":
+			var alerts []alertNotification
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&alerts))
+			alertmanager = append(alertmanager, alerts)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer receiver.Close()
+
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.AlertRules = []config.AlertRule{
+		{Name: "FlowsLost", Expr: "rate(lost_flows) > 0 for 2m", Severity: "warning"},
+		{Name: "SocketDown", Expr: "socket_active == 0", Severity: "critical", Summary: "socket is down"},
+		{Name: "SndbufFull", Expr: "socket_snd_buf_fill_percent > 90%"},
+	}
+	cfg.Exporter.AlertWebhookURLs = []string{receiver.URL + "/hook"}
+	cfg.Exporter.AlertmanagerURLs = []string{receiver.URL + "/am/", receiver.URL + "/missing"}
+	engine := newAlertEngine(cfg.Exporter, nil)
+	ctx := context.Background()
+	step := func(now time.Time, stat statparser.Statistics) {
+		changes, alertmanagerAlerts := engine.evaluate(now, stat)
+		engine.send(ctx, changes, alertmanagerAlerts)
+	}
+	stat := getTestStatistic(t)
+	stat.SockStatList[0].SockSndbuf = 100
+	stat.SockStatList[0].SockSndbufFill = 50
+
+	start := time.Now()
+	step(start, stat)
+	require.Empty(t, webhooks)
+	require.Empty(t, alertmanager)
+
+	// one flow lost per second, socket down
+	stat.LostFlows += 60
+	stat.SockStatList[0].SockActive = 0
+	step(start.Add(time.Minute), stat)
+	require.Len(t, webhooks, 1)
+	require.Equal(t, alertStateFiring, webhooks[0].Status)
+	require.Len(t, webhooks[0].Alerts, 1)
+	require.Equal(t, map[string]string{
+		"alertname": "SocketDown", "severity": "critical", "socket": "sock0", "destination": "localhost:1234",
+	}, webhooks[0].Alerts[0].Labels)
+	require.Equal(t, "socket is down", webhooks[0].Alerts[0].Annotations["summary"])
+	require.Len(t, alertmanager, 1)
+	require.Len(t, alertmanager[0], 1)
+	require.True(t, alertmanager[0][0].EndsAt.After(start.Add(time.Minute)))
+	require.InDelta(t, 1, testutil.ToFloat64(engine.notificationFailures.WithLabelValues("alertmanager")), 0)
+	expected := `
+	# HELP ipt_netflow_exporter_alerts Number of pending and firing alerts by rule.
+	# TYPE ipt_netflow_exporter_alerts gauge
+	ipt_netflow_exporter_alerts{rule="FlowsLost",state="firing"} 0
+	ipt_netflow_exporter_alerts{rule="FlowsLost",state="pending"} 1
+	ipt_netflow_exporter_alerts{rule="SndbufFull",state="firing"} 0
+	ipt_netflow_exporter_alerts{rule="SndbufFull",state="pending"} 0
+	ipt_netflow_exporter_alerts{rule="SocketDown",state="firing"} 1
+	ipt_netflow_exporter_alerts{rule="SocketDown",state="pending"} 0
+	`
+	require.NoError(t, testutil.CollectAndCompare(engine, strings.NewReader(expected), "ipt_netflow_exporter_alerts"))
+
+	// loss holds for 2m, unchanged rules keep their state on reload
+	engine.update(cfg.Exporter)
+	stat.LostFlows += 120
+	step(start.Add(3*time.Minute), stat)
+	require.Len(t, webhooks, 2)
+	require.Equal(t, "FlowsLost", webhooks[1].Alerts[0].Labels["alertname"])
+	require.Len(t, alertmanager[1], 2)
+
+	// no changes and no resend due
+	stat.LostFlows += 10
+	step(start.Add(3*time.Minute+10*time.Second), stat)
+	require.Len(t, webhooks, 2)
+	require.Len(t, alertmanager, 2)
+
+	// loss stopped and the socket is gone
+	stat.SockStatList = nil
+	step(start.Add(4*time.Minute), stat)
+	require.Len(t, webhooks, 3)
+	require.Equal(t, alertStatusResolved, webhooks[2].Status)
+	require.Len(t, webhooks[2].Alerts, 2)
+	for _, alert := range webhooks[2].Alerts {
+		require.Equal(t, start.Add(4*time.Minute).Unix(), alert.EndsAt.Unix())
+	}
+	require.Len(t, alertmanager[2], 2)
+	require.Empty(t, engine.instances)
+	require.InDelta(t, 3, testutil.ToFloat64(engine.notifications.WithLabelValues("webhook")), 0)
+	require.InDelta(t, 5, testutil.ToFloat64(engine.evaluations), 0)
+}
+
+func TestAlertEngineReload(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.AlertRules = []config.AlertRule{
+		{Name: "SocketDown", Expr: "socket_active == 0"},
+		{Name: "HashMetric", Expr: "hash_metrics > 1"},
+	}
+	engine := newAlertEngine(cfg.Exporter, nil)
+	stat := getTestStatistic(t)
+	stat.SockStatList[0].SockActive = 0
+	changes, _ := engine.evaluate(time.Now(), stat)
+	require.Len(t, changes, 2)
+
+	cfg.Exporter.AlertRules = []config.AlertRule{
+		{Name: "SocketDown", Expr: "socket_active == 0"},
+		{Name: "HashMetric", Expr: "hash_metrics > 10"},
+	}
+	engine.update(cfg.Exporter)
+	require.Len(t, engine.instances, 1)
+	// still firing, so no new notification
+	changes, _ = engine.evaluate(time.Now(), stat)
+	require.Empty(t, changes)
+}
+
+func TestAlertMetricNames(t *testing.T) {
+	families, err := MetricFamilies()
+	require.NoError(t, err)
+	names := []string{}
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	for name, metric := range config.AlertMetrics {
+		if metric.PercentOf == "" {
+			require.Contains(t, names, MetricsNamespace+"_"+name)
+		}
+	}
+}
+
+func TestAlertmanagerAlertsURL(t *testing.T) {
+	for _, tCase := range []struct {
+		baseURL string
+		url     string
+		error   string
+	}{
+		{"http://localhost:9093", "http://localhost:9093/api/v2/alerts", ""},
+		{"https://am.example.com/prefix/", "https://am.example.com/prefix/api/v2/alerts", ""},
+		{"localhost:9093", "", "error incorrect Alertmanager URL localhost:9093: must be http or https URL with host"},
+		{"ftp://localhost", "", "error incorrect Alertmanager URL ftp://localhost: must be http or https URL with host"},
+	} {
+		alertsURL, err := alertmanagerAlertsURL(tCase.baseURL)
+		if tCase.error != "" {
+			require.EqualError(t, err, tCase.error)
+
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tCase.url, alertsURL)
+	}
+}
+
+func TestAlertEngineReloadDuringSend(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.AlertWebhookURLs = []string{receiver.URL}
+	engine := newAlertEngine(cfg.Exporter, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 5 {
+			engine.send(context.Background(), []alertNotification{{Status: alertStateFiring}}, nil)
+		}
+	}()
+	// reloads must not race with notifications in flight, checked with -race
+	for reloading := true; reloading; {
+		select {
+		case <-done:
+			reloading = false
+		default:
+			engine.update(cfg.Exporter)
+		}
+	}
+	require.InDelta(t, 5, testutil.ToFloat64(engine.notifications.WithLabelValues("webhook")), 0)
+}
+
+func TestAlertEngineRun(t *testing.T) {
+	cfg, err := config.ReadConfig("")
+	require.NoError(t, err)
+	cfg.Exporter.AlertEvaluationInterval = 0
+	cfg.Exporter.AlertRules = []config.AlertRule{{Name: "Lost", Expr: "lost_total > 0"}}
+	var engine *alertEngine
+	engine = newAlertEngine(cfg.Exporter, func(context.Context) (statparser.Statistics, error) {
+		engine.close()
+
+		return statparser.Statistics{}, errors.New("read error")
+	})
+	// run returns after close, the failed read is still counted
+	engine.run()
+	require.GreaterOrEqual(t, testutil.ToFloat64(engine.evaluationFailures), 1.0)
+}
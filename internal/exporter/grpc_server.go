@@ -0,0 +1,375 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	netflowstatsv1 "github.com/mythvcode/ipt-netflow-exporter/api/netflowstats/v1"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/prometheus/exporter-toolkit/web"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StatReaderFunc adapts a function to StatParser, e.g. to read with the
+// stat collector of the current config.
+type StatReaderFunc func(ctx context.Context) (statparser.Statistics, error)
+
+func (f StatReaderFunc) CollectAndMarshal(ctx context.Context) (statparser.Statistics, error) {
+	return f(ctx)
+}
+
+// GRPCServer serves the NetflowStats service with health and reflection
+// services on its own listener. TLS and basic auth users are configured by
+// the web config file, which is re-read on every connection and call as on
+// the HTTP listeners. Enabling or disabling TLS requires a restart.
+type GRPCServer struct {
+	server   *grpc.Server
+	health   *health.Server
+	log      *logger.Logger
+	config   config.Exporter
+	listener net.Listener
+	service  *statsService
+}
+
+// statsService implements netflowstatsv1.NetflowStatsServer.
+type statsService struct {
+	netflowstatsv1.UnimplementedNetflowStatsServer
+	stat    StatParser
+	timeout time.Duration
+	// stopping ends watch streams, so that graceful stop does not wait for them.
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// basicAuth checks the authorization metadata of calls against bcrypt
+// hashes of basic auth users of the web config file, as the HTTP listeners
+// do. Calls are allowed when the file has no users.
+type basicAuth struct {
+	webConfigFile string
+	log           *logger.Logger
+	// mu runs one bcrypt comparison at a time as it is CPU intensive, valid
+	// caches successful ones.
+	mu    sync.Mutex
+	valid map[[sha256.Size]byte]bool
+}
+
+// unknownUserHash is compared for unknown users, so that they cannot be told
+// from known ones by response time.
+const unknownUserHash = "$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi"
+
+func NewGRPCServer(cfg config.Exporter, stat StatParser) (*GRPCServer, error) {
+	creds, err := grpcCredentials(cfg.WebConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	log := logger.GetLogger().With(slog.String(logger.Component, "grpc-server"))
+	options := []grpc.ServerOption{grpc.Creds(creds)}
+	if cfg.WebConfigFile != "" {
+		auth := &basicAuth{webConfigFile: cfg.WebConfigFile, log: log, valid: map[[sha256.Size]byte]bool{}}
+		options = append(options, grpc.UnaryInterceptor(auth.unary), grpc.StreamInterceptor(auth.stream))
+	}
+	grpcServer := &GRPCServer{
+		server: grpc.NewServer(options...),
+		health: health.NewServer(),
+		log:    log,
+		config: cfg,
+		service: &statsService{
+			stat:     stat,
+			timeout:  time.Duration(cfg.RequestTimeout) * time.Second,
+			stopping: make(chan struct{}),
+		},
+	}
+	netflowstatsv1.RegisterNetflowStatsServer(grpcServer.server, grpcServer.service)
+	healthpb.RegisterHealthServer(grpcServer.server, grpcServer.health)
+	grpcServer.health.SetServingStatus(netflowstatsv1.NetflowStats_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	reflection.Register(grpcServer.server)
+
+	return grpcServer, nil
+}
+
+// grpcCredentials returns TLS credentials when tls_server_config of the web
+// config file is set, or insecure ones otherwise. The TLS config and
+// certificates are re-read on every connection.
+func grpcCredentials(webConfigFile string) (credentials.TransportCredentials, error) {
+	if webConfigFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+	webConfig, err := config.ReadWebConfig(webConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if webConfig.TLSConfig.TLSCertPath == "" && webConfig.TLSConfig.TLSCert == "" {
+		return insecure.NewCredentials(), nil
+	}
+	tlsConfig, err := grpcTLSConfig(webConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return grpcTLSConfig(webConfigFile)
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+func grpcTLSConfig(webConfigFile string) (*tls.Config, error) {
+	webConfig, err := config.ReadWebConfig(webConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := web.ConfigToTLSConfig(&webConfig.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error load TLS config from %s: %w", webConfigFile, err)
+	}
+	// configs returned for clients replace the one gRPC added h2 to
+	tlsConfig.NextProtos = []string{"h2"}
+
+	return tlsConfig, nil
+}
+
+func (a *basicAuth) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *basicAuth) stream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, stream)
+}
+
+// authorize checks basic credentials of the authorization metadata against
+// users currently in the web config file.
+func (a *basicAuth) authorize(ctx context.Context) error {
+	webConfig, err := config.ReadWebConfig(a.webConfigFile)
+	if err != nil {
+		a.log.ErrorErr("Error read web config for basic auth", err, slog.String(logger.FileKey, a.webConfigFile))
+
+		return status.Error(codes.Internal, "error read web config")
+	}
+	if len(webConfig.Users) == 0 {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return status.Error(codes.Unauthenticated, "basic auth credentials are required")
+	}
+	encoded, ok := strings.CutPrefix(values[0], "Basic ")
+	if !ok {
+		return status.Error(codes.Unauthenticated, "basic auth credentials are required")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "incorrect basic auth credentials")
+	}
+	user, password, _ := strings.Cut(string(decoded), ":")
+	secret, known := webConfig.Users[user]
+	hash := string(secret)
+	if !known {
+		hash = unknownUserHash
+	}
+	key := sha256.Sum256([]byte(user + "\x00" + hash + "\x00" + password))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.valid[key] {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !known {
+			return status.Error(codes.Unauthenticated, "incorrect basic auth credentials")
+		}
+		a.valid[key] = true
+	}
+
+	return nil
+}
+
+// Listen opens the gRPC listener.
+func (s *GRPCServer) Listen() error {
+	s.log.Info("Starting gRPC server", slog.String("listen", s.config.GRPCListenAddress))
+	listener, err := listenAddress(s.config.GRPCListenAddress, s.config.UnixSocketMode)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	return nil
+}
+
+// Serve blocks serving connections on the listener opened by Listen.
+func (s *GRPCServer) Serve() error {
+	return s.serve(s.listener)
+}
+
+func (s *GRPCServer) serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown ends watch streams and waits for other calls until ctx is done,
+// then closes remaining connections.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	s.log.Info("Stopping gRPC server")
+	s.health.Shutdown()
+	s.service.stopOnce.Do(func() { close(s.service.stopping) })
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.log.ErrorErr("Error graceful stop gRPC server", ctx.Err())
+		s.server.Stop()
+
+		return ctx.Err()
+	}
+}
+
+func (s *statsService) GetStats(ctx context.Context, _ *netflowstatsv1.GetStatsRequest) (*netflowstatsv1.StatsSnapshot, error) {
+	return s.snapshot(ctx)
+}
+
+func (s *statsService) WatchStats(
+	req *netflowstatsv1.WatchStatsRequest,
+	stream grpc.ServerStreamingServer[netflowstatsv1.StatsSnapshot],
+) error {
+	interval := defaultStreamInterval
+	if req.GetInterval() != nil {
+		interval = req.GetInterval().AsDuration()
+	}
+	if interval < streamReadInterval || interval > maxStreamInterval {
+		return status.Errorf(codes.InvalidArgument, "interval must be from %s to %s", streamReadInterval, maxStreamInterval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		snapshot, err := s.snapshot(stream.Context())
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(snapshot); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *statsService) ListSockets(ctx context.Context, _ *netflowstatsv1.ListSocketsRequest) (*netflowstatsv1.ListSocketsResponse, error) {
+	snapshot, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &netflowstatsv1.ListSocketsResponse{Sockets: snapshot.GetStatistics().GetSockets()}, nil
+}
+
+// snapshot reads the stat file within the call deadline, or the request
+// timeout when the deadline is longer or not set.
+func (s *statsService) snapshot(ctx context.Context) (*netflowstatsv1.StatsSnapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	readTime := time.Now()
+	stat, err := s.stat.CollectAndMarshal(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &netflowstatsv1.StatsSnapshot{ReadTime: timestamppb.New(readTime), Statistics: statisticsToProto(&stat)}, nil
+}
+
+func statisticsToProto(stat *statparser.Statistics) *netflowstatsv1.Statistics {
+	result := &netflowstatsv1.Statistics{
+		InBitRate:    stat.InBitRate,
+		InPacketRate: stat.InPacketRate,
+		InFlows:      stat.InFlows,
+		InPackets:    stat.InPackets,
+		InBytes:      stat.InBytes,
+		HashMetric:   stat.HashMetric,
+		HashMemory:   stat.HashMemory,
+		HashFlows:    stat.HashFlows,
+		HashPackets:  stat.HashPackets,
+		HashBytes:    stat.HashBytes,
+		DropPackets:  stat.DropPackets,
+		DropBytes:    stat.DropBytes,
+		OutByteRate:  stat.OutByteRate,
+		OutFlows:     stat.OutFlows,
+		OutPackets:   stat.OutPackets,
+		OutBytes:     stat.OutBytes,
+		LostFlows:    stat.LostFlows,
+		LostPackets:  stat.LostPackets,
+		LostBytes:    stat.LostBytes,
+		ErrTotal:     stat.ErrTotal,
+		SndbufPeak:   stat.SndbufPeak,
+		Cpus:         make([]*netflowstatsv1.CPUStat, 0, len(stat.CPUStatList)),
+		Sockets:      make([]*netflowstatsv1.NFSockEntry, 0, len(stat.SockStatList)),
+	}
+	for _, cpu := range stat.CPUStatList {
+		result.Cpus = append(result.Cpus, &netflowstatsv1.CPUStat{
+			Cpu:          cpu.CPU,
+			InPacketRate: cpu.CPUInPacketRate,
+			InFlows:      cpu.CPUInFlows,
+			InPackets:    cpu.CPUInPackets,
+			InBytes:      cpu.CPUInBytes,
+			HashMetric:   cpu.CPUHashMetric,
+			DropPackets:  cpu.CPUDropPackets,
+			DropBytes:    cpu.CPUuDropBytes,
+			ErrTrunc:     cpu.CPUErrTrunc,
+			ErrFrag:      cpu.CPUErrFrag,
+			ErrAlloc:     cpu.CPUErrAlloc,
+			ErrMaxflows:  cpu.CPUErrMaxflows,
+		})
+	}
+	for _, socket := range stat.SockStatList {
+		result.Sockets = append(result.Sockets, &netflowstatsv1.NFSockEntry{
+			Name:        socket.SockName,
+			Destination: socket.SockDestination,
+			Active:      socket.SockActive,
+			ErrConnect:  socket.SockErrConnect,
+			ErrFull:     socket.SockErrFull,
+			ErrCberr:    socket.SockErrCberr,
+			ErrOther:    socket.SockErrOther,
+			Sndbuf:      socket.SockSndbuf,
+			SndbufFill:  socket.SockSndbufFill,
+			SndbufPeak:  socket.SockSndbufPeak,
+		})
+	}
+
+	return result
+}
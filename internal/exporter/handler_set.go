@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// handlerSet is the reloadable part of the API server: collectors, their
+// registry and routes built from one config.
+type handlerSet struct {
+	config    config.Exporter
+	collector *IPTNetFlowTCollector
+	mux       *http.ServeMux
+}
+
+func (s *APIServer) newHandlerSet(cfg config.Exporter, stat StatParser) (*handlerSet, error) {
+	handlers := handlerSet{
+		config:    cfg,
+		collector: newIPTNetFlowTCollector(stat),
+		mux:       http.NewServeMux(),
+	}
+	if !handlers.collector.Initialized() {
+		return nil, fmt.Errorf("collector %s was not initialized", handlers.collector.Name())
+	}
+	handlers.collector.scrapeErrors = s.scrapeErrors
+	// the IPT collector is registered per scrape, bound to its deadline
+	registry := prometheus.NewRegistry()
+	if cfg.EnableRuntimeMetrics {
+		if err := registry.Register(collectors.NewGoCollector()); err != nil {
+			return nil, err
+		}
+	}
+
+	metricsHandler := promhttp.InstrumentMetricHandler(
+		s.registry,
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, cancel := scrapeContext(req, cfg)
+			defer cancel()
+			scrapeRegistry := prometheus.NewRegistry()
+			if err := scrapeRegistry.Register(handlers.collector.withContext(ctx)); err != nil {
+				http.Error(w, "error register collector: "+err.Error(), http.StatusInternalServerError)
+
+				return
+			}
+			gatherers := prometheus.Gatherers{s.registry, registry, scrapeRegistry}
+			promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, req)
+		}),
+	)
+	accessLog := newAccessLog(cfg, s.httpMetrics)
+	// limited routes read /proc or change state, so they are protected from
+	// misbehaving clients
+	limited := func(name string, handler http.Handler) http.Handler {
+		return accessLog.wrap(name, s.limiter.wrap(name, handler))
+	}
+	handlers.mux.Handle("/", limited("index", http.HandlerFunc(s.indexPage)))
+	handlers.mux.Handle(cfg.TelemetryPath, limited("metrics", metricsHandler))
+	if cfg.StreamMaxSubscribers > 0 {
+		// streams are limited by subscriber count instead of concurrency
+		handlers.mux.Handle(streamPath, accessLog.wrap("stream", s.limiter.wrapRate("stream", http.HandlerFunc(s.streamHandler))))
+	}
+	if cfg.LogLevelEndpoint {
+		handlers.mux.Handle(logLevelPath, limited("log-level", http.HandlerFunc(s.logLevelHandler)))
+	}
+
+	return &handlers, nil
+}
+
+// scrapeContext returns the context for reading the stat file in a scrape.
+// Its deadline is the Prometheus scrape timeout minus the configured offset,
+// or the request timeout when the header is missing.
+func scrapeContext(req *http.Request, cfg config.Exporter) (context.Context, context.CancelFunc) {
+	var scrapeTimeout time.Duration
+	if header := req.Header.Get(scrapeTimeoutHeader); header != "" {
+		if seconds, err := strconv.ParseFloat(header, 64); err == nil && seconds > 0 {
+			scrapeTimeout = time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	return context.WithTimeout(req.Context(), cfg.ScrapeTimeout(scrapeTimeout))
+}
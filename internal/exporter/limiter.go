@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	rejectConcurrency = "concurrency"
+	rejectRateLimit   = "rate_limit"
+	// idleBucketTTL is how long a client bucket is kept after its last request.
+	idleBucketTTL = 10 * time.Minute
+)
+
+// bucket is a token bucket of one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter rejects requests exceeding the concurrency limit with 503 and
+// requests of clients exceeding their rate with 429. It lives as long as the
+// server, so limits and client buckets are kept on config reload.
+type limiter struct {
+	maxInFlight atomic.Int64
+	inFlight    atomic.Int64
+
+	mu sync.Mutex
+	// rate is tokens per second, zero disables rate limiting.
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+
+	rejected *prometheus.CounterVec
+}
+
+func newLimiter(cfg config.Exporter) *limiter {
+	l := &limiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "http_requests_rejected_total",
+				Help:      "Total number of HTTP requests rejected by concurrency or rate limits.",
+			},
+			[]string{"handler", "reason"},
+		),
+	}
+	l.update(cfg)
+
+	return l
+}
+
+// update applies limits from cfg, e.g. after config reload.
+func (l *limiter) update(cfg config.Exporter) {
+	l.maxInFlight.Store(int64(cfg.MaxConcurrentScrapes))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = float64(cfg.ClientRateLimit) / 60
+	l.burst = float64(max(cfg.ClientRateBurst, 1))
+	for _, clientBucket := range l.buckets {
+		clientBucket.tokens = math.Min(clientBucket.tokens, l.burst)
+	}
+}
+
+func (l *limiter) Describe(ch chan<- *prometheus.Desc) {
+	l.rejected.Describe(ch)
+}
+
+func (l *limiter) Collect(metricChan chan<- prometheus.Metric) {
+	l.rejected.Collect(metricChan)
+}
+
+// wrap limits a route, handler is the route name used in metric labels.
+func (l *limiter) wrap(handler string, next http.Handler) http.Handler {
+	return l.wrapRate(handler, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		inFlight := l.inFlight.Add(1)
+		defer l.inFlight.Add(-1)
+		if maxInFlight := l.maxInFlight.Load(); maxInFlight > 0 && inFlight > maxInFlight {
+			l.rejected.WithLabelValues(handler, rejectConcurrency).Inc()
+			http.Error(w, "Limit of concurrent requests reached, try again later.", http.StatusServiceUnavailable)
+
+			return
+		}
+		next.ServeHTTP(w, req)
+	}))
+}
+
+// wrapRate applies only the client rate limit, for long-lived requests like
+// streams which have their own limit and would hold a concurrency slot.
+func (l *limiter) wrapRate(handler string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if wait, ok := l.allow(clientIP(req.RemoteAddr)); !ok {
+			l.rejected.WithLabelValues(handler, rejectRateLimit).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests, try again later.", http.StatusTooManyRequests)
+
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// allow takes a token from the client bucket. When the bucket is empty it
+// returns the time until the next token.
+func (l *limiter) allow(client string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0, true
+	}
+	now := l.now()
+	l.sweep(now)
+	clientBucket, ok := l.buckets[client]
+	if !ok {
+		clientBucket = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = clientBucket
+	}
+	elapsed := now.Sub(clientBucket.last).Seconds()
+	clientBucket.tokens = math.Min(l.burst, clientBucket.tokens+elapsed*l.rate)
+	clientBucket.last = now
+	if clientBucket.tokens < 1 {
+		return time.Duration((1 - clientBucket.tokens) / l.rate * float64(time.Second)), false
+	}
+	clientBucket.tokens--
+
+	return 0, true
+}
+
+// sweep removes buckets of clients idle long enough to have refilled.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.lastSweep = now
+	ttl := max(idleBucketTTL, time.Duration(l.burst/l.rate*float64(time.Second)))
+	for client, clientBucket := range l.buckets {
+		if now.Sub(clientBucket.last) > ttl {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP returns the host of the remote address. Clients connected over a
+// unix socket share one bucket.
+func clientIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+
+	return remoteAddr
+}
@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+)
+
+var errNoSystemdListeners = errors.New("no socket activation file descriptors found")
+
+// listen opens every configured listener. With systemd_socket enabled the
+// sockets passed by systemd (LISTEN_FDS) are used instead of listen addresses.
+func listen(cfg config.Exporter) ([]net.Listener, error) {
+	if cfg.SystemdSocket {
+		listeners, err := activation.Listeners()
+		if err != nil {
+			return nil, fmt.Errorf("error get systemd listeners: %w", err)
+		}
+		if len(listeners) == 0 {
+			return nil, errNoSystemdListeners
+		}
+
+		return listeners, nil
+	}
+
+	listeners := make([]net.Listener, 0, len(cfg.Listeners()))
+	for _, address := range cfg.Listeners() {
+		listener, err := listenAddress(address, cfg.UnixSocketMode)
+		if err != nil {
+			closeListeners(listeners)
+
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+func listenAddress(address string, unixSocketMode string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, config.UnixSocketPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	mode, err := strconv.ParseUint(unixSocketMode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("error parse unix socket mode %s: %w", unixSocketMode, err)
+	}
+	path = filepath.Clean(path)
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, fs.FileMode(mode)); err != nil {
+		listener.Close()
+
+		return nil, fmt.Errorf("error set unix socket %s mode: %w", path, err)
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket removes a socket left after unclean shutdown. Other files
+// are never removed, so that a mistyped path does not delete data.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error check unix socket %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("error incorrect unix socket %s: file exists and is not a socket", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("error remove unix socket %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+)
+
+const logLevelPath = "/-/log-level"
+
+// logLevelHandler shows log levels on GET and changes them on PUT with a JSON
+// body like {"level":"info","components":{"StatCollector":"debug"}}. Omitted
+// level is kept, a component with empty level is removed from overrides.
+// Access is restricted by web config basic auth like any other route.
+func (s *APIServer) logLevelHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var update logger.Levels
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 64*1024)).Decode(&update); err != nil {
+			http.Error(w, "error decode log levels: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+		if err := logger.SetLevels(update); err != nil {
+			http.Error(w, "error set log levels: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+		levels := logger.GetLevels()
+		s.log.Info("Log levels changed",
+			slog.String("addr", req.RemoteAddr),
+			slog.String("level", levels.Level),
+			slog.Any("components", levels.Components),
+		)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(logger.GetLevels()); err != nil {
+		s.log.ErrorErr("Error write log levels", err)
+	}
+}
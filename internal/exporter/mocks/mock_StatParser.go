@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	statparser "github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -20,9 +22,9 @@ func (_m *MockStatParser) EXPECT() *MockStatParser_Expecter {
 	return &MockStatParser_Expecter{mock: &_m.Mock}
 }
 
-// CollectAndMarshal provides a mock function with no fields
-func (_m *MockStatParser) CollectAndMarshal() (statparser.Statistics, error) {
-	ret := _m.Called()
+// CollectAndMarshal provides a mock function with given fields: ctx
+func (_m *MockStatParser) CollectAndMarshal(ctx context.Context) (statparser.Statistics, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CollectAndMarshal")
@@ -30,17 +32,17 @@ func (_m *MockStatParser) CollectAndMarshal() (statparser.Statistics, error) {
 
 	var r0 statparser.Statistics
 	var r1 error
-	if rf, ok := ret.Get(0).(func() (statparser.Statistics, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(context.Context) (statparser.Statistics, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func() statparser.Statistics); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) statparser.Statistics); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(statparser.Statistics)
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,13 +56,14 @@ type MockStatParser_CollectAndMarshal_Call struct {
 }
 
 // CollectAndMarshal is a helper method to define mock.On call
-func (_e *MockStatParser_Expecter) CollectAndMarshal() *MockStatParser_CollectAndMarshal_Call {
-	return &MockStatParser_CollectAndMarshal_Call{Call: _e.mock.On("CollectAndMarshal")}
+//   - ctx context.Context
+func (_e *MockStatParser_Expecter) CollectAndMarshal(ctx interface{}) *MockStatParser_CollectAndMarshal_Call {
+	return &MockStatParser_CollectAndMarshal_Call{Call: _e.mock.On("CollectAndMarshal", ctx)}
 }
 
-func (_c *MockStatParser_CollectAndMarshal_Call) Run(run func()) *MockStatParser_CollectAndMarshal_Call {
+func (_c *MockStatParser_CollectAndMarshal_Call) Run(run func(ctx context.Context)) *MockStatParser_CollectAndMarshal_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		run(args[0].(context.Context))
 	})
 	return _c
 }
@@ -70,7 +73,7 @@ func (_c *MockStatParser_CollectAndMarshal_Call) Return(_a0 statparser.Statistic
 	return _c
 }
 
-func (_c *MockStatParser_CollectAndMarshal_Call) RunAndReturn(run func() (statparser.Statistics, error)) *MockStatParser_CollectAndMarshal_Call {
+func (_c *MockStatParser_CollectAndMarshal_Call) RunAndReturn(run func(context.Context) (statparser.Statistics, error)) *MockStatParser_CollectAndMarshal_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"slices"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type reloadMetrics struct {
+	lastReloadSuccessful  prometheus.Gauge
+	lastReloadSuccessTime prometheus.Gauge
+	lastReloadAttemptTime prometheus.Gauge
+}
+
+func newReloadMetrics() *reloadMetrics {
+	return &reloadMetrics{
+		lastReloadSuccessful: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "config_last_reload_successful",
+				Help:      "Whether the last configuration reload attempt was successful.",
+			},
+		),
+		lastReloadSuccessTime: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "config_last_reload_success_timestamp_seconds",
+				Help:      "Timestamp of the last successful configuration reload.",
+			},
+		),
+		lastReloadAttemptTime: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "config_last_reload_attempt_timestamp_seconds",
+				Help:      "Timestamp of the last configuration reload attempt.",
+			},
+		),
+	}
+}
+
+func (r *reloadMetrics) update(err error) {
+	r.lastReloadAttemptTime.SetToCurrentTime()
+	if err != nil {
+		r.lastReloadSuccessful.Set(0)
+
+		return
+	}
+	r.lastReloadSuccessful.Set(1)
+	r.lastReloadSuccessTime.SetToCurrentTime()
+}
+
+func (r *reloadMetrics) metricList() []prometheus.Collector {
+	return []prometheus.Collector{
+		r.lastReloadSuccessful,
+		r.lastReloadSuccessTime,
+		r.lastReloadAttemptTime,
+	}
+}
+
+func (r *reloadMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range r.metricList() {
+		metric.Describe(ch)
+	}
+}
+
+func (r *reloadMetrics) Collect(metricChan chan<- prometheus.Metric) {
+	for _, metric := range r.metricList() {
+		metric.Collect(metricChan)
+	}
+}
+
+// Reload atomically replaces collectors, stat parser and routes with ones
+// built from cfg. In-flight requests finish with the previous handlers.
+// Request limits are updated in place, keeping client rate limit state.
+// Listener, timeout and web config settings are applied only on restart.
+func (s *APIServer) Reload(cfg config.Exporter, stat StatParser) error {
+	handlers, err := s.newHandlerSet(cfg, stat)
+	if err != nil {
+		return err
+	}
+	if restartRequired(s.config, cfg) {
+		s.log.Warning("listener, timeout and web config settings changed, restart exporter to apply them")
+	}
+	s.limiter.update(cfg)
+	s.stream.update(cfg)
+	s.alerts.update(cfg)
+	s.handlers.Store(handlers)
+
+	return nil
+}
+
+// ReportReload records the result of a config reload attempt in metrics.
+func (s *APIServer) ReportReload(err error) {
+	s.reloadMetrics.update(err)
+}
+
+func restartRequired(running, reloaded config.Exporter) bool {
+	return running.ServerAddress != reloaded.ServerAddress ||
+		running.ServerPort != reloaded.ServerPort ||
+		!slices.Equal(running.ListenAddresses, reloaded.ListenAddresses) ||
+		running.UnixSocketMode != reloaded.UnixSocketMode ||
+		running.SystemdSocket != reloaded.SystemdSocket ||
+		running.RequestTimeout != reloaded.RequestTimeout ||
+		running.WebConfigFile != reloaded.WebConfigFile ||
+		running.DebugListenAddress != reloaded.DebugListenAddress ||
+		running.GRPCListenAddress != reloaded.GRPCListenAddress
+}
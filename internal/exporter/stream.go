@@ -0,0 +1,358 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	streamPath = "/api/v1/stream"
+	// streamReadInterval is the period of the background reader, subscriber
+	// intervals are rounded up to it.
+	streamReadInterval    = time.Second
+	defaultStreamInterval = 5 * time.Second
+	maxStreamInterval     = 10 * time.Minute
+)
+
+var errStreamFull = errors.New("limit of stream subscribers reached")
+
+// streamSample is one read of the stat file by the background reader.
+type streamSample struct {
+	time time.Time
+	stat statparser.Statistics
+	err  error
+}
+
+// streamSubscriber receives samples every interval. events holds only the
+// latest sample, so a slow client skips samples instead of blocking others.
+type streamSubscriber struct {
+	interval time.Duration
+	next     time.Time
+	events   chan streamSample
+}
+
+// streamHub shares one background reader of the stat file among all stream
+// subscribers. The reader runs only while there are subscribers. It lives as
+// long as the server.
+type streamHub struct {
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	// stopReader stops the background reader, nil when it is not running.
+	stopReader     context.CancelFunc
+	closed         chan struct{}
+	maxSubscribers atomic.Int64
+	read           func(ctx context.Context) (statparser.Statistics, error)
+
+	subscribersGauge prometheus.Gauge
+	dropped          prometheus.Counter
+}
+
+// streamEvent is the data of a stats event. Rates are per second deltas of
+// integer fields since the previous event of the subscriber, counters which
+// went backwards are omitted.
+type streamEvent struct {
+	Time        time.Time                     `json:"time"`
+	Interval    float64                       `json:"interval_seconds,omitempty"`
+	Stat        statparser.Statistics         `json:"stat"`
+	Rates       map[string]float64            `json:"rates,omitempty"`
+	CPURates    map[string]map[string]float64 `json:"cpu_rates,omitempty"`
+	SocketRates map[string]map[string]float64 `json:"socket_rates,omitempty"`
+}
+
+type streamError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+func newStreamHub(cfg config.Exporter, read func(ctx context.Context) (statparser.Statistics, error)) *streamHub {
+	hub := &streamHub{
+		subscribers: map[*streamSubscriber]struct{}{},
+		closed:      make(chan struct{}),
+		read:        read,
+		subscribersGauge: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "stream_subscribers",
+				Help:      "Number of connected statistics stream subscribers.",
+			},
+		),
+		dropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Subsystem: "exporter",
+				Name:      "stream_dropped_events_total",
+				Help:      "Total number of stream events skipped because a subscriber was too slow.",
+			},
+		),
+	}
+	hub.update(cfg)
+
+	return hub
+}
+
+// update applies the subscriber limit from cfg, connected subscribers above
+// the new limit stay connected.
+func (h *streamHub) update(cfg config.Exporter) {
+	h.maxSubscribers.Store(int64(cfg.StreamMaxSubscribers))
+}
+
+func (h *streamHub) Describe(ch chan<- *prometheus.Desc) {
+	h.subscribersGauge.Describe(ch)
+	h.dropped.Describe(ch)
+}
+
+func (h *streamHub) Collect(metricChan chan<- prometheus.Metric) {
+	h.subscribersGauge.Collect(metricChan)
+	h.dropped.Collect(metricChan)
+}
+
+func (h *streamHub) subscribe(interval time.Duration) (*streamSubscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	select {
+	case <-h.closed:
+		return nil, http.ErrServerClosed
+	default:
+	}
+	if int64(len(h.subscribers)) >= h.maxSubscribers.Load() {
+		return nil, errStreamFull
+	}
+	subscriber := &streamSubscriber{interval: interval, events: make(chan streamSample, 1)}
+	h.subscribers[subscriber] = struct{}{}
+	h.subscribersGauge.Set(float64(len(h.subscribers)))
+	if h.stopReader == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.stopReader = cancel
+		go h.run(ctx)
+	}
+
+	return subscriber, nil
+}
+
+func (h *streamHub) unsubscribe(subscriber *streamSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, subscriber)
+	h.subscribersGauge.Set(float64(len(h.subscribers)))
+	if len(h.subscribers) == 0 && h.stopReader != nil {
+		h.stopReader()
+		h.stopReader = nil
+	}
+}
+
+// close ends all streams, so that server shutdown does not wait for them.
+func (h *streamHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	select {
+	case <-h.closed:
+	default:
+		close(h.closed)
+	}
+}
+
+func (h *streamHub) run(ctx context.Context) {
+	ticker := time.NewTicker(streamReadInterval)
+	defer ticker.Stop()
+	for {
+		stat, err := h.read(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		h.publish(streamSample{time: time.Now(), stat: stat, err: err})
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish sends the sample to subscribers whose interval has passed,
+// replacing a sample the subscriber has not taken yet.
+func (h *streamHub) publish(sample streamSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for subscriber := range h.subscribers {
+		if sample.time.Before(subscriber.next) {
+			continue
+		}
+		subscriber.next = sample.time.Add(subscriber.interval - streamReadInterval/2)
+		select {
+		case subscriber.events <- sample:
+			continue
+		default:
+		}
+		select {
+		case <-subscriber.events:
+			h.dropped.Inc()
+		default:
+		}
+		subscriber.events <- sample
+	}
+}
+
+// streamHandler sends statistics as Server-Sent Events every ?interval
+// (default 5s), a Go duration or seconds. Events are "stats" with
+// streamEvent data and "read_error" when the stat file cannot be read.
+func (s *APIServer) streamHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+	interval, err := parseStreamInterval(req.URL.Query().Get("interval"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+	subscriber, err := s.stream.subscribe(interval)
+	if err != nil {
+		http.Error(w, err.Error()+", try again later.", http.StatusServiceUnavailable)
+
+		return
+	}
+	defer s.stream.unsubscribe(subscriber)
+
+	writeTimeout := time.Duration(s.handlers.Load().config.RequestTimeout) * time.Second
+	controller := http.NewResponseController(w)
+	// the server write timeout would end the stream, every write gets its
+	// own deadline instead so that stuck clients are dropped
+	flush := func() error {
+		if err := controller.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+
+		return controller.Flush()
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := flush(); err != nil {
+		s.log.ErrorErr("Error start stream", err)
+
+		return
+	}
+	var prev *streamSample
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-s.stream.closed:
+			return
+		case sample := <-subscriber.events:
+			if err := writeStreamEvent(w, prev, sample); err != nil {
+				return
+			}
+			if err := flush(); err != nil {
+				return
+			}
+			if sample.err == nil {
+				prev = &sample
+			}
+		}
+	}
+}
+
+func parseStreamInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultStreamInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil {
+			return 0, fmt.Errorf("error incorrect interval %s: %w", value, err)
+		}
+		interval = time.Duration(seconds * float64(time.Second))
+	}
+	if interval < streamReadInterval || interval > maxStreamInterval {
+		return 0, fmt.Errorf("error incorrect interval %s: must be from %s to %s", value, streamReadInterval, maxStreamInterval)
+	}
+
+	return interval, nil
+}
+
+func writeStreamEvent(w http.ResponseWriter, prev *streamSample, sample streamSample) error {
+	name := "stats"
+	var data any
+	if sample.err != nil {
+		name = "read_error"
+		data = streamError{Time: sample.time, Error: sample.err.Error()}
+	} else {
+		data = newStreamEvent(prev, sample)
+	}
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, content)
+
+	return err
+}
+
+func newStreamEvent(prev *streamSample, sample streamSample) streamEvent {
+	event := streamEvent{Time: sample.time, Stat: sample.stat}
+	if prev == nil {
+		return event
+	}
+	seconds := sample.time.Sub(prev.time).Seconds()
+	event.Interval = seconds
+	event.Rates = fieldRates(reflect.ValueOf(prev.stat), reflect.ValueOf(sample.stat), seconds)
+	event.CPURates = map[string]map[string]float64{}
+	for _, cpu := range sample.stat.CPUStatList {
+		for _, prevCPU := range prev.stat.CPUStatList {
+			if prevCPU.CPU == cpu.CPU {
+				event.CPURates[cpu.CPU] = fieldRates(reflect.ValueOf(prevCPU), reflect.ValueOf(cpu), seconds)
+			}
+		}
+	}
+	event.SocketRates = map[string]map[string]float64{}
+	for _, socket := range sample.stat.SockStatList {
+		for _, prevSocket := range prev.stat.SockStatList {
+			if prevSocket.SockName == socket.SockName && prevSocket.SockDestination == socket.SockDestination {
+				event.SocketRates[socket.SockName] = fieldRates(reflect.ValueOf(prevSocket), reflect.ValueOf(socket), seconds)
+			}
+		}
+	}
+
+	return event
+}
+
+// fieldRates returns per second deltas of counter fields of two structs of
+// the same type, see statparser.IsCounter.
+func fieldRates(prev, current reflect.Value, seconds float64) map[string]float64 {
+	rates := map[string]float64{}
+	if seconds <= 0 {
+		return rates
+	}
+	for index := range current.NumField() {
+		field := current.Field(index)
+		if !statparser.IsCounter(current.Type().Field(index).Name) {
+			continue
+		}
+		prevValue, value := prev.Field(index).Uint(), field.Uint()
+		if value < prevValue {
+			continue
+		}
+		rates[current.Type().Field(index).Name] = float64(value-prevValue) / seconds
+	}
+
+	return rates
+}
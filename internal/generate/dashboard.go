@@ -0,0 +1,214 @@
+package generate
+
+import (
+	"encoding/json"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	dashboardUID           = "ipt-netflow"
+	dashboardSchemaVersion = 39
+	defaultDashboardTitle  = "ipt_NETFLOW"
+	panelWidth             = 8
+	panelHeight            = 8
+	rowWidth               = 24
+	datasourceVariable     = "${datasource}"
+)
+
+var groupTitles = []struct {
+	group string
+	title string
+}{
+	{GroupCommon, "Common"},
+	{GroupCPU, "CPU"},
+	{GroupSocket, "Sockets"},
+}
+
+type dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Panels        []panel    `json:"panels"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      string      `json:"query"`
+	Datasource *datasource `json:"datasource,omitempty"`
+	Multi      bool        `json:"multi,omitempty"`
+	IncludeAll bool        `json:"includeAll,omitempty"`
+	Refresh    int         `json:"refresh,omitempty"`
+}
+
+type datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type panel struct {
+	ID          int          `json:"id"`
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	GridPos     gridPos      `json:"gridPos"`
+	Datasource  *datasource  `json:"datasource,omitempty"`
+	Targets     []target     `json:"targets,omitempty"`
+	FieldConfig *fieldConfig `json:"fieldConfig,omitempty"`
+}
+
+type gridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+type fieldConfig struct {
+	Defaults fieldDefaults `json:"defaults"`
+}
+
+type fieldDefaults struct {
+	Unit string `json:"unit"`
+}
+
+// Dashboard returns a Grafana dashboard with a row of time series panels for
+// common, CPU and socket metrics. Counters are shown as rates.
+func Dashboard(families []*dto.MetricFamily, opts Options) ([]byte, error) {
+	g := newGenerator(families, opts)
+	title := opts.Title
+	if title == "" {
+		title = defaultDashboardTitle
+	}
+	promDatasource := &datasource{Type: "prometheus", UID: datasourceVariable}
+	board := dashboard{
+		UID:           dashboardUID,
+		Title:         title,
+		Tags:          []string{"ipt-netflow"},
+		Timezone:      "browser",
+		SchemaVersion: dashboardSchemaVersion,
+		Refresh:       "30s",
+		Time:          timeRange{From: "now-6h", To: "now"},
+		Templating: templating{List: []variable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			{
+				Name:       "instance",
+				Label:      "Instance",
+				Type:       "query",
+				Query:      "label_values(" + g.series(g.metric("in_flows")) + ", instance)",
+				Datasource: promDatasource,
+				Multi:      true,
+				IncludeAll: true,
+				Refresh:    2,
+			},
+		}},
+	}
+	if err := g.err(); err != nil {
+		return nil, err
+	}
+
+	id, y := 1, 0
+	for _, group := range groupTitles {
+		metrics := []Metric{}
+		for _, metric := range g.metrics {
+			if g.group(metric) == group.group {
+				metrics = append(metrics, metric)
+			}
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+		board.Panels = append(board.Panels, panel{
+			ID:      id,
+			Type:    "row",
+			Title:   group.title,
+			GridPos: gridPos{X: 0, Y: y, W: rowWidth, H: 1},
+		})
+		id++
+		y++
+		for index, metric := range metrics {
+			board.Panels = append(board.Panels, g.panel(id, metric, promDatasource,
+				gridPos{X: index % (rowWidth / panelWidth) * panelWidth, Y: y + index/(rowWidth/panelWidth)*panelHeight, W: panelWidth, H: panelHeight}))
+			id++
+		}
+		y += (len(metrics) + rowWidth/panelWidth - 1) / (rowWidth / panelWidth) * panelHeight
+	}
+
+	data, err := json.MarshalIndent(board, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+func (g *generator) panel(id int, metric Metric, promDatasource *datasource, position gridPos) panel {
+	name := g.baseName(metric)
+	title := strings.ReplaceAll(name, "_", " ")
+	expr := g.series(metric, `instance=~"$instance"`)
+	if metric.Type == TypeCounter {
+		title += " per second"
+		expr = g.perSecond(metric, "$__rate_interval", `instance=~"$instance"`)
+	}
+	legend := "{{instance}}"
+	for _, label := range metric.Labels {
+		legend += " {{" + label + "}}"
+	}
+
+	return panel{
+		ID:          id,
+		Type:        "timeseries",
+		Title:       title,
+		Description: metric.Help,
+		GridPos:     position,
+		Datasource:  promDatasource,
+		Targets:     []target{{RefID: "A", Expr: expr, LegendFormat: legend}},
+		FieldConfig: &fieldConfig{Defaults: fieldDefaults{Unit: unit(name, metric.Type)}},
+	}
+}
+
+// unit returns the Grafana unit of a metric by its name.
+func unit(name, metricType string) string {
+	counter := metricType == TypeCounter
+	switch {
+	case strings.HasSuffix(name, "bit_rate"):
+		return "bps"
+	case strings.HasSuffix(name, "byte_rate"):
+		return "Bps"
+	case strings.HasSuffix(name, "packet_rate"):
+		return "pps"
+	case strings.HasSuffix(name, "bytes") && counter:
+		return "Bps"
+	case strings.HasSuffix(name, "bytes"), !counter && (strings.Contains(name, "snd_buf") || strings.Contains(name, "sndbuf")):
+		return "bytes"
+	case strings.HasSuffix(name, "packets") && counter:
+		return "pps"
+	case counter:
+		return "ops"
+	default:
+		return "short"
+	}
+}
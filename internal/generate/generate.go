@@ -0,0 +1,137 @@
+// Package generate builds Prometheus alerting rules and a Grafana dashboard
+// from metric families of the exporter, so that names, types and labels
+// always match what the exporter exposes.
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Metric types of generated queries, counters are queried as rates.
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+)
+
+// Metric groups of dashboard rows.
+const (
+	GroupCommon = "common"
+	GroupCPU    = "cpu"
+	GroupSocket = "socket"
+)
+
+// gaugeMetrics are exposed as counters by the exporter but go up and down, so
+// they are queried as they are instead of as rates.
+var gaugeMetrics = []string{"socket_active", "sndbuf_peak"}
+
+// Metric is an exporter metric rules and panels are generated for.
+type Metric struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels []string
+}
+
+// Options change generated queries and alerts.
+type Options struct {
+	// Namespace prefixes metric names, metrics are looked up without it.
+	Namespace string
+	// Selector holds label matchers added to every query, e.g. job="netflow".
+	Selector string
+	// Labels are added to every alert, severity is set by the alert.
+	Labels map[string]string
+	// Title of the dashboard.
+	Title string
+}
+
+type generator struct {
+	metrics []Metric
+	opts    Options
+	errs    []error
+}
+
+func newGenerator(families []*dto.MetricFamily, opts Options) *generator {
+	metrics := make([]Metric, 0, len(families))
+	for _, family := range families {
+		metric := Metric{Name: family.GetName(), Help: family.GetHelp(), Type: TypeGauge}
+		if family.GetType() == dto.MetricType_COUNTER && !slices.Contains(gaugeMetrics, strings.TrimPrefix(metric.Name, opts.Namespace+"_")) {
+			metric.Type = TypeCounter
+		}
+		for _, sample := range family.GetMetric() {
+			for _, label := range sample.GetLabel() {
+				if !slices.Contains(metric.Labels, label.GetName()) {
+					metric.Labels = append(metric.Labels, label.GetName())
+				}
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return &generator{metrics: metrics, opts: opts}
+}
+
+// metric returns a metric by name without namespace. A missing metric is
+// recorded as an error, so that generators fail instead of emitting queries
+// for metrics which do not exist.
+func (g *generator) metric(name string) Metric {
+	fullName := g.opts.Namespace + "_" + name
+	for _, metric := range g.metrics {
+		if metric.Name == fullName {
+			return metric
+		}
+	}
+	g.errs = append(g.errs, fmt.Errorf("error unknown metric %s", fullName))
+
+	return Metric{Name: fullName, Type: TypeGauge}
+}
+
+func (g *generator) err() error {
+	return errors.Join(g.errs...)
+}
+
+// group returns the dashboard group of a metric, exporter metrics have none.
+func (g *generator) group(metric Metric) string {
+	switch {
+	case strings.HasPrefix(metric.Name, g.opts.Namespace+"_exporter_"):
+		return ""
+	case slices.Contains(metric.Labels, "cpu"):
+		return GroupCPU
+	case slices.Contains(metric.Labels, "socket"):
+		return GroupSocket
+	default:
+		return GroupCommon
+	}
+}
+
+// series returns a metric selector with matchers and the configured selector.
+func (g *generator) series(metric Metric, matchers ...string) string {
+	if g.opts.Selector != "" {
+		matchers = append(matchers, g.opts.Selector)
+	}
+	if len(matchers) == 0 {
+		return metric.Name
+	}
+
+	return metric.Name + "{" + strings.Join(matchers, ",") + "}"
+}
+
+// perSecond returns the per second change of a metric over window, rate for
+// counters and deriv for gauges.
+func (g *generator) perSecond(metric Metric, window string, matchers ...string) string {
+	function := "deriv"
+	if metric.Type == TypeCounter {
+		function = "rate"
+	}
+
+	return fmt.Sprintf("%s(%s[%s])", function, g.series(metric, matchers...), window)
+}
+
+// baseName returns the metric name without namespace.
+func (g *generator) baseName(metric Metric) string {
+	return strings.TrimPrefix(metric.Name, g.opts.Namespace+"_")
+}
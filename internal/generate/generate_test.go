@@ -0,0 +1,98 @@
+package generate
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/exporter"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func init() {
+	logger.SetDefaultDiscardLogger()
+}
+
+func testOptions() Options {
+	return Options{
+		Namespace: exporter.MetricsNamespace,
+		Selector:  `job="ipt-netflow"`,
+		Labels:    map[string]string{"team": "network"},
+	}
+}
+
+func requireGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, actual, 0o600))
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+}
+
+func TestRules(t *testing.T) {
+	families, err := exporter.MetricFamilies()
+	require.NoError(t, err)
+	rules, err := Rules(families, testOptions())
+	require.NoError(t, err)
+	requireGolden(t, "rules.yml", rules)
+}
+
+func TestDashboard(t *testing.T) {
+	families, err := exporter.MetricFamilies()
+	require.NoError(t, err)
+	dashboard, err := Dashboard(families, testOptions())
+	require.NoError(t, err)
+	requireGolden(t, "dashboard.json", dashboard)
+}
+
+func TestGaugeMetrics(t *testing.T) {
+	families, err := exporter.MetricFamilies()
+	require.NoError(t, err)
+	opts := testOptions()
+	opts.Labels = map[string]string{"severity": "info"}
+	rules, err := Rules(families, opts)
+	require.NoError(t, err)
+	require.Contains(t, string(rules), "expr: ipt_netflow_socket_active{job=\"ipt-netflow\"} == 0")
+	require.NotContains(t, string(rules), "severity: info")
+	dashboard, err := Dashboard(families, opts)
+	require.NoError(t, err)
+	for _, name := range gaugeMetrics {
+		require.NotContains(t, string(dashboard), "rate(ipt_netflow_"+name)
+		require.NotContains(t, string(dashboard), strings.ReplaceAll(name, "_", " ")+" per second")
+	}
+}
+
+func TestUnknownMetric(t *testing.T) {
+	families, err := exporter.MetricFamilies()
+	require.NoError(t, err)
+	opts := testOptions()
+	opts.Namespace = "other"
+	_, err = Rules(families, opts)
+	require.ErrorContains(t, err, "error unknown metric other_lost_flows")
+	_, err = Dashboard(families, opts)
+	require.ErrorContains(t, err, "error unknown metric other_in_flows")
+}
+
+func TestUnit(t *testing.T) {
+	for _, test := range []struct {
+		name, metricType, unit string
+	}{
+		{"in_bit_rate", TypeGauge, "bps"},
+		{"in_bytes", TypeCounter, "Bps"},
+		{"hash_bytes", TypeGauge, "bytes"},
+		{"socket_snd_buf_fill", TypeGauge, "bytes"},
+		{"cpu_drop_packets", TypeCounter, "pps"},
+		{"socket_error_full", TypeCounter, "ops"},
+		{"hash_metrics", TypeGauge, "short"},
+	} {
+		require.Equal(t, test.unit, unit(test.name, test.metricType), test.name)
+	}
+}
@@ -0,0 +1,90 @@
+package generate
+
+import (
+	"bytes"
+	"maps"
+
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	rulesGroupName = "ipt-netflow"
+	rulesWindow    = "5m"
+)
+
+type rulesFile struct {
+	Groups []rulesGroup `yaml:"groups"`
+}
+
+type rulesGroup struct {
+	Name  string `yaml:"name"`
+	Rules []rule `yaml:"rules"`
+}
+
+type rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// Rules returns a Prometheus rules file with alerts on flow loss, sockets
+// down, send buffer saturation, drops and a missing ipt_NETFLOW module.
+func Rules(families []*dto.MetricFamily, opts Options) ([]byte, error) {
+	g := newGenerator(families, opts)
+	lostFlows := g.metric("lost_flows")
+	socketActive := g.metric("socket_active")
+	sndbufFill := g.metric("socket_snd_buf_fill")
+	sndbuf := g.metric("socket_snd_buf")
+	dropPackets := g.metric("drop_packets")
+	scrapeErrors := g.metric("exporter_scrape_errors_total")
+	if err := g.err(); err != nil {
+		return nil, err
+	}
+
+	rules := []rule{
+		g.rule("IPTNetflowFlowsLost", g.perSecond(lostFlows, rulesWindow)+" > 0", "5m", "critical",
+			"ipt_NETFLOW loses flows",
+			"{{ $labels.instance }} loses {{ $value | humanize }} flows per second on export because of socket errors."),
+		g.rule("IPTNetflowSocketDown", g.series(socketActive)+" == 0", "5m", "critical",
+			"ipt_NETFLOW export socket is down",
+			"Socket {{ $labels.socket }} to {{ $labels.destination }} on {{ $labels.instance }} is not active."),
+		g.rule("IPTNetflowSndbufSaturated", g.series(sndbufFill)+" / "+g.series(sndbuf)+" > 0.9", "5m", "warning",
+			"ipt_NETFLOW socket send buffer is saturated",
+			"Send buffer of socket {{ $labels.socket }} to {{ $labels.destination }} on {{ $labels.instance }} is {{ $value | humanizePercentage }} full, flows are lost when it is full."),
+		g.rule("IPTNetflowPacketsDropped", g.perSecond(dropPackets, rulesWindow)+" > 0", "10m", "warning",
+			"ipt_NETFLOW drops packets",
+			"{{ $labels.instance }} drops {{ $value | humanize }} packets per second in the metering process."),
+		g.rule("IPTNetflowModuleMissing", g.perSecond(scrapeErrors, rulesWindow, `reason="missing"`)+" > 0", "5m", "critical",
+			"ipt_NETFLOW module is not loaded",
+			"The ipt_NETFLOW stat file does not exist on {{ $labels.instance }}, the module is not loaded."),
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(rulesFile{Groups: []rulesGroup{{Name: rulesGroupName, Rules: rules}}}); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (g *generator) rule(name, expr, duration, severity, summary, description string) rule {
+	labels := map[string]string{}
+	maps.Copy(labels, g.opts.Labels)
+	labels["severity"] = severity
+
+	return rule{
+		Alert:       name,
+		Expr:        expr,
+		For:         duration,
+		Labels:      labels,
+		Annotations: map[string]string{"summary": summary, "description": description},
+	}
+}
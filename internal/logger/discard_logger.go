@@ -25,6 +25,7 @@ func (h discardHandler) WithGroup(string) slog.Handler {
 	return h
 }
 
+// SetDefaultDiscardLogger drops all logs and closes opened sinks.
 func SetDefaultDiscardLogger() {
-	slog.SetDefault(slog.New(&discardHandler{}))
+	swapHandler(&discardHandler{}, nil)
 }
@@ -0,0 +1,310 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+	megabyte         = 1024 * 1024
+)
+
+// Rotation configures built-in log file rotation. Zero values disable the
+// corresponding limit.
+type Rotation struct {
+	MaxSize      int64
+	MaxFileAge   time.Duration
+	MaxBackups   int
+	MaxBackupAge time.Duration
+	Compress     bool
+}
+
+func (r Rotation) enabled() bool {
+	return r.MaxSize > 0 || r.MaxFileAge > 0
+}
+
+// fileWriter appends logs to a file. The file can be reopened by path (for
+// external logrotate) and rotated when it exceeds the size or age limit.
+// Rotated backups are named <name>-<timestamp>[-<n>]<ext>[.gz], the counter
+// is added when a backup of the same millisecond exists.
+type fileWriter struct {
+	mu       sync.Mutex
+	path     string
+	rotation Rotation
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	// cleanupErr holds compression failures of the background cleanup,
+	// they are returned by the next write like rotation errors.
+	cleanupErr error
+	// cleanupMu serializes compression and removal of old backups, which
+	// run in background so they do not block logging.
+	cleanupMu sync.Mutex
+}
+
+func newFileWriter(path string, rotation Rotation) (*fileWriter, error) {
+	writer := &fileWriter{path: filepath.Clean(path), rotation: rotation}
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// open opens the file by path and replaces the current one. On error the
+// current file is kept.
+func (w *fileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = time.Now()
+
+	return nil
+}
+
+func (w *fileWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if w.rotationDue(int64(len(data))) {
+		// a failed rotation is retried on the next write, logs still go
+		// to the current file meanwhile
+		rotateErr = w.rotate()
+	}
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	cleanupErr := w.cleanupErr
+	w.cleanupErr = nil
+
+	return n, errors.Join(rotateErr, cleanupErr, err)
+}
+
+func (w *fileWriter) rotationDue(writeSize int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.rotation.MaxSize > 0 && w.size+writeSize > w.rotation.MaxSize {
+		return true
+	}
+
+	return w.rotation.MaxFileAge > 0 && time.Since(w.openedAt) > w.rotation.MaxFileAge
+}
+
+// rotate renames the current file to a backup and opens a new one. When
+// the rename fails the file is reopened by path, so logging goes on in case
+// the file was removed.
+func (w *fileWriter) rotate() error {
+	if err := os.Rename(w.path, w.backupName(time.Now())); err != nil {
+		return errors.Join(fmt.Errorf("error rotate log file %s: %w", w.path, err), w.open())
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	go w.cleanup()
+
+	return nil
+}
+
+// Reopen opens the file again by path, e.g. after logrotate renamed it.
+func (w *fileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.open()
+}
+
+func (w *fileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+func (w *fileWriter) backupName(now time.Time) string {
+	ext := filepath.Ext(w.path)
+	name := strings.TrimSuffix(w.path, ext) + "-" + now.Format(backupTimeFormat)
+	backup := name + ext
+	for counter := 1; backupExists(backup); counter++ {
+		backup = name + "-" + strconv.Itoa(counter) + ext
+	}
+
+	return backup
+}
+
+func backupExists(backup string) bool {
+	for _, path := range []string{backup, backup + compressSuffix} {
+		if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseBackupSuffix parses <timestamp>[-<n>] of a backup name.
+func parseBackupSuffix(suffix string) (time.Time, int, bool) {
+	if timestamp, err := time.Parse(backupTimeFormat, suffix); err == nil {
+		return timestamp, 0, true
+	}
+	index := strings.LastIndex(suffix, "-")
+	if index < 0 {
+		return time.Time{}, 0, false
+	}
+	counter, err := strconv.Atoi(suffix[index+1:])
+	if err != nil || counter < 1 {
+		return time.Time{}, 0, false
+	}
+	timestamp, err := time.Parse(backupTimeFormat, suffix[:index])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	return timestamp, counter, true
+}
+
+// backups returns backup files sorted from newest to oldest.
+func (w *fileWriter) backups() ([]string, error) {
+	ext := filepath.Ext(w.path)
+	prefix := filepath.Base(strings.TrimSuffix(w.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		path      string
+		timestamp time.Time
+		counter   int
+	}
+	found := make([]backup, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), compressSuffix)
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		timestamp, counter, ok := parseBackupSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		if !ok {
+			continue
+		}
+		found = append(found, backup{filepath.Join(filepath.Dir(w.path), entry.Name()), timestamp, counter})
+	}
+	slices.SortFunc(found, func(a, b backup) int {
+		if cmp := b.timestamp.Compare(a.timestamp); cmp != 0 {
+			return cmp
+		}
+
+		return b.counter - a.counter
+	})
+	backups := make([]string, 0, len(found))
+	for _, backup := range found {
+		backups = append(backups, backup.path)
+	}
+
+	return backups, nil
+}
+
+// cleanup removes backups exceeding retention limits and compresses the rest.
+func (w *fileWriter) cleanup() {
+	w.cleanupMu.Lock()
+	defer w.cleanupMu.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		return
+	}
+	var compressErr error
+	for index, backup := range backups {
+		if w.expired(index, backup) {
+			os.Remove(backup)
+
+			continue
+		}
+		if w.rotation.Compress && !strings.HasSuffix(backup, compressSuffix) {
+			if err := compressFile(backup); err != nil {
+				compressErr = errors.Join(compressErr, fmt.Errorf("error compress log file %s: %w", backup, err))
+			}
+		}
+	}
+	if compressErr != nil {
+		w.mu.Lock()
+		w.cleanupErr = errors.Join(w.cleanupErr, compressErr)
+		w.mu.Unlock()
+	}
+}
+
+func (w *fileWriter) expired(index int, backup string) bool {
+	if w.rotation.MaxBackups > 0 && index >= w.rotation.MaxBackups {
+		return true
+	}
+	if w.rotation.MaxBackupAge <= 0 {
+		return false
+	}
+	info, err := os.Stat(backup)
+
+	return err == nil && time.Since(info.ModTime()) > w.rotation.MaxBackupAge
+}
+
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+
+		return err
+	}
+	if err = errors.Join(gz.Close(), dst.Close()); err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
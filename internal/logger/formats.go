@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	consoleTimeFormat = "15:04:05.000"
+	colorReset        = "\033[0m"
+	colorDim          = "\033[2m"
+	colorRed          = "\033[31m"
+	colorGreen        = "\033[32m"
+	colorYellow       = "\033[33m"
+	colorMagenta      = "\033[35m"
+)
+
+// logfmtOptions makes the text handler output logfmt as used across the
+// Prometheus ecosystem: ts in UTC with milliseconds and lower case level.
+func logfmtOptions(opts *slog.HandlerOptions) *slog.HandlerOptions {
+	replace := opts.ReplaceAttr
+	logfmt := *opts
+	logfmt.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+		if replace != nil {
+			if attr = replace(groups, attr); attr.Key == "" {
+				return attr
+			}
+		}
+		if len(groups) > 0 {
+			return attr
+		}
+		switch attr.Key {
+		case slog.TimeKey:
+			return slog.String("ts", attr.Value.Time().UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+		case slog.LevelKey:
+			if level, ok := attr.Value.Any().(slog.Level); ok {
+				return slog.String(slog.LevelKey, levelName(level))
+			}
+		}
+
+		return attr
+	}
+
+	return &logfmt
+}
+
+// consoleHandler writes human readable colored lines for terminals:
+//
+//	15:04:05.000 INF [StatCollector] message key=value
+//
+// Colors are disabled by the NO_COLOR environment variable.
+type consoleHandler struct {
+	mu        *sync.Mutex
+	writer    io.Writer
+	opts      slog.HandlerOptions
+	color     bool
+	component string
+	// attrs are attributes added with WithAttrs, already formatted.
+	attrs  string
+	prefix string
+}
+
+func newConsoleHandler(writer io.Writer, opts *slog.HandlerOptions) *consoleHandler {
+	_, noColor := os.LookupEnv("NO_COLOR")
+
+	return &consoleHandler{mu: &sync.Mutex{}, writer: writer, opts: *opts, color: !noColor}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	line := &bytes.Buffer{}
+	if timeAttr := h.replace(nil, slog.Time(slog.TimeKey, record.Time)); !record.Time.IsZero() && timeAttr.Key != "" {
+		h.paint(line, colorDim, record.Time.Format(consoleTimeFormat))
+		line.WriteByte(' ')
+	}
+	color, name := consoleLevel(record.Level)
+	h.paint(line, color, name)
+	if h.component != "" {
+		line.WriteString(" [" + h.component + "]")
+	}
+	line.WriteString(" " + record.Message)
+	line.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		h.appendAttr(line, h.prefix, attr)
+
+		return true
+	})
+	line.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.writer.Write(line.Bytes())
+
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	buf := bytes.NewBufferString(h.attrs)
+	for _, attr := range attrs {
+		if h.prefix == "" && attr.Key == Component {
+			handler.component = attr.Value.String()
+
+			continue
+		}
+		h.appendAttr(buf, h.prefix, attr)
+	}
+	handler.attrs = buf.String()
+
+	return &handler
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.prefix = h.prefix + name + "."
+
+	return &handler
+}
+
+func (h *consoleHandler) replace(groups []string, attr slog.Attr) slog.Attr {
+	if h.opts.ReplaceAttr == nil {
+		return attr
+	}
+
+	return h.opts.ReplaceAttr(groups, attr)
+}
+
+func (h *consoleHandler) appendAttr(buf *bytes.Buffer, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, groupAttr := range value.Group() {
+			h.appendAttr(buf, prefix, groupAttr)
+		}
+
+		return
+	}
+	if attr.Key == "" {
+		return
+	}
+	buf.WriteByte(' ')
+	h.paint(buf, colorDim, prefix+attr.Key+"=")
+	text := value.String()
+	if value.Kind() == slog.KindTime {
+		text = value.Time().Format(time.RFC3339Nano)
+	}
+	if text == "" || strings.ContainsAny(text, " \"=\n\t") {
+		text = strconv.Quote(text)
+	}
+	if attr.Key == ErrorKey {
+		h.paint(buf, colorRed, text)
+
+		return
+	}
+	buf.WriteString(text)
+}
+
+func (h *consoleHandler) paint(buf *bytes.Buffer, color, text string) {
+	if !h.color {
+		buf.WriteString(text)
+
+		return
+	}
+	fmt.Fprintf(buf, "%s%s%s", color, text, colorReset)
+}
+
+func consoleLevel(level slog.Level) (string, string) {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed, "ERR"
+	case level >= slog.LevelWarn:
+		return colorYellow, "WRN"
+	case level >= slog.LevelInfo:
+		return colorGreen, "INF"
+	default:
+		return colorMagenta, "DBG"
+	}
+}
@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journaldWriter sends entries to journald over its native protocol.
+type journaldWriter struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func newJournaldWriter(path string) (*journaldWriter, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return nil, fmt.Errorf("%s is not a socket", path)
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &journaldWriter{conn: conn, addr: &net.UnixAddr{Name: path, Net: "unixgram"}}, nil
+}
+
+// Send sends an entry. Entries exceeding the datagram size limit are passed
+// in a temporary file descriptor as journald expects.
+func (w *journaldWriter) Send(entry []byte) error {
+	_, _, err := w.conn.WriteMsgUnix(entry, nil, w.addr)
+	if err == nil || !(errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)) {
+		return err
+	}
+
+	file, err := os.CreateTemp("/dev/shm", "journal.*")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+	if _, err := file.Write(entry); err != nil {
+		return err
+	}
+	_, _, err = w.conn.WriteMsgUnix([]byte{}, syscall.UnixRights(int(file.Fd())), w.addr)
+
+	return err
+}
+
+func (w *journaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// journaldHandler writes records as journal entries: the message goes to
+// MESSAGE and attributes to separate fields named in upper case with group
+// prefixes, e.g. component becomes COMPONENT.
+type journaldHandler struct {
+	writer     *journaldWriter
+	level      slog.Leveler
+	identifier string
+	// fields are entry fields added with WithAttrs.
+	fields []byte
+	prefix string
+}
+
+func newJournaldHandler(writer *journaldWriter, level slog.Leveler, identifier string) *journaldHandler {
+	return &journaldHandler{writer: writer, level: level, identifier: identifier}
+}
+
+func (h *journaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *journaldHandler) Handle(_ context.Context, record slog.Record) error {
+	entry := &bytes.Buffer{}
+	appendJournalField(entry, "MESSAGE", record.Message)
+	appendJournalField(entry, "PRIORITY", strconv.Itoa(journalPriority(record.Level)))
+	appendJournalField(entry, "SYSLOG_IDENTIFIER", h.identifier)
+	entry.Write(h.fields)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendJournalAttr(entry, h.prefix, attr)
+
+		return true
+	})
+
+	return h.writer.Send(entry.Bytes())
+}
+
+func (h *journaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := bytes.NewBuffer(bytes.Clone(h.fields))
+	for _, attr := range attrs {
+		appendJournalAttr(fields, h.prefix, attr)
+	}
+	handler := *h
+	handler.fields = fields.Bytes()
+
+	return &handler
+}
+
+func (h *journaldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.prefix = h.prefix + name + "_"
+
+	return &handler
+}
+
+func journalPriority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
+
+func appendJournalAttr(entry *bytes.Buffer, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "_"
+		}
+		for _, groupAttr := range value.Group() {
+			appendJournalAttr(entry, prefix, groupAttr)
+		}
+
+		return
+	}
+	if attr.Key == "" {
+		return
+	}
+	appendJournalField(entry, journalFieldName(prefix+attr.Key), value.String())
+}
+
+// journalFieldName converts a key to a valid journal field name: upper case
+// letters, digits and underscores, not starting with an underscore or digit,
+// which are reserved for trusted fields.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	}
+
+	return name
+}
+
+// appendJournalField appends NAME=value. Values with new lines are written as
+// NAME, new line, 64-bit little endian length and the value.
+func appendJournalField(entry *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(entry, "%s=%s\n", name, value)
+
+		return
+	}
+	entry.WriteString(name)
+	entry.WriteByte('\n')
+	binary.Write(entry, binary.LittleEndian, uint64(len(value))) //nolint:errcheck
+	entry.WriteString(value)
+	entry.WriteByte('\n')
+}
@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+)
+
+// Levels is the runtime log level state: the default level and overrides
+// keyed by the Component attribute of loggers.
+type Levels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+var (
+	levelsMu sync.Mutex
+	// defaultLevel applies to loggers of components without an override.
+	defaultLevel slog.LevelVar
+	// minLevel is the lowest of all levels. Sinks without their own level
+	// filter by it, and levelHandler decides by component.
+	minLevel        slog.LevelVar
+	componentLevels atomic.Pointer[map[string]slog.Level]
+)
+
+// GetLevels returns the current log levels.
+func GetLevels() Levels {
+	levels := Levels{Level: levelName(defaultLevel.Level()), Components: map[string]string{}}
+	if components := componentLevels.Load(); components != nil {
+		for component, level := range *components {
+			levels.Components[component] = levelName(level)
+		}
+	}
+
+	return levels
+}
+
+// SetLevels changes log levels at runtime. Empty Level keeps the default
+// level, a component with empty level is removed from overrides. Levels are
+// reset to the config on the next Init.
+func SetLevels(update Levels) error {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+
+	level := defaultLevel.Level()
+	if update.Level != "" {
+		var err error
+		if level, err = marshalLevel(update.Level); err != nil {
+			return err
+		}
+	}
+	components := map[string]slog.Level{}
+	if current := componentLevels.Load(); current != nil {
+		components = maps.Clone(*current)
+	}
+	for component, name := range update.Components {
+		if name == "" {
+			delete(components, component)
+
+			continue
+		}
+		componentLevel, err := marshalLevel(name)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component, err)
+		}
+		components[component] = componentLevel
+	}
+	storeLevels(level, components)
+
+	return nil
+}
+
+func resetLevels(level slog.Level, overrides []string) error {
+	parsed, err := config.ParseComponentLevels(overrides)
+	if err != nil {
+		return err
+	}
+	components := make(map[string]slog.Level, len(parsed))
+	for component, name := range parsed {
+		components[component], _ = marshalLevel(name)
+	}
+
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	storeLevels(level, components)
+
+	return nil
+}
+
+func storeLevels(level slog.Level, components map[string]slog.Level) {
+	lowest := level
+	for _, componentLevel := range components {
+		lowest = min(lowest, componentLevel)
+	}
+	// lower minLevel first, so records enabled by the new levels are not
+	// dropped by sinks in between
+	if lowest < minLevel.Level() {
+		minLevel.Set(lowest)
+	}
+	defaultLevel.Set(level)
+	componentLevels.Store(&components)
+	minLevel.Set(lowest)
+}
+
+// levelEnabled reports whether records of level are logged for component.
+func levelEnabled(component string, level slog.Level) bool {
+	if components := componentLevels.Load(); components != nil && component != "" {
+		if componentLevel, ok := (*components)[component]; ok {
+			return level >= componentLevel
+		}
+	}
+
+	return level >= defaultLevel.Level()
+}
+
+func levelName(level slog.Level) string {
+	switch level {
+	case slog.LevelDebug:
+		return "debug"
+	case slog.LevelInfo:
+		return "info"
+	case slog.LevelWarn:
+		return "warning"
+	case slog.LevelError:
+		return "error"
+	default:
+		return strings.ToLower(level.String())
+	}
+}
+
+// levelHandler filters records of sinks without their own level by the
+// runtime levels. It remembers the Component attribute added with WithAttrs
+// to apply per component levels.
+type levelHandler struct {
+	handler   slog.Handler
+	component string
+	grouped   bool
+}
+
+func newLevelHandler(handler slog.Handler) *levelHandler {
+	return &levelHandler{handler: handler}
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return levelEnabled(h.component, level) && h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := &levelHandler{handler: h.handler.WithAttrs(attrs), component: h.component, grouped: h.grouped}
+	if !h.grouped {
+		for _, attr := range attrs {
+			if attr.Key == Component {
+				handler.component = attr.Value.String()
+			}
+		}
+	}
+
+	return handler
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{handler: h.handler.WithGroup(name), component: h.component, grouped: h.grouped || name != ""}
+}
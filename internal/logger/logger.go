@@ -1,16 +1,37 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"path/filepath"
+	"time"
 
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
 	slogmulti "github.com/samber/slog-multi"
 )
 
 const Component = "component"
 
+// Keys of common log attributes, so that log pipelines can index on them.
+const (
+	ErrorKey    = "error"
+	FileKey     = "file"
+	LineKey     = "line"
+	SocketKey   = "socket"
+	CPUKey      = "cpu"
+	DurationKey = "duration"
+)
+
+// Log formats.
+const (
+	FormatJSON    = "json"
+	FormatText    = "text"
+	FormatLogfmt  = "logfmt"
+	FormatConsole = "console"
+)
+
 type Logger struct {
 	logger *slog.Logger
 }
@@ -19,43 +40,80 @@ func marshalLevel(level string) (slog.Level, error) {
 	if level == "" {
 		return slog.LevelDebug, nil
 	}
+	if level == "warning" {
+		level = "warn"
+	}
 	var l slog.Level
 	err := l.UnmarshalText([]byte(level))
 
 	return l, err
 }
 
-func getHandler(format string, logFile *os.File, level slog.Level) slog.Handler {
-	if format == "text" {
-		return slog.NewTextHandler(logFile, &slog.HandlerOptions{Level: level})
+func getHandler(format string, logFile io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	switch format {
+	case FormatText:
+		return slog.NewTextHandler(logFile, opts)
+	case FormatLogfmt:
+		return slog.NewTextHandler(logFile, logfmtOptions(opts))
+	case FormatConsole:
+		return newConsoleHandler(logFile, opts)
+	default:
+		return slog.NewJSONHandler(logFile, opts)
 	}
+}
 
-	return slog.NewJSONHandler(logFile, &slog.HandlerOptions{Level: level})
+func rotationSettings(cfg config.Logger) Rotation {
+	return Rotation{
+		MaxSize:      int64(cfg.MaxSize) * megabyte,
+		MaxFileAge:   time.Duration(cfg.MaxFileAge) * time.Hour,
+		MaxBackups:   cfg.MaxBackups,
+		MaxBackupAge: time.Duration(cfg.MaxBackupAge) * 24 * time.Hour,
+		Compress:     cfg.Compress,
+	}
 }
 
-func Init(logFile string, level string, format string) error {
-	parsedLevel, err := marshalLevel(level)
+// Init builds handlers from the logger settings and swaps them into the
+// default logger. It may be called again on config reload: loggers created
+// before the call switch to the new handlers, and the previous sinks are
+// closed. Log levels changed at runtime are reset to the config. On error the
+// current handlers are kept.
+func Init(cfg config.Logger) error {
+	level, err := marshalLevel(cfg.Level)
 	if err != nil {
 		return err
 	}
+	if _, err := config.ParseComponentLevels(cfg.ComponentLevels); err != nil {
+		return err
+	}
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []config.LogSink{{Type: config.LogSinkStdout}}
+		if cfg.File != "" {
+			sinks = []config.LogSink{{Type: config.LogSinkFile, File: cfg.File}}
+		}
+	}
 
-	slogHandlers := make([]slog.Handler, 0, 2)
-	var slogHandler slog.Handler
-	if logFile != "" {
-		logFile, err := os.OpenFile(filepath.Clean(logFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	slogHandlers := make([]slog.Handler, 0, len(sinks))
+	closers := make([]io.Closer, 0, len(sinks))
+	for _, sink := range sinks {
+		handler, closer, err := newSinkHandler(cfg, sink)
 		if err != nil {
-			return fmt.Errorf("failed to initialize log file %w", err)
+			closeAll(closers)
+
+			return err
+		}
+		slogHandlers = append(slogHandlers, handler)
+		if closer != nil {
+			closers = append(closers, closer)
 		}
-		slogHandler = getHandler(format, logFile, parsedLevel)
-	} else {
-		slogHandler = getHandler(format, os.Stdout, parsedLevel)
 	}
 
-	slogHandlers = append(slogHandlers, slogHandler)
+	if err := resetLevels(level, cfg.ComponentLevels); err != nil {
+		closeAll(closers)
 
-	logger := slog.New(slogmulti.Fanout(slogHandlers...))
-
-	slog.SetDefault(logger)
+		return err
+	}
+	swapHandler(slogmulti.Fanout(slogHandlers...), closers)
 
 	return nil
 }
@@ -71,10 +129,52 @@ func GetLogger() *Logger {
 	return &Logger{slog.Default()}
 }
 
+// Slog returns the underlying slog.Logger for libraries that expect one.
+func (l *Logger) Slog() *slog.Logger {
+	return l.logger
+}
+
 func (l *Logger) With(args ...any) *Logger {
 	return &Logger{l.logger.With(args...)}
 }
 
+// Err returns the error attribute.
+func Err(err error) slog.Attr {
+	return slog.Any(ErrorKey, err)
+}
+
+func (l *Logger) log(level slog.Level, msg string, attrs []slog.Attr) {
+	l.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// Debug logs msg with structured attributes, e.g.
+// log.Debug("stat file parsed", slog.String(logger.FileKey, path)).
+func (l *Logger) Debug(msg string, attrs ...slog.Attr) {
+	l.log(slog.LevelDebug, msg, attrs)
+}
+
+func (l *Logger) Info(msg string, attrs ...slog.Attr) {
+	l.log(slog.LevelInfo, msg, attrs)
+}
+
+func (l *Logger) Warning(msg string, attrs ...slog.Attr) {
+	l.log(slog.LevelWarn, msg, attrs)
+}
+
+func (l *Logger) Error(msg string, attrs ...slog.Attr) {
+	l.log(slog.LevelError, msg, attrs)
+}
+
+// WarningErr logs msg with err in the error attribute.
+func (l *Logger) WarningErr(msg string, err error, attrs ...slog.Attr) {
+	l.log(slog.LevelWarn, msg, append([]slog.Attr{Err(err)}, attrs...))
+}
+
+// ErrorErr logs msg with err in the error attribute.
+func (l *Logger) ErrorErr(msg string, err error, attrs ...slog.Attr) {
+	l.log(slog.LevelError, msg, append([]slog.Attr{Err(err)}, attrs...))
+}
+
 func (l *Logger) Debugf(fsting string, formaters ...any) {
 	l.logger.Debug(fmt.Sprintf(fsting, formaters...))
 }
@@ -0,0 +1,356 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestInitSwapsHandlers(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	firstFile := filepath.Join(t.TempDir(), "first.log")
+	secondFile := filepath.Join(t.TempDir(), "second.log")
+
+	require.NoError(t, Init(config.Logger{File: firstFile, Level: "info", Format: "json"}))
+	log := GetLogger().With(slog.String(Component, "test"))
+	log.Debugf("skipped %d", 1)
+	log.Infof("first %d", 1)
+
+	require.NoError(t, Init(config.Logger{File: secondFile, Level: "debug", Format: "text"}))
+	log.Debugf("second %d", 2)
+
+	require.Error(t, Init(config.Logger{File: filepath.Join(t.TempDir(), "not_exist", "third.log"), Level: "debug", Format: "text"}))
+	log.Debugf("third %d", 3)
+
+	firstLog := readLog(t, firstFile)
+	require.Contains(t, firstLog, `"msg":"first 1","component":"test"`)
+	require.NotContains(t, firstLog, "skipped")
+	require.NotContains(t, firstLog, "second")
+	secondLog := readLog(t, secondFile)
+	require.Contains(t, secondLog, `msg="second 2" component=test`)
+	require.Contains(t, secondLog, `msg="third 3" component=test`)
+}
+
+func TestInitDrainsWriters(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	dir := t.TempDir()
+	require.NoError(t, Init(config.Logger{File: filepath.Join(dir, "0.log"), Level: "info", Format: "text"}))
+
+	const writers, records = 4, 200
+	wg := sync.WaitGroup{}
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range records {
+				GetLogger().Infof("record %d", index)
+			}
+		}()
+	}
+	for index := 1; index <= 10; index++ {
+		require.NoError(t, Init(config.Logger{File: filepath.Join(dir, strconv.Itoa(index)+".log"), Level: "info", Format: "text"}))
+	}
+	wg.Wait()
+
+	written := 0
+	for index := 0; index <= 10; index++ {
+		written += strings.Count(readLog(t, filepath.Join(dir, strconv.Itoa(index)+".log")), "msg=\"record ")
+	}
+	require.Equal(t, writers*records, written)
+}
+
+func TestReopen(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	require.NoError(t, Init(config.Logger{File: logFile, Level: "info", Format: "text"}))
+	GetLogger().Infof("before rotate")
+	// external logrotate renames the file and sends SIGUSR1
+	require.NoError(t, os.Rename(logFile, logFile+".1"))
+	require.NoError(t, Reopen())
+	GetLogger().Infof("after rotate")
+
+	require.Contains(t, readLog(t, logFile+".1"), "before rotate")
+	require.NotContains(t, readLog(t, logFile+".1"), "after rotate")
+	require.Contains(t, readLog(t, logFile), "after rotate")
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := newFileWriter(filepath.Join(dir, "exporter.log"), Rotation{MaxSize: 10, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { writer.Close() })
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		_, err := writer.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.Equal(t, "line-4\n", readLog(t, filepath.Join(dir, "exporter.log")))
+	require.Eventually(t, func() bool {
+		backups, err := writer.backups()
+		if err != nil || len(backups) != 2 {
+			return false
+		}
+		for _, backup := range backups {
+			if filepath.Ext(backup) != compressSuffix {
+				return false
+			}
+		}
+
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRotationBackupCollision(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := newFileWriter(filepath.Join(dir, "exporter.log"), Rotation{MaxSize: 10})
+	require.NoError(t, err)
+	t.Cleanup(func() { writer.Close() })
+
+	now := time.Now()
+	first := writer.backupName(now)
+	require.Equal(t, filepath.Join(dir, "exporter-"+now.Format(backupTimeFormat)+".log"), first)
+	require.NoError(t, os.WriteFile(first, nil, 0o600))
+	second := writer.backupName(now)
+	require.Equal(t, filepath.Join(dir, "exporter-"+now.Format(backupTimeFormat)+"-1.log"), second)
+	require.NoError(t, os.WriteFile(second+compressSuffix, nil, 0o600))
+	third := writer.backupName(now)
+	require.Equal(t, filepath.Join(dir, "exporter-"+now.Format(backupTimeFormat)+"-2.log"), third)
+	require.NoError(t, os.WriteFile(third, nil, 0o600))
+	older := filepath.Join(dir, "exporter-"+now.Add(-time.Second).Format(backupTimeFormat)+"-3.log")
+	require.NoError(t, os.WriteFile(older, nil, 0o600))
+
+	backups, err := writer.backups()
+	require.NoError(t, err)
+	require.Equal(t, []string{third, second + compressSuffix, first, older}, backups)
+}
+
+func TestRotationRenameFails(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "exporter.log")
+	writer, err := newFileWriter(logFile, Rotation{MaxSize: 10})
+	require.NoError(t, err)
+	t.Cleanup(func() { writer.Close() })
+
+	_, err = writer.Write([]byte("line-1\n"))
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(dir, 0o500))
+	t.Cleanup(func() { os.Chmod(dir, 0o700) })
+	_, err = writer.Write([]byte("line-2\n"))
+	require.ErrorContains(t, err, "error rotate log file")
+	require.Equal(t, "line-1\nline-2\n", readLog(t, logFile))
+
+	require.NoError(t, os.Chmod(dir, 0o700))
+	_, err = writer.Write([]byte("line-3\n"))
+	require.NoError(t, err)
+	require.Equal(t, "line-3\n", readLog(t, logFile))
+}
+
+func TestRotationCompressFails(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := newFileWriter(filepath.Join(dir, "exporter.log"), Rotation{MaxSize: 10, Compress: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { writer.Close() })
+
+	backup := writer.backupName(time.Now())
+	require.NoError(t, os.WriteFile(backup, []byte("line-1\n"), 0o600))
+	// a non-empty directory in place of the compressed backup
+	require.NoError(t, os.MkdirAll(filepath.Join(backup+compressSuffix, "dir"), 0o700))
+	writer.cleanup()
+
+	_, err = writer.Write([]byte("line-2\n"))
+	require.ErrorContains(t, err, "error compress log file "+backup)
+	_, err = writer.Write([]byte("line-3\n"))
+	require.NoError(t, err)
+	require.FileExists(t, backup)
+}
+
+func TestRotationRemovedFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	writer, err := newFileWriter(logFile, Rotation{MaxSize: 10})
+	require.NoError(t, err)
+	t.Cleanup(func() { writer.Close() })
+
+	_, err = writer.Write([]byte("line-1\n"))
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(logFile))
+	// rename fails, the file is created again by path
+	_, err = writer.Write([]byte("line-2\n"))
+	require.ErrorContains(t, err, "error rotate log file")
+	require.Equal(t, "line-2\n", readLog(t, logFile))
+	_, err = writer.Write([]byte("line-3\n"))
+	require.NoError(t, err)
+	require.Equal(t, "line-3\n", readLog(t, logFile))
+}
+
+func listenUnixgram(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, path
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestSinks(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	syslogConn, syslogPath := listenUnixgram(t)
+	journalConn, journalPath := listenUnixgram(t)
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+
+	require.NoError(t, Init(config.Logger{Level: "info", Format: "json", Sinks: []config.LogSink{
+		{Type: config.LogSinkFile, File: logFile, Format: "text", Level: "warning"},
+		{Type: config.LogSinkSyslog, Network: "unixgram", Address: syslogPath, Tag: "exporter"},
+		{Type: config.LogSinkJournald, Address: journalPath, Level: "debug"},
+	}}))
+	log := GetLogger().With(slog.String(Component, "test"))
+	log.Debugf("debug %d", 1)
+	log.Warningf("warning %d", 2)
+
+	syslogMsg := readDatagram(t, syslogConn)
+	// <daemon.warning> priority, debug record is filtered by level
+	require.True(t, strings.HasPrefix(syslogMsg, "<28>"), syslogMsg)
+	require.Contains(t, syslogMsg, `exporter[`)
+	require.Contains(t, syslogMsg, `{"level":"WARN","msg":"warning 2","component":"test"}`)
+
+	require.Equal(t, "MESSAGE=debug 1\nPRIORITY=7\nSYSLOG_IDENTIFIER=ipt-netflow-exporter\nCOMPONENT=test\n", readDatagram(t, journalConn))
+	require.Equal(t, "MESSAGE=warning 2\nPRIORITY=4\nSYSLOG_IDENTIFIER=ipt-netflow-exporter\nCOMPONENT=test\n", readDatagram(t, journalConn))
+
+	fileLog := readLog(t, logFile)
+	require.Contains(t, fileLog, `level=WARN msg="warning 2" component=test`)
+	require.NotContains(t, fileLog, "debug 1")
+}
+
+func TestInitSinkError(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	firstFile := filepath.Join(t.TempDir(), "first.log")
+	secondFile := filepath.Join(t.TempDir(), "second.log")
+	require.NoError(t, Init(config.Logger{Level: "info", Format: "text", File: firstFile}))
+
+	err := Init(config.Logger{Level: "info", Format: "text", Sinks: []config.LogSink{
+		{Type: config.LogSinkFile, File: secondFile},
+		{Type: config.LogSinkJournald, Address: filepath.Join(t.TempDir(), "not_exist.sock")},
+	}})
+	require.ErrorContains(t, err, "failed to connect to journald")
+	GetLogger().Infof("after error")
+
+	require.Contains(t, readLog(t, firstFile), "after error")
+	require.Empty(t, readLog(t, secondFile))
+}
+
+func TestJournalField(t *testing.T) {
+	entry := &bytes.Buffer{}
+	appendJournalField(entry, journalFieldName("http.remote-addr"), "1.2.3.4")
+	appendJournalField(entry, journalFieldName("_pid"), "1\n2")
+
+	expected := &bytes.Buffer{}
+	expected.WriteString("HTTP_REMOTE_ADDR=1.2.3.4\nX_PID\n")
+	require.NoError(t, binary.Write(expected, binary.LittleEndian, uint64(3)))
+	expected.WriteString("1\n2\n")
+	require.Equal(t, expected.String(), entry.String())
+}
+
+func TestComponentLevels(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	require.NoError(t, Init(config.Logger{
+		File: logFile, Level: "info", Format: "text", ComponentLevels: []string{"StatCollector=debug"},
+	}))
+	statLog := GetLogger().With(slog.String(Component, "StatCollector"))
+	apiLog := GetLogger().With(slog.String(Component, "exporter-api-server"))
+	statLog.Debugf("stat debug %d", 1)
+	apiLog.Debugf("api debug %d", 1)
+	require.Equal(t, Levels{Level: "info", Components: map[string]string{"StatCollector": "debug"}}, GetLevels())
+
+	require.NoError(t, SetLevels(Levels{Level: "debug", Components: map[string]string{"StatCollector": "", "exporter-api-server": "error"}}))
+	statLog.Debugf("stat debug %d", 2)
+	apiLog.Warningf("api warning %d", 2)
+	require.Error(t, SetLevels(Levels{Components: map[string]string{"StatCollector": "verbose"}}))
+
+	// reload resets runtime changes
+	require.NoError(t, Init(config.Logger{File: logFile, Level: "info", Format: "text"}))
+	statLog.Debugf("stat debug %d", 3)
+
+	log := readLog(t, logFile)
+	require.Contains(t, log, "stat debug 1")
+	require.NotContains(t, log, "api debug 1")
+	require.Contains(t, log, "stat debug 2")
+	require.NotContains(t, log, "api warning 2")
+	require.NotContains(t, log, "stat debug 3")
+}
+
+func TestStructuredLogging(t *testing.T) {
+	t.Cleanup(SetDefaultDiscardLogger)
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	require.NoError(t, Init(config.Logger{File: logFile, Level: "debug", Format: "json"}))
+	log := GetLogger().With(slog.String(Component, "test"))
+	log.Debug("parsed", slog.String(FileKey, "/proc/stat"), slog.Int(LineKey, 3))
+	log.ErrorErr("failed", errors.New("boom"), slog.String(SocketKey, "sock0"))
+
+	content := readLog(t, logFile)
+	require.Contains(t, content, `"level":"DEBUG","msg":"parsed","component":"test","file":"/proc/stat","line":3}`)
+	require.Contains(t, content, `"level":"ERROR","msg":"failed","component":"test","error":"boom","socket":"sock0"}`)
+}
+
+func TestFormats(t *testing.T) {
+	record := slog.NewRecord(time.Date(2024, 5, 1, 10, 20, 30, 123e6, time.UTC), slog.LevelWarn, "stat read", 0)
+	record.AddAttrs(slog.String(FileKey, "/proc/net/stat"), slog.Any(ErrorKey, errors.New("no such file")))
+	tCases := []struct {
+		format   string
+		expected string
+	}{
+		{FormatLogfmt, `ts=2024-05-01T10:20:30.123Z level=warning msg="stat read" component=test group.file=/proc/net/stat group.error="no such file"`},
+		{FormatConsole, `10:20:30.123 WRN [test] stat read group.file=/proc/net/stat group.error="no such file"`},
+	}
+	t.Setenv("NO_COLOR", "1")
+	for _, tCase := range tCases {
+		buf := &bytes.Buffer{}
+		handler := getHandler(tCase.format, buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+		handler = handler.WithAttrs([]slog.Attr{slog.String(Component, "test")}).WithGroup("group")
+		require.NoError(t, handler.Handle(context.Background(), record))
+		require.Equal(t, tCase.expected+"\n", buf.String(), tCase.format)
+	}
+}
+
+func TestConsoleColors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := newConsoleHandler(buf, &slog.HandlerOptions{})
+	handler.color = true
+	require.False(t, handler.Enabled(context.Background(), slog.LevelDebug))
+	require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelError, "failed", 0)))
+	require.Equal(t, colorRed+"ERR"+colorReset+" failed\n", buf.String())
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// rootHandler holds the handler built by the last Init call.
+	rootHandler atomic.Pointer[slog.Handler]
+	swapMu      sync.Mutex
+	// handleMu is read locked while a record is handled, so a swap waits for
+	// records going to the old handlers before closing their sinks.
+	handleMu sync.RWMutex
+	// openedSinks are files and sockets of the current handlers.
+	openedSinks []io.Closer
+)
+
+type derivedHandler struct {
+	root    *slog.Handler
+	handler slog.Handler
+}
+
+// reloadableHandler resolves rootHandler on every record, so loggers created
+// with With before a reload write to the new handlers after it. WithAttrs and
+// WithGroup calls are recorded and replayed on top of the current root.
+type reloadableHandler struct {
+	wrappers []func(slog.Handler) slog.Handler
+	cache    atomic.Pointer[derivedHandler]
+}
+
+func swapHandler(handler slog.Handler, sinks []io.Closer) {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+
+	rootHandler.Store(&handler)
+	slog.SetDefault(slog.New(&reloadableHandler{}))
+	// records started before the store may still write to the old sinks
+	handleMu.Lock()
+	handleMu.Unlock() //nolint:staticcheck // empty critical section drains handlers
+	closeAll(openedSinks)
+	openedSinks = sinks
+}
+
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		closer.Close()
+	}
+}
+
+// Reopen reopens log files by path, so that logs go to new files after
+// external logrotate renamed the old ones. Without log files it does nothing.
+func Reopen() error {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+
+	var errs []error
+	for _, sink := range openedSinks {
+		if file, ok := sink.(*fileWriter); ok {
+			errs = append(errs, file.Reopen())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (h *reloadableHandler) current() slog.Handler {
+	root := rootHandler.Load()
+	if cached := h.cache.Load(); cached != nil && cached.root == root {
+		return cached.handler
+	}
+	handler := *root
+	for _, wrap := range h.wrappers {
+		handler = wrap(handler)
+	}
+	h.cache.Store(&derivedHandler{root: root, handler: handler})
+
+	return handler
+}
+
+func (h *reloadableHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.current().Enabled(ctx, level)
+}
+
+func (h *reloadableHandler) Handle(ctx context.Context, record slog.Record) error {
+	handleMu.RLock()
+	defer handleMu.RUnlock()
+
+	return h.current().Handle(ctx, record)
+}
+
+func (h *reloadableHandler) with(wrap func(slog.Handler) slog.Handler) slog.Handler {
+	wrappers := make([]func(slog.Handler) slog.Handler, 0, len(h.wrappers)+1)
+	wrappers = append(wrappers, h.wrappers...)
+
+	return &reloadableHandler{wrappers: append(wrappers, wrap)}
+}
+
+func (h *reloadableHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *reloadableHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
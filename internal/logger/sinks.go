@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
+)
+
+const defaultTag = "ipt-netflow-exporter"
+
+// newSinkHandler builds a handler for the sink. Empty sink format is taken
+// from the logger section. Sinks without their own level follow the runtime
+// log levels, see SetLevels. The returned closer is nil for stdout and stderr.
+func newSinkHandler(cfg config.Logger, sink config.LogSink) (slog.Handler, io.Closer, error) {
+	if sink.Level != "" {
+		level, err := marshalLevel(sink.Level)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return sinkHandler(cfg, sink, level)
+	}
+	handler, closer, err := sinkHandler(cfg, sink, &minLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newLevelHandler(handler), closer, nil
+}
+
+func sinkHandler(cfg config.Logger, sink config.LogSink, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	format := cmp.Or(sink.Format, cfg.Format)
+	opts := &slog.HandlerOptions{Level: level}
+	tag := cmp.Or(sink.Tag, defaultTag)
+
+	switch sink.Type {
+	case config.LogSinkStdout, "":
+		return getHandler(format, os.Stdout, opts), nil, nil
+	case config.LogSinkStderr:
+		return getHandler(format, os.Stderr, opts), nil, nil
+	case config.LogSinkFile:
+		file, err := newFileWriter(sink.File, rotationSettings(cfg))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize log file %w", err)
+		}
+
+		return getHandler(format, file, opts), file, nil
+	case config.LogSinkSyslog:
+		writer, err := syslog.Dial(sink.Network, sink.Address, syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to syslog %w", err)
+		}
+
+		return newSyslogHandler(writer, format, level), writer, nil
+	case config.LogSinkJournald:
+		writer, err := newJournaldWriter(cmp.Or(sink.Address, journaldSocket))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to journald %w", err)
+		}
+
+		return newJournaldHandler(writer, level, tag), writer, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown log sink type %s", sink.Type)
+	}
+}
+
+// syslogHandler formats records with the handler of the format and sends them
+// to syslog with the severity matching the record level. Time is omitted as
+// syslog adds its own timestamp.
+type syslogHandler struct {
+	writer  *syslog.Writer
+	mu      *sync.Mutex
+	buf     *bytes.Buffer
+	handler slog.Handler
+}
+
+func newSyslogHandler(writer *syslog.Writer, format string, level slog.Leveler) *syslogHandler {
+	buf := &bytes.Buffer{}
+
+	return &syslogHandler{
+		writer: writer,
+		mu:     &sync.Mutex{},
+		buf:    buf,
+		handler: getHandler(format, buf, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+				if len(groups) == 0 && attr.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+
+				return attr
+			},
+		}),
+	}
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.handler.Handle(ctx, record); err != nil {
+		return err
+	}
+	msg := strings.TrimSuffix(h.buf.String(), "\n")
+	switch {
+	case record.Level >= slog.LevelError:
+		return h.writer.Err(msg)
+	case record.Level >= slog.LevelWarn:
+		return h.writer.Warning(msg)
+	case record.Level >= slog.LevelInfo:
+		return h.writer.Info(msg)
+	default:
+		return h.writer.Debug(msg)
+	}
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{writer: h.writer, mu: h.mu, buf: h.buf, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{writer: h.writer, mu: h.mu, buf: h.buf, handler: h.handler.WithGroup(name)}
+}
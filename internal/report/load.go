@@ -0,0 +1,189 @@
+package report
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// maxCaptureSize limits a stat file capture read from an archive.
+const maxCaptureSize = 16 << 20
+
+var (
+	// basicTimestamp matches e.g. 20260102T150405Z or 2026-01-02T15:04:05Z,
+	// dashes, colons and the zone are optional.
+	basicTimestamp = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})[T_](\d{2})[:-]?(\d{2})[:-]?(\d{2})(Z)?`)
+	unixTimestamp  = regexp.MustCompile(`(?:^|\D)(\d{10})(?:\D|$)`)
+)
+
+// Skipped is a file which was not used for the report.
+type Skipped struct {
+	Name   string
+	Reason string
+}
+
+// Load reads stat file captures from a directory or a tar archive, gzip
+// compressed or not. Capture time is taken from the file name, e.g.
+// ipt_netflow_snmp-20260102T150405Z or ipt_netflow_snmp.1767366245, or the
+// modification time otherwise. Samples are sorted by time.
+func Load(source string) ([]Sample, []Skipped, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error read captures: %w", err)
+	}
+	var samples []Sample
+	var skipped []Skipped
+	if info.IsDir() {
+		samples, skipped, err = loadDir(source)
+	} else {
+		samples, skipped, err = loadArchive(source)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.SortStableFunc(samples, func(a, b Sample) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return samples, skipped, nil
+}
+
+func loadDir(dir string) ([]Sample, []Skipped, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error read captures directory %s: %w", dir, err)
+	}
+	samples := []Sample{}
+	skipped := []Skipped{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			skipped = append(skipped, Skipped{Name: entry.Name(), Reason: err.Error()})
+
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			skipped = append(skipped, Skipped{Name: entry.Name(), Reason: err.Error()})
+
+			continue
+		}
+		sample, err := parseCapture(entry.Name(), info.ModTime(), content)
+		if err != nil {
+			skipped = append(skipped, Skipped{Name: entry.Name(), Reason: err.Error()})
+
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, skipped, nil
+}
+
+func loadArchive(file string) ([]Sample, []Skipped, error) {
+	archiveFile, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error open captures archive %s: %w", file, err)
+	}
+	defer archiveFile.Close()
+	reader := bufio.NewReader(archiveFile)
+	var input io.Reader = reader
+	// gzip magic number
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error read captures archive %s: %w", file, err)
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+
+	archive := tar.NewReader(input)
+	samples := []Sample{}
+	skipped := []Skipped{}
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error read captures archive %s: %w", file, err)
+		}
+		if header.Typeflag != tar.TypeReg || strings.HasPrefix(path.Base(header.Name), ".") {
+			continue
+		}
+		if header.Size > maxCaptureSize {
+			skipped = append(skipped, Skipped{Name: header.Name, Reason: "file is too large for a stat file capture"})
+
+			continue
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error read %s from captures archive %s: %w", header.Name, file, err)
+		}
+		sample, err := parseCapture(header.Name, header.ModTime, content)
+		if err != nil {
+			skipped = append(skipped, Skipped{Name: header.Name, Reason: err.Error()})
+
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, skipped, nil
+}
+
+func parseCapture(name string, modTime time.Time, content []byte) (Sample, error) {
+	stat, err := statparser.New(name).Parse(content)
+	if err != nil {
+		return Sample{}, err
+	}
+	if len(stat.CPUStatList) == 0 && len(stat.SockStatList) == 0 && stat.InFlows == 0 && stat.InPackets == 0 {
+		return Sample{}, errors.New("no ipt_NETFLOW statistics found")
+	}
+	captureTime, ok := nameTime(path.Base(name))
+	if !ok {
+		captureTime = modTime
+	}
+
+	return Sample{Time: captureTime, Name: name, Stat: stat}, nil
+}
+
+// nameTime returns the capture time from a file name, in local time when the
+// name has no zone.
+func nameTime(name string) (time.Time, bool) {
+	if match := basicTimestamp.FindStringSubmatch(name); match != nil {
+		location := time.Local
+		if match[7] == "Z" {
+			location = time.UTC
+		}
+		captureTime, err := time.ParseInLocation("20060102150405", strings.Join(match[1:7], ""), location)
+		if err == nil {
+			return captureTime, true
+		}
+	}
+	if match := unixTimestamp.FindStringSubmatch(name); match != nil {
+		seconds, err := strconv.ParseInt(match[1], 10, 64)
+		if err == nil {
+			return time.Unix(seconds, 0), true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statfmt"
+)
+
+const (
+	sparklineWidth  = 160
+	sparklineHeight = 28
+)
+
+var (
+	//go:embed templates/report.md
+	markdownText string
+	//go:embed templates/report.html
+	htmlText string
+)
+
+var funcs = map[string]any{
+	"human":     statfmt.Humanize,
+	"count":     func(value uint64) string { return statfmt.Humanize(float64(value)) },
+	"timestamp": func(value time.Time) string { return value.Format(time.RFC3339) },
+	"duration":  func(value time.Duration) string { return value.Round(time.Second).String() },
+	"percent":   func(value float64) string { return strconv.FormatFloat(value, 'f', 1, 64) + "%" },
+	"peak":      formatPeak,
+	"total":     formatTotal,
+}
+
+var (
+	markdownTemplate = texttemplate.Must(texttemplate.New("report").Funcs(funcs).Funcs(texttemplate.FuncMap{
+		"sparkline": func(points []float64) string {
+			return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(sparkline(points)))
+		},
+		"cell": func(value string) string { return strings.ReplaceAll(value, "|", `\|`) },
+	}).Parse(markdownText))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(funcs).Funcs(htmltemplate.FuncMap{
+		// the SVG is built from numbers only
+		"sparkline": func(points []float64) htmltemplate.HTML { return htmltemplate.HTML(sparkline(points)) }, //nolint:gosec
+	}).Parse(htmlText))
+)
+
+// WriteMarkdown writes the report as Markdown, sparklines are SVG images in
+// data URIs.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	return markdownTemplate.Execute(w, r)
+}
+
+// WriteHTML writes the report as a standalone HTML page with inline SVG
+// sparklines.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// sparkline returns an SVG polyline of points scaled to the image height.
+func sparkline(points []float64) string {
+	low, high := 0.0, 0.0
+	for index, point := range points {
+		if index == 0 || point < low {
+			low = point
+		}
+		if index == 0 || point > high {
+			high = point
+		}
+	}
+	coordinates := make([]string, 0, len(points))
+	for index, point := range points {
+		x := float64(sparklineWidth-2)/2 + 1
+		if len(points) > 1 {
+			x = 1 + float64(index)*float64(sparklineWidth-2)/float64(len(points)-1)
+		}
+		y := float64(sparklineHeight) / 2
+		if high > low {
+			y = 1 + (high-point)*float64(sparklineHeight-2)/(high-low)
+		}
+		coordinates = append(coordinates, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	shape := fmt.Sprintf(`<polyline fill="none" stroke="#4a90d9" stroke-width="1.5" points="%s"/>`, strings.Join(coordinates, " "))
+	if len(points) == 1 {
+		shape = fmt.Sprintf(`<circle r="1.5" fill="#4a90d9" cx="%s"/>`, strings.Replace(coordinates[0], ",", `" cy="`, 1))
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">%s</svg>`,
+		sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight, shape)
+}
+
+// formatTotal formats growth of a counter issue, level issues have none.
+func formatTotal(issue Issue) string {
+	switch issue.Kind {
+	case KindSndbuf, KindHashMetric, KindImbalance, KindReset:
+		return "-"
+	default:
+		return statfmt.Humanize(issue.Total)
+	}
+}
+
+// formatPeak formats the peak of an issue with its unit.
+func formatPeak(issue Issue) string {
+	switch issue.Kind {
+	case KindSndbuf:
+		return strconv.FormatFloat(issue.Peak, 'f', 1, 64) + "% fill"
+	case KindHashMetric:
+		return strconv.FormatFloat(issue.Peak, 'f', 2, 64)
+	case KindImbalance:
+		return strconv.FormatFloat(issue.Peak, 'f', 2, 64) + "x even share"
+	case KindReset:
+		return "-"
+	default:
+		return statfmt.Humanize(issue.Peak) + "/s"
+	}
+}
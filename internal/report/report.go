@@ -0,0 +1,400 @@
+// Package report summarizes a series of ipt_NETFLOW stat file captures for
+// postmortems, as Markdown or HTML.
+package report
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statfmt"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// Issue kinds, in the order of the summary table.
+const (
+	KindLoss         = "export loss"
+	KindSocketErrors = "socket errors"
+	KindSndbuf       = "sndbuf saturation"
+	KindMaxflows     = "maxflows drops"
+	KindAlloc        = "allocation failures"
+	KindHashMetric   = "hash metric degradation"
+	KindImbalance    = "CPU imbalance"
+	KindReset        = "counter reset"
+)
+
+var kinds = []string{KindLoss, KindSocketErrors, KindSndbuf, KindMaxflows, KindAlloc, KindHashMetric, KindImbalance, KindReset}
+
+// Sample is one capture of the stat file.
+type Sample struct {
+	Time time.Time
+	Name string
+	Stat statparser.Statistics
+}
+
+// Options are thresholds of level issues.
+type Options struct {
+	// SndbufFill is the socket send buffer fill, percent.
+	SndbufFill float64
+	// HashMetric is the hash metric, optimal is 1.0.
+	HashMetric float64
+	// ImbalanceRatio is the share of packets of the busiest CPU to an even
+	// share.
+	ImbalanceRatio float64
+}
+
+// DefaultOptions returns the thresholds of the status page.
+func DefaultOptions() Options {
+	return Options{SndbufFill: 90, HashMetric: 1.5, ImbalanceRatio: 1.5}
+}
+
+// Issue is a time range with a problem. Ranges of counter issues span the
+// intervals the counter grew in, Total is its growth and Peak the highest
+// per second rate. Level issues span captures above the threshold, Peak is
+// the highest value.
+type Issue struct {
+	Kind    string
+	Subject string
+	Start   time.Time
+	End     time.Time
+	Total   float64
+	Peak    float64
+}
+
+// KindSummary counts issues of one kind.
+type KindSummary struct {
+	Kind     string
+	Ranges   int
+	Duration time.Duration
+	Total    float64
+}
+
+// Series is a value over time for sparklines.
+type Series struct {
+	Name   string
+	Points []float64
+	Min    float64
+	Max    float64
+	Last   float64
+}
+
+// CPUSummary sums one CPU over the report range.
+type CPUSummary struct {
+	CPU      string
+	Packets  uint64
+	Share    float64
+	Maxflows uint64
+	Alloc    uint64
+}
+
+// SocketSummary sums one socket over the report range.
+type SocketSummary struct {
+	Name            string
+	Destination     string
+	Errors          uint64
+	MaxFill         float64
+	InactiveSamples int
+}
+
+// Report is the summary of captures.
+type Report struct {
+	Source    string
+	Generated time.Time
+	Start     time.Time
+	End       time.Time
+	Samples   int
+	Options   Options
+	Kinds     []KindSummary
+	Issues    []Issue
+	Series    []Series
+	CPUs      []CPUSummary
+	Sockets   []SocketSummary
+	Skipped   []Skipped
+}
+
+// Duration returns the time range of the captures.
+func (r *Report) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// openIssue is an issue which grows while following captures have it.
+type openIssue struct {
+	issue Issue
+	last  int
+}
+
+type issueTracker struct {
+	open   map[string]*openIssue
+	issues []Issue
+}
+
+// add records an issue at capture index, it extends the issue of the same
+// kind and subject at the previous capture.
+func (t *issueTracker) add(index int, start, end time.Time, kind, subject string, total, peak float64) {
+	key := kind + "\x00" + subject
+	if open, ok := t.open[key]; ok {
+		if open.last == index-1 {
+			open.issue.End = end
+			open.issue.Total += total
+			open.issue.Peak = max(open.issue.Peak, peak)
+			open.last = index
+
+			return
+		}
+		t.issues = append(t.issues, open.issue)
+	}
+	t.open[key] = &openIssue{
+		issue: Issue{Kind: kind, Subject: subject, Start: start, End: end, Total: total, Peak: peak},
+		last:  index,
+	}
+}
+
+func (t *issueTracker) close() []Issue {
+	for _, open := range t.open {
+		t.issues = append(t.issues, open.issue)
+	}
+	slices.SortFunc(t.issues, func(a, b Issue) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(slices.Index(kinds, a.Kind), slices.Index(kinds, b.Kind)),
+			cmp.Compare(a.Subject, b.Subject))
+	})
+
+	return t.issues
+}
+
+// Build analyzes samples sorted by time.
+func Build(source string, samples []Sample, skipped []Skipped, opts Options) Report {
+	report := Report{Source: source, Generated: time.Now(), Samples: len(samples), Options: opts, Skipped: skipped}
+	if len(samples) > 0 {
+		report.Start, report.End = samples[0].Time, samples[len(samples)-1].Time
+	}
+	tracker := &issueTracker{open: map[string]*openIssue{}}
+	series := map[string]*Series{}
+	addPoint := func(name string, value float64) {
+		if _, ok := series[name]; !ok {
+			series[name] = &Series{Name: name}
+		}
+		series[name].Points = append(series[name].Points, value)
+	}
+	cpus := map[string]*CPUSummary{}
+	sockets := map[string]*SocketSummary{}
+
+	for index := range samples {
+		current := &samples[index]
+		levelIssues(tracker, index, current, opts)
+		addPoint("Max sndbuf fill, %", maxSndbufFill(&current.Stat))
+		addPoint("Hash metric", current.Stat.HashMetric)
+		for _, socket := range current.Stat.SockStatList {
+			summary := socketSummary(sockets, &socket)
+			summary.MaxFill = max(summary.MaxFill, statfmt.SndbufFill(&socket))
+			if socket.SockActive == 0 {
+				summary.InactiveSamples++
+			}
+		}
+		if index == 0 {
+			for _, cpu := range current.Stat.CPUStatList {
+				cpuSummary(cpus, cpu.CPU)
+			}
+
+			continue
+		}
+		prev := &samples[index-1]
+		seconds := current.Time.Sub(prev.Time).Seconds()
+		if seconds <= 0 {
+			continue
+		}
+		if counterReset(&prev.Stat, &current.Stat) {
+			tracker.add(index, prev.Time, current.Time, KindReset, "module", 0, 0)
+
+			continue
+		}
+		intervalIssues(tracker, index, prev, current, seconds, opts, addPoint)
+		sumEntities(cpus, sockets, &prev.Stat, &current.Stat)
+	}
+
+	report.Issues = tracker.close()
+	report.Kinds = summarizeKinds(report.Issues)
+	for _, name := range []string{"In packets/s", "Lost flows/s", "Socket errors/s", "Max sndbuf fill, %", "Hash metric", "Busiest CPU share, %"} {
+		if current, ok := series[name]; ok && len(current.Points) > 0 {
+			current.Min, current.Max = slices.Min(current.Points), slices.Max(current.Points)
+			current.Last = current.Points[len(current.Points)-1]
+			report.Series = append(report.Series, *current)
+		}
+	}
+	report.CPUs = sortedValues(cpus, func(a, b *CPUSummary) int { return statfmt.CompareNames(a.CPU, b.CPU) })
+	var totalPackets uint64
+	for _, cpu := range report.CPUs {
+		totalPackets += cpu.Packets
+	}
+	for index := range report.CPUs {
+		if totalPackets > 0 {
+			report.CPUs[index].Share = float64(report.CPUs[index].Packets) * 100 / float64(totalPackets)
+		}
+	}
+	report.Sockets = sortedValues(sockets, func(a, b *SocketSummary) int {
+		return cmp.Or(statfmt.CompareNames(a.Name, b.Name), cmp.Compare(a.Destination, b.Destination))
+	})
+
+	return report
+}
+
+// levelIssues checks values of one capture.
+func levelIssues(tracker *issueTracker, index int, sample *Sample, opts Options) {
+	for _, socket := range sample.Stat.SockStatList {
+		if fill := statfmt.SndbufFill(&socket); fill >= opts.SndbufFill {
+			tracker.add(index, sample.Time, sample.Time, KindSndbuf, socketName(&socket), 0, fill)
+		}
+	}
+	if sample.Stat.HashMetric >= opts.HashMetric {
+		tracker.add(index, sample.Time, sample.Time, KindHashMetric, "hash table", 0, sample.Stat.HashMetric)
+	}
+}
+
+// intervalIssues checks counter growth between two captures.
+func intervalIssues(tracker *issueTracker, index int, prev, current *Sample, seconds float64, opts Options, addPoint func(string, float64)) {
+	add := func(kind, subject string, delta uint64) {
+		if delta > 0 {
+			tracker.add(index, prev.Time, current.Time, kind, subject, float64(delta), float64(delta)/seconds)
+		}
+	}
+	add(KindLoss, "flows", statfmt.CounterDelta(prev.Stat.LostFlows, current.Stat.LostFlows))
+	add(KindLoss, "packets", statfmt.CounterDelta(prev.Stat.LostPackets, current.Stat.LostPackets))
+	addPoint("In packets/s", float64(statfmt.CounterDelta(prev.Stat.InPackets, current.Stat.InPackets))/seconds)
+	addPoint("Lost flows/s", float64(statfmt.CounterDelta(prev.Stat.LostFlows, current.Stat.LostFlows))/seconds)
+
+	prevSockets := map[string]statparser.NFSockEntry{}
+	for _, socket := range prev.Stat.SockStatList {
+		prevSockets[socketName(&socket)] = socket
+	}
+	var socketErrors uint64
+	for _, socket := range current.Stat.SockStatList {
+		if prevSocket, ok := prevSockets[socketName(&socket)]; ok {
+			delta := statfmt.CounterDelta(statfmt.SocketErrors(&prevSocket), statfmt.SocketErrors(&socket))
+			socketErrors += delta
+			add(KindSocketErrors, socketName(&socket), delta)
+		}
+	}
+	addPoint("Socket errors/s", float64(socketErrors)/seconds)
+
+	prevCPUs := map[string]statparser.CPUStat{}
+	for _, cpu := range prev.Stat.CPUStatList {
+		prevCPUs[cpu.CPU] = cpu
+	}
+	var totalPackets, busiestPackets uint64
+	busiest := ""
+	common := 0
+	for _, cpu := range current.Stat.CPUStatList {
+		prevCPU, ok := prevCPUs[cpu.CPU]
+		if !ok {
+			continue
+		}
+		common++
+		add(KindMaxflows, cpu.CPU, statfmt.CounterDelta(prevCPU.CPUErrMaxflows, cpu.CPUErrMaxflows))
+		add(KindAlloc, cpu.CPU, statfmt.CounterDelta(prevCPU.CPUErrAlloc, cpu.CPUErrAlloc))
+		packets := statfmt.CounterDelta(prevCPU.CPUInPackets, cpu.CPUInPackets)
+		totalPackets += packets
+		if packets > busiestPackets {
+			busiest, busiestPackets = cpu.CPU, packets
+		}
+	}
+	if totalPackets == 0 || common < 2 {
+		return
+	}
+	share := float64(busiestPackets) / float64(totalPackets)
+	addPoint("Busiest CPU share, %", share*100)
+	if ratio := share * float64(common); ratio >= opts.ImbalanceRatio {
+		tracker.add(index, prev.Time, current.Time, KindImbalance, busiest, 0, ratio)
+	}
+}
+
+// sumEntities adds counter growth of CPUs and sockets to their summaries.
+func sumEntities(cpus map[string]*CPUSummary, sockets map[string]*SocketSummary, prev, current *statparser.Statistics) {
+	prevCPUs := map[string]statparser.CPUStat{}
+	for _, cpu := range prev.CPUStatList {
+		prevCPUs[cpu.CPU] = cpu
+	}
+	for _, cpu := range current.CPUStatList {
+		summary := cpuSummary(cpus, cpu.CPU)
+		if prevCPU, ok := prevCPUs[cpu.CPU]; ok {
+			summary.Packets += statfmt.CounterDelta(prevCPU.CPUInPackets, cpu.CPUInPackets)
+			summary.Maxflows += statfmt.CounterDelta(prevCPU.CPUErrMaxflows, cpu.CPUErrMaxflows)
+			summary.Alloc += statfmt.CounterDelta(prevCPU.CPUErrAlloc, cpu.CPUErrAlloc)
+		}
+	}
+	prevSockets := map[string]statparser.NFSockEntry{}
+	for _, socket := range prev.SockStatList {
+		prevSockets[socketName(&socket)] = socket
+	}
+	for _, socket := range current.SockStatList {
+		if prevSocket, ok := prevSockets[socketName(&socket)]; ok {
+			socketSummary(sockets, &socket).Errors += statfmt.CounterDelta(statfmt.SocketErrors(&prevSocket), statfmt.SocketErrors(&socket))
+		}
+	}
+}
+
+// counterReset reports whether global counters went backwards, i.e. the
+// module was reloaded between captures.
+func counterReset(prev, current *statparser.Statistics) bool {
+	return current.InFlows < prev.InFlows || current.InPackets < prev.InPackets ||
+		current.OutFlows < prev.OutFlows || current.LostFlows < prev.LostFlows
+}
+
+func summarizeKinds(issues []Issue) []KindSummary {
+	summaries := make([]KindSummary, 0, len(kinds))
+	for _, kind := range kinds {
+		summary := KindSummary{Kind: kind}
+		for _, issue := range issues {
+			if issue.Kind == kind {
+				summary.Ranges++
+				summary.Duration += issue.End.Sub(issue.Start)
+				summary.Total += issue.Total
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+func cpuSummary(cpus map[string]*CPUSummary, name string) *CPUSummary {
+	if _, ok := cpus[name]; !ok {
+		cpus[name] = &CPUSummary{CPU: name}
+	}
+
+	return cpus[name]
+}
+
+func socketSummary(sockets map[string]*SocketSummary, socket *statparser.NFSockEntry) *SocketSummary {
+	key := socketName(socket)
+	if _, ok := sockets[key]; !ok {
+		sockets[key] = &SocketSummary{Name: socket.SockName, Destination: socket.SockDestination}
+	}
+
+	return sockets[key]
+}
+
+func sortedValues[T any](values map[string]*T, compare func(a, b *T) int) []T {
+	pointers := make([]*T, 0, len(values))
+	for _, value := range values {
+		pointers = append(pointers, value)
+	}
+	slices.SortFunc(pointers, compare)
+	result := make([]T, 0, len(pointers))
+	for _, value := range pointers {
+		result = append(result, *value)
+	}
+
+	return result
+}
+
+func socketName(socket *statparser.NFSockEntry) string {
+	return socket.SockName + " " + socket.SockDestination
+}
+
+func maxSndbufFill(stat *statparser.Statistics) float64 {
+	var fill float64
+	for _, socket := range stat.SockStatList {
+		fill = max(fill, statfmt.SndbufFill(&socket))
+	}
+
+	return fill
+}
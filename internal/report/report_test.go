@@ -0,0 +1,234 @@
+package report
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var reportStart = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// capture returns stat file content at index of a test recording: flows are
+// lost at captures 4-6, sock0 errors grow at 2, the send buffer is full at 8,
+// cpu0 drops flows at 9 and the hash metric degrades at 10.
+func capture(index int) string {
+	lost := 10
+	if index >= 4 {
+		lost += 5 * (min(index, 6) - 3)
+	}
+	fill, maxflows, hashMetric, sockErrors := 20, 0, 1.0, 0
+	if index == 8 {
+		fill = 250
+	}
+	if index >= 9 {
+		maxflows = 3
+	}
+	if index >= 10 {
+		hashMetric = 1.8
+	}
+	if index >= 2 {
+		sockErrors = 4
+	}
+
+	return fmt.Sprintf(`inFlows %d
+inPackets %d
+lostFlows %d
+hashMetric %.1f
+cpu0 10 2 %d 4 1.0 5 6 7 8 9 %d
+cpu1 10 2 %d 4 1.0 5 6 7 8 9 0
+sock0 127.0.0.1:2055 1 0 %d 0 0 263 %d 7
+`, 1000*index, 100000*index, lost, hashMetric, 60000*index, maxflows, 40000*index, sockErrors, fill)
+}
+
+func captureName(index int) string {
+	return "ipt_netflow_snmp-" + reportStart.Add(time.Duration(index)*30*time.Second).Format("20060102T150405Z")
+}
+
+func writeCaptures(t *testing.T, count int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for index := range count {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, captureName(index)), []byte(capture(index)), 0o600))
+	}
+
+	return dir
+}
+
+func findIssue(t *testing.T, issues []Issue, kind string) Issue {
+	t.Helper()
+	for _, issue := range issues {
+		if issue.Kind == kind {
+			return issue
+		}
+	}
+	require.Failf(t, "issue not found", "kind %s", kind)
+
+	return Issue{}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := writeCaptures(t, 3)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a capture"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte(capture(0)), 0o600))
+	samples, skipped, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	require.Equal(t, reportStart, samples[0].Time)
+	require.Equal(t, reportStart.Add(time.Minute), samples[2].Time)
+	require.Equal(t, uint64(200000), samples[2].Stat.InPackets)
+	require.Equal(t, []Skipped{{Name: "notes.txt", Reason: "no ipt_NETFLOW statistics found"}}, skipped)
+}
+
+func TestLoadArchive(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	archive := tar.NewWriter(gzipWriter)
+	modTime := reportStart.Add(time.Hour)
+	// stored out of order, the second file has no time in its name
+	for _, file := range []struct{ name, content string }{
+		{"captures/" + captureName(1), capture(1)},
+		{"captures/latest", capture(2)},
+		{"captures/" + captureName(0), capture(0)},
+	} {
+		require.NoError(t, archive.WriteHeader(&tar.Header{
+			Name: file.name, Mode: 0o600, Size: int64(len(file.content)), ModTime: modTime, Typeflag: tar.TypeReg,
+		}))
+		_, err := archive.Write([]byte(file.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	require.NoError(t, gzipWriter.Close())
+	file := filepath.Join(t.TempDir(), "captures.tar.gz")
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0o600))
+
+	samples, skipped, err := Load(file)
+	require.NoError(t, err)
+	require.Empty(t, skipped)
+	require.Len(t, samples, 3)
+	require.Equal(t, "captures/"+captureName(0), samples[0].Name)
+	require.Equal(t, "captures/latest", samples[2].Name)
+	require.True(t, modTime.Equal(samples[2].Time))
+}
+
+func TestNameTime(t *testing.T) {
+	for name, expected := range map[string]time.Time{
+		"ipt_netflow_snmp-20261001T120000Z":  reportStart,
+		"snmp_2026-10-01T12:00:00Z.txt":      reportStart,
+		"snmp-2026-10-01_12-00-00":           time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local),
+		"ipt_netflow_snmp.1790856000":        time.Unix(1790856000, 0),
+		"ipt_netflow_snmp.1790856000.backup": time.Unix(1790856000, 0),
+	} {
+		captureTime, ok := nameTime(name)
+		require.True(t, ok, name)
+		require.True(t, expected.Equal(captureTime), name)
+	}
+	_, ok := nameTime("ipt_netflow_snmp")
+	require.False(t, ok)
+}
+
+func TestBuild(t *testing.T) {
+	dir := writeCaptures(t, 12)
+	samples, skipped, err := Load(dir)
+	require.NoError(t, err)
+	report := Build("captures", samples, skipped, DefaultOptions())
+	require.Equal(t, 12, report.Samples)
+	require.Equal(t, 330*time.Second, report.Duration())
+
+	loss := findIssue(t, report.Issues, KindLoss)
+	require.Equal(t, "flows", loss.Subject)
+	require.Equal(t, reportStart.Add(90*time.Second), loss.Start)
+	require.Equal(t, reportStart.Add(180*time.Second), loss.End)
+	require.InDelta(t, 15, loss.Total, 0.001)
+	require.InDelta(t, 5.0/30, loss.Peak, 0.001)
+
+	socketErrors := findIssue(t, report.Issues, KindSocketErrors)
+	require.Equal(t, "sock0 127.0.0.1:2055", socketErrors.Subject)
+	require.InDelta(t, 4, socketErrors.Total, 0.001)
+
+	sndbuf := findIssue(t, report.Issues, KindSndbuf)
+	require.Equal(t, reportStart.Add(240*time.Second), sndbuf.Start)
+	require.Equal(t, sndbuf.Start, sndbuf.End)
+	require.InDelta(t, 95.06, sndbuf.Peak, 0.01)
+
+	maxflows := findIssue(t, report.Issues, KindMaxflows)
+	require.Equal(t, "cpu0", maxflows.Subject)
+	require.InDelta(t, 3, maxflows.Total, 0.001)
+
+	hashMetric := findIssue(t, report.Issues, KindHashMetric)
+	require.Equal(t, reportStart.Add(300*time.Second), hashMetric.Start)
+	require.Equal(t, reportStart.Add(330*time.Second), hashMetric.End)
+
+	for _, summary := range report.Kinds {
+		switch summary.Kind {
+		case KindAlloc, KindImbalance, KindReset:
+			require.Zero(t, summary.Ranges, summary.Kind)
+		default:
+			require.Equal(t, 1, summary.Ranges, summary.Kind)
+		}
+	}
+
+	require.Len(t, report.CPUs, 2)
+	require.Equal(t, CPUSummary{CPU: "cpu0", Packets: 660000, Share: 60, Maxflows: 3}, report.CPUs[0])
+	require.Len(t, report.Sockets, 1)
+	require.Equal(t, uint64(4), report.Sockets[0].Errors)
+	require.InDelta(t, 95.06, report.Sockets[0].MaxFill, 0.01)
+	require.Len(t, report.Series, 6)
+	require.Len(t, report.Series[0].Points, 11)
+	require.InDelta(t, 1.8, report.Series[4].Last, 0.001)
+}
+
+func TestBuildImbalanceAndReset(t *testing.T) {
+	dir := writeCaptures(t, 3)
+	samples, _, err := Load(dir)
+	require.NoError(t, err)
+	samples[1].Stat.CPUStatList[0].CPUInPackets = 190000
+	samples[2].Stat.CPUStatList[0].CPUInPackets = 200000
+	samples[2].Stat.InFlows = 0
+	report := Build("captures", samples, nil, DefaultOptions())
+
+	imbalance := findIssue(t, report.Issues, KindImbalance)
+	require.Equal(t, "cpu0", imbalance.Subject)
+	require.InDelta(t, 1.652, imbalance.Peak, 0.001)
+	reset := findIssue(t, report.Issues, KindReset)
+	require.Equal(t, samples[2].Time, reset.End)
+}
+
+func TestWriteReport(t *testing.T) {
+	dir := writeCaptures(t, 12)
+	samples, _, err := Load(dir)
+	require.NoError(t, err)
+	report := Build("<captures>", samples, []Skipped{{Name: "notes.txt", Reason: "no ipt_NETFLOW statistics found"}}, DefaultOptions())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteMarkdown(buf))
+	markdown := buf.String()
+	require.Contains(t, markdown, "Source: `<captures>`, 12 captures from 2026-10-01T12:00:00Z to 2026-10-01T12:05:30Z (5m30s).")
+	require.Contains(t, markdown, "| export loss | 1 | 1m30s | 15 |")
+	require.Contains(t, markdown, "| allocation failures | 0 | - | - |")
+	require.Contains(t, markdown, "| 2026-10-01T12:01:30Z | 2026-10-01T12:03:00Z | export loss | flows | 15 | 0.17/s |")
+	require.Contains(t, markdown, "| 2026-10-01T12:04:00Z | 2026-10-01T12:04:00Z | sndbuf saturation | sock0 127.0.0.1:2055 | - | 95.1% fill |")
+	require.Contains(t, markdown, "| cpu0 | 660.00k | 60.0% | 3 | 0 |")
+	require.Contains(t, markdown, "![Hash metric](data:image/svg+xml;base64,")
+	require.Contains(t, markdown, "- `notes.txt`: no ipt_NETFLOW statistics found")
+
+	buf.Reset()
+	require.NoError(t, report.WriteHTML(buf))
+	html := buf.String()
+	require.Contains(t, html, "<code>&lt;captures&gt;</code>")
+	require.Equal(t, 6, strings.Count(html, `<svg xmlns="http://www.w3.org/2000/svg"`))
+	require.Contains(t, html, `<tr class="warning"><td>export loss</td><td>1</td><td>1m30s</td><td>15</td></tr>`)
+}
+
+func TestSparkline(t *testing.T) {
+	require.Contains(t, sparkline([]float64{0, 1}), `points="1.0,27.0 159.0,1.0"`)
+	require.Contains(t, sparkline([]float64{5, 5}), `points="1.0,14.0 159.0,14.0"`)
+	require.Contains(t, sparkline([]float64{5}), `<circle r="1.5" fill="#4a90d9" cx="80.0" cy="14.0"/>`)
+}
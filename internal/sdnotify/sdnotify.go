@@ -0,0 +1,90 @@
+package sdnotify
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+)
+
+// readyRetryInterval is used to retry the readiness check when systemd
+// watchdog is disabled.
+const readyRetryInterval = time.Second
+
+// Notifier sends service state to systemd over NOTIFY_SOCKET.
+// Without NOTIFY_SOCKET all notifications are no-ops.
+type Notifier struct {
+	log *logger.Logger
+}
+
+func New() *Notifier {
+	return &Notifier{
+		log: logger.GetLogger().With(slog.String(logger.Component, "SdNotifier")),
+	}
+}
+
+func (n *Notifier) Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+func (n *Notifier) notify(state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		n.log.Errorf("error send %s to systemd: %s", state, err.Error())
+	}
+}
+
+func (n *Notifier) Ready() {
+	n.notify(daemon.SdNotifyReady)
+}
+
+func (n *Notifier) Watchdog() {
+	n.notify(daemon.SdNotifyWatchdog)
+}
+
+func (n *Notifier) Stopping() {
+	n.notify(daemon.SdNotifyStopping)
+}
+
+// Run sends READY=1 after the first successful check and then, if systemd
+// watchdog is enabled, WATCHDOG=1 after every successful check at half of the
+// watchdog interval. A failed check skips the notification so a stuck stat
+// file makes systemd restart the service. Run returns when ctx is done.
+func (n *Notifier) Run(ctx context.Context, check func() error) {
+	if !n.Enabled() {
+		return
+	}
+	watchdog, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		n.log.Errorf("error read systemd watchdog settings: %s", err.Error())
+	}
+	interval := watchdog / 2
+	if interval <= 0 {
+		interval = readyRetryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	ready := false
+	for {
+		if err := check(); err != nil {
+			n.log.Warningf("systemd health check failed: %s", err.Error())
+		} else if !ready {
+			n.Ready()
+			ready = true
+			if watchdog <= 0 {
+				return
+			}
+		} else {
+			n.Watchdog()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sdnotify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	logger.SetDefaultDiscardLogger()
+}
+
+func notifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	return conn
+}
+
+func readState(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestDisabled(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	notifier := New()
+	require.False(t, notifier.Enabled())
+	called := false
+	notifier.Run(context.Background(), func() error {
+		called = true
+
+		return nil
+	})
+	require.False(t, called)
+}
+
+func TestReadyAfterSuccessfulCheck(t *testing.T) {
+	conn := notifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "")
+	var checks atomic.Int32
+	New().Run(context.Background(), func() error {
+		if checks.Add(1) < 2 {
+			return errors.New("stat file not ready")
+		}
+
+		return nil
+	})
+	require.Equal(t, int32(2), checks.Load())
+	require.Equal(t, "READY=1", readState(t, conn))
+}
+
+func TestWatchdog(t *testing.T) {
+	conn := notifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		New().Run(ctx, func() error { return nil })
+		close(done)
+	}()
+	require.Equal(t, "READY=1", readState(t, conn))
+	require.Equal(t, "WATCHDOG=1", readState(t, conn))
+	require.Equal(t, "WATCHDOG=1", readState(t, conn))
+	cancel()
+	<-done
+
+	New().Stopping()
+	require.Equal(t, "STOPPING=1", readState(t, conn))
+}
@@ -0,0 +1,309 @@
+// Package statdiff compares two reads of the ipt_NETFLOW stat file.
+package statdiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statfmt"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// Entry states of CPUs and sockets.
+const (
+	StateCommon  = "common"
+	StateAdded   = "added"
+	StateRemoved = "removed"
+)
+
+// Field is the change of one stat field. Rate is per second and set for
+// counters (see statparser.IsCounter) which did not go backwards when the
+// interval is known.
+type Field struct {
+	Name      string   `json:"name"`
+	Before    float64  `json:"before"`
+	After     float64  `json:"after"`
+	Delta     float64  `json:"delta"`
+	Rate      *float64 `json:"rate,omitempty"`
+	Counter   bool     `json:"counter"`
+	Backwards bool     `json:"backwards,omitempty"`
+}
+
+// CPU is the change of one CPU. Shares are percents of packets of all CPUs:
+// ShareBefore of packets counted until the first read, ShareAfter of packets
+// counted between reads. The packet rate is used when no packets were
+// counted, e.g. after a module reload.
+type CPU struct {
+	CPU          string  `json:"cpu"`
+	State        string  `json:"state"`
+	ShareBefore  float64 `json:"share_before"`
+	ShareAfter   float64 `json:"share_after"`
+	ShareShifted bool    `json:"share_shifted,omitempty"`
+	Fields       []Field `json:"fields"`
+}
+
+// Socket is the change of one socket, matched by name and destination.
+type Socket struct {
+	Name        string  `json:"name"`
+	Destination string  `json:"destination"`
+	State       string  `json:"state"`
+	Fields      []Field `json:"fields"`
+}
+
+// Result is the difference of two stat files.
+type Result struct {
+	Interval float64 `json:"interval_seconds,omitempty"`
+	// IntervalFromModTime marks an interval guessed from file modification
+	// times, which are wrong for copied captures.
+	IntervalFromModTime bool     `json:"interval_from_mod_time,omitempty"`
+	Common              []Field  `json:"common"`
+	CPUs                []CPU    `json:"cpus"`
+	Sockets             []Socket `json:"sockets"`
+	// Findings are counters which went backwards, added and removed CPUs and
+	// sockets, and CPUs whose share moved by ShareThreshold or more.
+	Findings []string `json:"findings"`
+}
+
+// Options of Diff. ShareThreshold is in percentage points, zero disables
+// share checks. Interval is zero when unknown, rates are not computed then.
+// IntervalFromModTime is set when Interval is the difference of file
+// modification times.
+type Options struct {
+	Interval            time.Duration
+	IntervalFromModTime bool
+	ShareThreshold      float64
+}
+
+// Diff returns changes from before to after.
+func Diff(before, after *statparser.Statistics, opts Options) Result {
+	result := Result{
+		Interval:            opts.Interval.Seconds(),
+		IntervalFromModTime: opts.IntervalFromModTime && opts.Interval > 0,
+		CPUs:                []CPU{},
+		Sockets:             []Socket{},
+		Findings:            []string{},
+	}
+	result.Common = result.fields("", reflect.ValueOf(*before), reflect.ValueOf(*after))
+	result.diffCPUs(before, after, opts.ShareThreshold)
+	result.diffSockets(before, after)
+
+	return result
+}
+
+func (r *Result) diffCPUs(before, after *statparser.Statistics, shareThreshold float64) {
+	beforeCPUs := map[string]statparser.CPUStat{}
+	for _, cpu := range before.CPUStatList {
+		beforeCPUs[cpu.CPU] = cpu
+	}
+	beforeShares := cpuShares(before.CPUStatList, func(cpu *statparser.CPUStat) uint64 {
+		return cpu.CPUInPackets
+	})
+	afterShares := cpuShares(after.CPUStatList, func(cpu *statparser.CPUStat) uint64 {
+		// CPUs which appeared count from zero
+		return statfmt.CounterDelta(beforeCPUs[cpu.CPU].CPUInPackets, cpu.CPUInPackets)
+	})
+	for _, cpu := range after.CPUStatList {
+		entry := CPU{CPU: cpu.CPU, State: StateAdded, ShareAfter: afterShares[cpu.CPU]}
+		if prev, ok := beforeCPUs[cpu.CPU]; ok {
+			entry.State = StateCommon
+			entry.ShareBefore = beforeShares[cpu.CPU]
+			entry.Fields = r.fields(cpu.CPU+" ", reflect.ValueOf(prev), reflect.ValueOf(cpu))
+			delete(beforeCPUs, cpu.CPU)
+			if shareThreshold > 0 && math.Abs(entry.ShareAfter-entry.ShareBefore) >= shareThreshold {
+				entry.ShareShifted = true
+				r.Findings = append(r.Findings, fmt.Sprintf("%s share of packets moved from %.1f%% to %.1f%%",
+					cpu.CPU, entry.ShareBefore, entry.ShareAfter))
+			}
+		} else {
+			entry.Fields = r.fields("", reflect.Value{}, reflect.ValueOf(cpu))
+			r.Findings = append(r.Findings, cpu.CPU+" appeared")
+		}
+		r.CPUs = append(r.CPUs, entry)
+	}
+	for _, cpu := range before.CPUStatList {
+		if _, ok := beforeCPUs[cpu.CPU]; !ok {
+			continue
+		}
+		r.CPUs = append(r.CPUs, CPU{
+			CPU: cpu.CPU, State: StateRemoved, ShareBefore: beforeShares[cpu.CPU],
+			Fields: r.fields("", reflect.ValueOf(cpu), reflect.Value{}),
+		})
+		r.Findings = append(r.Findings, cpu.CPU+" disappeared")
+	}
+}
+
+func (r *Result) diffSockets(before, after *statparser.Statistics) {
+	beforeSockets := map[string]statparser.NFSockEntry{}
+	for _, socket := range before.SockStatList {
+		beforeSockets[socketKey(&socket)] = socket
+	}
+	for _, socket := range after.SockStatList {
+		entry := Socket{Name: socket.SockName, Destination: socket.SockDestination, State: StateAdded}
+		if prev, ok := beforeSockets[socketKey(&socket)]; ok {
+			entry.State = StateCommon
+			entry.Fields = r.fields(socketKey(&socket)+" ", reflect.ValueOf(prev), reflect.ValueOf(socket))
+			delete(beforeSockets, socketKey(&socket))
+		} else {
+			entry.Fields = r.fields("", reflect.Value{}, reflect.ValueOf(socket))
+			r.Findings = append(r.Findings, socketKey(&socket)+" appeared")
+		}
+		r.Sockets = append(r.Sockets, entry)
+	}
+	for _, socket := range before.SockStatList {
+		if _, ok := beforeSockets[socketKey(&socket)]; !ok {
+			continue
+		}
+		r.Sockets = append(r.Sockets, Socket{
+			Name: socket.SockName, Destination: socket.SockDestination, State: StateRemoved,
+			Fields: r.fields("", reflect.ValueOf(socket), reflect.Value{}),
+		})
+		r.Findings = append(r.Findings, socketKey(&socket)+" disappeared")
+	}
+}
+
+func socketKey(socket *statparser.NFSockEntry) string {
+	return socket.SockName + " " + socket.SockDestination
+}
+
+// cpuShares returns percents of packets of every CPU, or of the packet rate
+// when there are no packets.
+func cpuShares(cpus []statparser.CPUStat, packets func(cpu *statparser.CPUStat) uint64) map[string]float64 {
+	values := make(map[string]uint64, len(cpus))
+	var total uint64
+	for _, cpu := range cpus {
+		values[cpu.CPU] = packets(&cpu)
+		total += values[cpu.CPU]
+	}
+	if total == 0 {
+		for _, cpu := range cpus {
+			values[cpu.CPU] = cpu.CPUInPacketRate
+			total += cpu.CPUInPacketRate
+		}
+	}
+	shares := map[string]float64{}
+	if total == 0 {
+		return shares
+	}
+	for name, value := range values {
+		shares[name] = float64(value) * 100 / float64(total)
+	}
+
+	return shares
+}
+
+// fields returns changes of numeric fields of two structs of the same type,
+// a missing struct is the zero value. Counters which went backwards are
+// added to findings prefixed with findingPrefix.
+func (r *Result) fields(findingPrefix string, before, after reflect.Value) []Field {
+	structValue := after
+	if !structValue.IsValid() {
+		structValue = before
+	}
+	structType := structValue.Type()
+	fields := []Field{}
+	for index := range structType.NumField() {
+		kind := structType.Field(index).Type.Kind()
+		if kind != reflect.Uint64 && kind != reflect.Uint32 && kind != reflect.Float64 {
+			continue
+		}
+		name := structType.Field(index).Name
+		beforeValue, afterValue := numericField(before, index), numericField(after, index)
+		field := Field{Name: name, Before: beforeValue, After: afterValue, Delta: afterValue - beforeValue, Counter: statparser.IsCounter(name)}
+		if field.Counter && before.IsValid() && after.IsValid() {
+			switch {
+			case field.Delta < 0:
+				field.Backwards = true
+				r.Findings = append(r.Findings, fmt.Sprintf("%s%s went backwards from %s to %s",
+					findingPrefix, name, formatValue(beforeValue), formatValue(afterValue)))
+			case r.Interval > 0:
+				rate := field.Delta / r.Interval
+				field.Rate = &rate
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// numericField returns the value of a numeric field, zero for a missing
+// struct.
+func numericField(value reflect.Value, index int) float64 {
+	if !value.IsValid() {
+		return 0
+	}
+	field := value.Field(index)
+	if field.CanFloat() {
+		return field.Float()
+	}
+
+	return float64(field.Uint())
+}
+
+// WriteTable writes changed fields as a table followed by findings.
+func (r *Result) WriteTable(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch {
+	case r.IntervalFromModTime:
+		fmt.Fprintf(writer, "Interval: %s from file modification times, set --interval for copied captures\n",
+			time.Duration(r.Interval*float64(time.Second)))
+	case r.Interval > 0:
+		fmt.Fprintf(writer, "Interval: %s\n", time.Duration(r.Interval*float64(time.Second)))
+	default:
+		fmt.Fprintln(writer, "Interval: unknown, rates are not computed")
+	}
+	fmt.Fprintln(writer, "\nSCOPE\tFIELD\tBEFORE\tAFTER\tDELTA\tRATE/S\tNOTE")
+	writeFields(writer, "global", StateCommon, r.Common)
+	for _, cpu := range r.CPUs {
+		writeFields(writer, cpu.CPU, cpu.State, cpu.Fields)
+		if cpu.ShareShifted {
+			fmt.Fprintf(writer, "%s\tshare %%\t%.1f\t%.1f\t%.1f\t-\tshifted\n",
+				cpu.CPU, cpu.ShareBefore, cpu.ShareAfter, cpu.ShareAfter-cpu.ShareBefore)
+		}
+	}
+	for _, socket := range r.Sockets {
+		writeFields(writer, socket.Name+" "+socket.Destination, socket.State, socket.Fields)
+	}
+	if len(r.Findings) > 0 {
+		fmt.Fprintln(writer, "\nFindings:")
+		for _, finding := range r.Findings {
+			fmt.Fprintf(writer, "  %s\n", finding)
+		}
+	}
+
+	return writer.Flush()
+}
+
+// writeFields writes changed fields of a common entry, or one row for an
+// added or removed entry.
+func writeFields(w io.Writer, scope, state string, fields []Field) {
+	if state != StateCommon {
+		fmt.Fprintf(w, "%s\t-\t\t\t\t\t%s\n", scope, state)
+
+		return
+	}
+	for _, field := range fields {
+		if field.Delta == 0 {
+			continue
+		}
+		rate := "-"
+		if field.Rate != nil {
+			rate = strconv.FormatFloat(*field.Rate, 'f', 2, 64)
+		}
+		note := ""
+		if field.Backwards {
+			note = "backwards"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", scope, field.Name,
+			formatValue(field.Before), formatValue(field.After), formatValue(field.Delta), rate, note)
+	}
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,169 @@
+package statdiff
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/stretchr/testify/require"
+)
+
+func testStats() (statparser.Statistics, statparser.Statistics) {
+	before := statparser.Statistics{
+		InFlows:   100,
+		LostFlows: 10,
+		CPUStatList: []statparser.CPUStat{
+			{CPU: "cpu0", CPUInPacketRate: 80, CPUInPackets: 300},
+			{CPU: "cpu1", CPUInPacketRate: 20, CPUInPackets: 300},
+		},
+		SockStatList: []statparser.NFSockEntry{
+			{SockName: "sock0", SockDestination: "127.0.0.1:1234", SockActive: 1, SockErrFull: 3},
+			{SockName: "sock1", SockDestination: "127.0.0.1:5555", SockActive: 1},
+		},
+	}
+	after := statparser.Statistics{
+		InFlows:   400,
+		LostFlows: 4,
+		CPUStatList: []statparser.CPUStat{
+			{CPU: "cpu0", CPUInPacketRate: 52, CPUInPackets: 900},
+			{CPU: "cpu1", CPUInPacketRate: 48, CPUInPackets: 600},
+		},
+		SockStatList: []statparser.NFSockEntry{
+			{SockName: "sock0", SockDestination: "127.0.0.1:1234", SockActive: 0, SockErrFull: 9},
+			{SockName: "sock1", SockDestination: "127.0.0.1:6666", SockActive: 1},
+		},
+	}
+
+	return before, after
+}
+
+func findField(t *testing.T, fields []Field, name string) Field {
+	t.Helper()
+	for _, field := range fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	require.Failf(t, "field not found", "field %s", name)
+
+	return Field{}
+}
+
+func TestDiff(t *testing.T) {
+	before, after := testStats()
+	result := Diff(&before, &after, Options{Interval: 30 * time.Second, ShareThreshold: 10})
+	require.InDelta(t, 30, result.Interval, 0.001)
+
+	inFlows := findField(t, result.Common, "InFlows")
+	require.True(t, inFlows.Counter)
+	require.InDelta(t, 300, inFlows.Delta, 0.001)
+	require.NotNil(t, inFlows.Rate)
+	require.InDelta(t, 10, *inFlows.Rate, 0.001)
+
+	lostFlows := findField(t, result.Common, "LostFlows")
+	require.True(t, lostFlows.Backwards)
+	require.Nil(t, lostFlows.Rate)
+
+	inBitRate := findField(t, result.Common, "InBitRate")
+	require.False(t, inBitRate.Counter)
+	require.Nil(t, inBitRate.Rate)
+
+	require.Len(t, result.CPUs, 2)
+	require.Equal(t, StateCommon, result.CPUs[0].State)
+	require.True(t, result.CPUs[0].ShareShifted)
+	require.InDelta(t, 50, result.CPUs[0].ShareBefore, 0.001)
+	require.InDelta(t, 66.667, result.CPUs[0].ShareAfter, 0.001)
+	require.InDelta(t, 20, *findField(t, result.CPUs[0].Fields, "CPUInPackets").Rate, 0.001)
+
+	require.Len(t, result.Sockets, 3)
+	require.Equal(t, "sock0", result.Sockets[0].Name)
+	require.Equal(t, StateCommon, result.Sockets[0].State)
+	// socket state is not a counter
+	require.False(t, findField(t, result.Sockets[0].Fields, "SockActive").Backwards)
+	require.InDelta(t, 0.2, *findField(t, result.Sockets[0].Fields, "SockErrFull").Rate, 0.001)
+	require.Equal(t, StateAdded, result.Sockets[1].State)
+	require.Equal(t, "127.0.0.1:6666", result.Sockets[1].Destination)
+	require.Equal(t, StateRemoved, result.Sockets[2].State)
+	require.Equal(t, "127.0.0.1:5555", result.Sockets[2].Destination)
+
+	require.Equal(t, []string{
+		"LostFlows went backwards from 10 to 4",
+		"cpu0 share of packets moved from 50.0% to 66.7%",
+		"cpu1 share of packets moved from 50.0% to 33.3%",
+		"sock1 127.0.0.1:6666 appeared",
+		"sock1 127.0.0.1:5555 disappeared",
+	}, result.Findings)
+}
+
+func TestDiffWithoutInterval(t *testing.T) {
+	before, after := testStats()
+	result := Diff(&before, &after, Options{ShareThreshold: 50})
+	require.Nil(t, findField(t, result.Common, "InFlows").Rate)
+	require.False(t, result.CPUs[0].ShareShifted)
+	require.NotContains(t, result.Findings, "cpu0 share of packets moved from 50.0% to 66.7%")
+}
+
+func TestCPUSharesWithoutPackets(t *testing.T) {
+	before, after := testStats()
+	// no packets between reads, the packet rate is used
+	for index := range after.CPUStatList {
+		after.CPUStatList[index].CPUInPackets = before.CPUStatList[index].CPUInPackets
+	}
+	result := Diff(&before, &after, Options{ShareThreshold: 10})
+	require.InDelta(t, 50, result.CPUs[0].ShareBefore, 0.001)
+	require.InDelta(t, 52, result.CPUs[0].ShareAfter, 0.001)
+	require.False(t, result.CPUs[0].ShareShifted)
+
+	before.CPUStatList[1].CPUInPackets = 0
+	after.CPUStatList[1].CPUInPackets = 0
+	before.CPUStatList[0].CPUInPackets = 0
+	after.CPUStatList[0].CPUInPackets = 0
+	result = Diff(&before, &after, Options{ShareThreshold: 10})
+	require.InDelta(t, 80, result.CPUs[0].ShareBefore, 0.001)
+	require.True(t, result.CPUs[0].ShareShifted)
+}
+
+func TestCPUChanges(t *testing.T) {
+	before, after := testStats()
+	after.CPUStatList = append(after.CPUStatList[1:], statparser.CPUStat{CPU: "cpu2", CPUErrAlloc: 1})
+	after.CPUStatList[0].CPUInPackets = 200
+	result := Diff(&before, &after, Options{Interval: time.Second})
+	require.Len(t, result.CPUs, 3)
+	require.Equal(t, "cpu1", result.CPUs[0].CPU)
+	require.Equal(t, "cpu2", result.CPUs[1].CPU)
+	require.Equal(t, StateAdded, result.CPUs[1].State)
+	require.InDelta(t, 1, findField(t, result.CPUs[1].Fields, "CPUErrAlloc").After, 0.001)
+	require.Equal(t, "cpu0", result.CPUs[2].CPU)
+	require.Equal(t, StateRemoved, result.CPUs[2].State)
+	require.Contains(t, result.Findings, "cpu1 CPUInPackets went backwards from 300 to 200")
+	require.Contains(t, result.Findings, "cpu2 appeared")
+	require.Contains(t, result.Findings, "cpu0 disappeared")
+}
+
+func TestWriteTable(t *testing.T) {
+	before, after := testStats()
+	result := Diff(&before, &after, Options{Interval: 30 * time.Second, ShareThreshold: 10})
+	buf := &bytes.Buffer{}
+	require.NoError(t, result.WriteTable(buf))
+	table := buf.String()
+	require.Contains(t, table, "Interval: 30s")
+	require.Regexp(t, `global +InFlows +100 +400 +300 +10\.00`, table)
+	require.Regexp(t, `global +LostFlows +10 +4 +-6 +- +backwards`, table)
+	require.Regexp(t, `cpu0 +share % +50\.0 +66\.7 +16\.7 +- +shifted`, table)
+	require.Regexp(t, `sock1 127\.0\.0\.1:6666 +- +added`, table)
+	require.Regexp(t, `sock1 127\.0\.0\.1:5555 +- +removed`, table)
+	// unchanged fields are omitted
+	require.NotContains(t, table, "InBitRate")
+	require.Contains(t, table, "Findings:\n  LostFlows went backwards from 10 to 4\n")
+
+	result = Diff(&before, &after, Options{})
+	buf.Reset()
+	require.NoError(t, result.WriteTable(buf))
+	require.Contains(t, buf.String(), "Interval: unknown, rates are not computed")
+
+	result = Diff(&before, &after, Options{Interval: 30 * time.Second, IntervalFromModTime: true})
+	buf.Reset()
+	require.NoError(t, result.WriteTable(buf))
+	require.Contains(t, buf.String(), "Interval: 30s from file modification times, set --interval for copied captures\n")
+}
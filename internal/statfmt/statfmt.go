@@ -0,0 +1,96 @@
+// Package statfmt holds helpers shared by views of ipt_NETFLOW statistics:
+// counter rates, error sums, send buffer fill, name ordering and number
+// formatting.
+package statfmt
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// SndbufFillWarning is the send buffer fill in percent from which sockets
+// are highlighted, flows are lost when the buffer is full.
+const SndbufFillWarning = 90
+
+// CounterRate returns the per second rate of a counter, false when there is
+// no interval or the counter was reset, e.g. on module reload.
+func CounterRate(prev, current uint64, interval time.Duration) (float64, bool) {
+	if interval <= 0 || current < prev {
+		return 0, false
+	}
+
+	return float64(current-prev) / interval.Seconds(), true
+}
+
+// CounterDelta returns the growth of a counter, zero when it went backwards.
+func CounterDelta(prev, current uint64) uint64 {
+	if current < prev {
+		return 0
+	}
+
+	return current - prev
+}
+
+// CPUErrors returns the sum of error counters of a CPU.
+func CPUErrors(cpu *statparser.CPUStat) uint64 {
+	return cpu.CPUErrTrunc + cpu.CPUErrFrag + cpu.CPUErrAlloc + cpu.CPUErrMaxflows
+}
+
+// SocketErrors returns the sum of error counters of a socket, connection
+// errors included. Counters are summed as uint64, so that the sum of 32-bit
+// counters does not wrap.
+func SocketErrors(socket *statparser.NFSockEntry) uint64 {
+	return uint64(socket.SockErrConnect) + uint64(socket.SockErrFull) + uint64(socket.SockErrCberr) + uint64(socket.SockErrOther)
+}
+
+// SndbufFill returns the send buffer fill of a socket in percent, at most
+// 100 and zero when the buffer size is unknown.
+func SndbufFill(socket *statparser.NFSockEntry) float64 {
+	return sndbufPercent(socket.SockSndbufFill, socket.SockSndbuf)
+}
+
+// SndbufPeak returns the send buffer peak of a socket in percent, at most
+// 100 and zero when the buffer size is unknown.
+func SndbufPeak(socket *statparser.NFSockEntry) float64 {
+	return sndbufPercent(socket.SockSndbufPeak, socket.SockSndbuf)
+}
+
+func sndbufPercent(value, sndbuf uint32) float64 {
+	if sndbuf == 0 {
+		return 0
+	}
+
+	return min(100, float64(value)*100/float64(sndbuf))
+}
+
+// CompareNames orders names like cpu2 before cpu10.
+func CompareNames(a, b string) int {
+	trimmedA := strings.TrimRight(a, "0123456789")
+	trimmedB := strings.TrimRight(b, "0123456789")
+	if trimmedA == trimmedB && len(a) != len(b) {
+		return cmp.Compare(len(a), len(b))
+	}
+
+	return cmp.Compare(a, b)
+}
+
+// Humanize formats a value with SI prefix, e.g. 1.5k or 12.3M.
+func Humanize(value float64) string {
+	prefixes := []string{"", "k", "M", "G", "T", "P"}
+	index := 0
+	for math.Abs(value) >= 1000 && index < len(prefixes)-1 {
+		value /= 1000
+		index++
+	}
+	if index == 0 && value == math.Trunc(value) {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+
+	return fmt.Sprintf("%.2f%s", value, prefixes[index])
+}
@@ -0,0 +1,58 @@
+package statfmt
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterRate(t *testing.T) {
+	rate, ok := CounterRate(10, 30, 2*time.Second)
+	require.True(t, ok)
+	require.InDelta(t, 10, rate, 0)
+	_, ok = CounterRate(30, 10, time.Second)
+	require.False(t, ok)
+	_, ok = CounterRate(10, 30, 0)
+	require.False(t, ok)
+
+	require.Equal(t, uint64(20), CounterDelta(10, 30))
+	require.Zero(t, CounterDelta(30, 10))
+}
+
+func TestErrors(t *testing.T) {
+	require.Equal(t, uint64(10), CPUErrors(&statparser.CPUStat{CPUErrTrunc: 1, CPUErrFrag: 2, CPUErrAlloc: 3, CPUErrMaxflows: 4}))
+	socket := statparser.NFSockEntry{SockErrConnect: math.MaxUint32, SockErrFull: math.MaxUint32, SockErrCberr: 1, SockErrOther: 2}
+	require.Equal(t, uint64(2*math.MaxUint32+3), SocketErrors(&socket))
+}
+
+func TestSndbufFill(t *testing.T) {
+	require.Zero(t, SndbufFill(&statparser.NFSockEntry{SockSndbufFill: 10}))
+	require.InDelta(t, 25, SndbufFill(&statparser.NFSockEntry{SockSndbuf: 400, SockSndbufFill: 100}), 0)
+	// fill may exceed the buffer size, it is accounted by the kernel
+	require.InDelta(t, 100, SndbufFill(&statparser.NFSockEntry{SockSndbuf: 100, SockSndbufFill: 150}), 0)
+	require.InDelta(t, 50, SndbufPeak(&statparser.NFSockEntry{SockSndbuf: 400, SockSndbufPeak: 200}), 0)
+}
+
+func TestCompareNames(t *testing.T) {
+	require.Negative(t, CompareNames("cpu2", "cpu10"))
+	require.Positive(t, CompareNames("cpu10", "cpu1"))
+	require.Negative(t, CompareNames("cpu10", "sock0"))
+}
+
+func TestHumanize(t *testing.T) {
+	for value, expected := range map[float64]string{
+		0:        "0",
+		999:      "999",
+		0.5:      "0.50",
+		1.03:     "1.03",
+		1500:     "1.50k",
+		-1500:    "-1.50k",
+		2345678:  "2.35M",
+		12300000: "12.30M",
+	} {
+		require.Equal(t, expected, Humanize(value))
+	}
+}
@@ -24,6 +24,43 @@ type statType interface {
 	typeName() string
 }
 
+// counterFields are fields of Statistics, CPUStat and NFSockEntry which only
+// grow until the module is reloaded, the rest are gauges with current
+// values.
+var counterFields = map[string]bool{
+	"InFlows":        true,
+	"InPackets":      true,
+	"InBytes":        true,
+	"DropPackets":    true,
+	"DropBytes":      true,
+	"OutFlows":       true,
+	"OutPackets":     true,
+	"OutBytes":       true,
+	"LostFlows":      true,
+	"LostPackets":    true,
+	"LostBytes":      true,
+	"ErrTotal":       true,
+	"CPUInFlows":     true,
+	"CPUInPackets":   true,
+	"CPUInBytes":     true,
+	"CPUDropPackets": true,
+	"CPUuDropBytes":  true,
+	"CPUErrTrunc":    true,
+	"CPUErrFrag":     true,
+	"CPUErrAlloc":    true,
+	"CPUErrMaxflows": true,
+	"SockErrConnect": true,
+	"SockErrFull":    true,
+	"SockErrCberr":   true,
+	"SockErrOther":   true,
+}
+
+// IsCounter reports whether a field of Statistics, CPUStat or NFSockEntry is
+// a counter, so that its rate is meaningful.
+func IsCounter(field string) bool {
+	return counterFields[field]
+}
+
 // metric with names from stats files
 type Statistics struct {
 	InBitRate    uint64
@@ -2,17 +2,25 @@ package statparser
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
 )
 
 var readFile = os.ReadFile
 
+// maxRecentErrors is the number of parse errors kept for the status page.
+const maxRecentErrors = 10
+
 var (
 	isCPUStat    = regexp.MustCompile(`^cpu\d+$`).MatchString
 	isSocketStat = regexp.MustCompile(`^sock\d+$`).MatchString
@@ -21,6 +29,38 @@ var (
 type StatCollector struct {
 	filepath string
 	log      *logger.Logger
+	mu       sync.Mutex
+	// reading is the in-flight read of the stat file. Concurrent callers and
+	// callers which gave up on timeout share it, so a hung read of /proc holds
+	// one goroutine instead of one per scrape.
+	reading *statRead
+	last    *Snapshot
+	// errors are recent read and parse errors, oldest first.
+	errors []ParseError
+}
+
+// ParseError is a failed read or parse of the stat file. Line is zero for
+// errors of the whole file.
+type ParseError struct {
+	Time    time.Time `json:"time"`
+	Line    int       `json:"line,omitempty"`
+	Message string    `json:"message"`
+}
+
+type statRead struct {
+	done    chan struct{}
+	content []byte
+	err     error
+}
+
+// Snapshot is the result of the last completed read of the stat file, kept
+// for debugging.
+type Snapshot struct {
+	Time  time.Time  `json:"time"`
+	File  string     `json:"file"`
+	Raw   []byte     `json:"-"`
+	Stat  Statistics `json:"stat"`
+	Error string     `json:"error,omitempty"`
 }
 
 func New(statPath string) *StatCollector {
@@ -30,33 +70,121 @@ func New(statPath string) *StatCollector {
 	}
 }
 
-func readStatFile(filePath string) ([]string, error) {
+func splitLines(fileContent []byte) []string {
 	result := make([]string, 0, 30)
-	fileContent, err := readFile(filePath)
-	if err != nil {
-		return nil, err
-	}
 	for _, line := range bytes.Split(fileContent, []byte("\n")) {
 		result = append(result, string(line))
 	}
 
-	return result, nil
+	return result
 }
 
-func (s *StatCollector) CollectAndMarshal() (Statistics, error) {
-	file, err := readStatFile(s.filepath)
+// read returns content of the stat file or an error wrapping ctx.Err() when
+// ctx is done before the read finishes.
+func (s *StatCollector) read(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	read := s.reading
+	if read == nil {
+		read = &statRead{done: make(chan struct{})}
+		s.reading = read
+		go func() {
+			read.content, read.err = readFile(s.filepath)
+			s.mu.Lock()
+			s.reading = nil
+			s.mu.Unlock()
+			close(read.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-read.done:
+		return read.content, read.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("error read stat file %s: %w", s.filepath, ctx.Err())
+	}
+}
+
+// CollectAndMarshal reads and parses the stat file. It returns when ctx is
+// done even if the read hangs.
+func (s *StatCollector) CollectAndMarshal(ctx context.Context) (Statistics, error) {
+	start := time.Now()
+	content, err := s.read(ctx)
 	if err != nil {
+		if ctx.Err() == nil {
+			s.store(start, content, Statistics{}, err)
+		}
+
 		return Statistics{}, err
 	}
+	stat, err := s.parseFields(splitLines(content))
+	s.store(start, content, stat, err)
+	if err != nil {
+		return stat, err
+	}
+	s.log.Debug("stat file parsed",
+		slog.String(logger.FileKey, s.filepath),
+		slog.Int("sockets", len(stat.SockStatList)),
+		slog.Int("cpus", len(stat.CPUStatList)),
+		slog.Duration(logger.DurationKey, time.Since(start)),
+	)
 
-	return s.parseFields(file)
+	return stat, nil
+}
+
+// Parse parses stat file content read elsewhere, e.g. from an archive of
+// captures. Errors are logged with the file name of the collector.
+func (s *StatCollector) Parse(content []byte) (Statistics, error) {
+	return s.parseFields(splitLines(content))
+}
+
+// LastSnapshot returns the result of the last completed read, false when the
+// stat file was not read yet.
+func (s *StatCollector) LastSnapshot() (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return Snapshot{}, false
+	}
+
+	return *s.last, true
+}
+
+func (s *StatCollector) store(start time.Time, content []byte, stat Statistics, err error) {
+	snapshot := &Snapshot{Time: start, File: s.filepath, Raw: content, Stat: stat}
+	if err != nil {
+		snapshot.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.last = snapshot
+	s.mu.Unlock()
+	if err != nil {
+		s.recordError(0, err)
+	}
+}
+
+// RecentErrors returns the last read and parse errors, oldest first.
+func (s *StatCollector) RecentErrors() []ParseError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.errors)
+}
+
+func (s *StatCollector) recordError(lineNum int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errors) == maxRecentErrors {
+		s.errors = slices.Delete(s.errors, 0, 1)
+	}
+	s.errors = append(s.errors, ParseError{Time: time.Now(), Line: lineNum, Message: err.Error()})
 }
 
 func (s *StatCollector) parseFields(fileLines []string) (Statistics, error) {
 	resultStruct := Statistics{}
-	for _, line := range fileLines {
+	for index, line := range fileLines {
 		if splitLine := strings.Fields(line); len(splitLine) > 0 {
-			if err := s.parseStatLine(&resultStruct, splitLine); err != nil {
+			if err := s.parseStatLine(&resultStruct, splitLine, index+1); err != nil {
 				return resultStruct, err
 			}
 		}
@@ -65,16 +193,16 @@ func (s *StatCollector) parseFields(fileLines []string) (Statistics, error) {
 	return resultStruct, nil
 }
 
-func (s *StatCollector) parseStatLine(statStruct *Statistics, splitLine []string) error {
+func (s *StatCollector) parseStatLine(statStruct *Statistics, splitLine []string, lineNum int) error {
 	if isCPUStat(splitLine[0]) {
-		if res := s.parseCPUFields(splitLine); res != nil {
+		if res := s.parseCPUFields(splitLine, lineNum); res != nil {
 			statStruct.CPUStatList = append(statStruct.CPUStatList, *res)
 		}
 		// do not return errors for specific metrics
 		return nil
 	}
 	if isSocketStat(splitLine[0]) {
-		if res := s.parseSocketFields(splitLine); res != nil {
+		if res := s.parseSocketFields(splitLine, lineNum); res != nil {
 			statStruct.SockStatList = append(statStruct.SockStatList, *res)
 		}
 		// do not return errors for specific metrics
@@ -83,7 +211,11 @@ func (s *StatCollector) parseStatLine(statStruct *Statistics, splitLine []string
 
 	if err := setValueByName(statStruct, splitLine[0], splitLine[1]); err != nil {
 		if errors.Is(errNotFoundField, err) {
-			s.log.Debugf("found unsupported metrics in ipt_NETFLOW stat file: metric %s", splitLine[0])
+			s.log.Debug("found unsupported metric in ipt_NETFLOW stat file",
+				slog.String(logger.FileKey, s.filepath),
+				slog.Int(logger.LineKey, lineNum),
+				slog.String("metric", splitLine[0]),
+			)
 		} else {
 			return err
 		}
@@ -92,10 +224,15 @@ func (s *StatCollector) parseStatLine(statStruct *Statistics, splitLine []string
 	return nil
 }
 
-func (s *StatCollector) parseCPUFields(cpuFields []string) *CPUStat {
+func (s *StatCollector) parseCPUFields(cpuFields []string, lineNum int) *CPUStat {
 	result := CPUStat{}
 	if err := setValues(&result, cpuFields); err != nil {
-		s.log.Errorf("%s", err.Error())
+		s.log.ErrorErr("error parse cpu stat", err,
+			slog.String(logger.FileKey, s.filepath),
+			slog.Int(logger.LineKey, lineNum),
+			slog.String(logger.CPUKey, cpuFields[0]),
+		)
+		s.recordError(lineNum, fmt.Errorf("error parse %s stat: %w", cpuFields[0], err))
 
 		return nil
 	}
@@ -103,10 +240,15 @@ func (s *StatCollector) parseCPUFields(cpuFields []string) *CPUStat {
 	return &result
 }
 
-func (s *StatCollector) parseSocketFields(cpuFields []string) *NFSockEntry {
+func (s *StatCollector) parseSocketFields(sockFields []string, lineNum int) *NFSockEntry {
 	result := NFSockEntry{}
-	if err := setValues(&result, cpuFields); err != nil {
-		s.log.Errorf("%s", err.Error())
+	if err := setValues(&result, sockFields); err != nil {
+		s.log.ErrorErr("error parse socket stat", err,
+			slog.String(logger.FileKey, s.filepath),
+			slog.Int(logger.LineKey, lineNum),
+			slog.String(logger.SocketKey, sockFields[0]),
+		)
+		s.recordError(lineNum, fmt.Errorf("error parse %s stat: %w", sockFields[0], err))
 
 		return nil
 	}
@@ -1,11 +1,16 @@
 package statparser
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/mythvcode/ipt-netflow-exporter/internal/config"
 	"github.com/mythvcode/ipt-netflow-exporter/internal/logger"
 	"github.com/stretchr/testify/require"
 )
@@ -126,7 +131,7 @@ func testDefaults(t *testing.T, stat Statistics) {
 func TestReadStatistics(t *testing.T) {
 	setReadFileFunc(t, fileContent, nil)
 	statCollector := New("test_path")
-	stat, err := statCollector.CollectAndMarshal()
+	stat, err := statCollector.CollectAndMarshal(context.Background())
 	require.NoError(t, err)
 	testDefaults(t, stat)
 }
@@ -134,7 +139,7 @@ func TestReadStatistics(t *testing.T) {
 func TestReadFileError(t *testing.T) {
 	setReadFileFunc(t, fileContent, errors.New("test_error"))
 	statCollector := New("test_path")
-	_, err := statCollector.CollectAndMarshal()
+	_, err := statCollector.CollectAndMarshal(context.Background())
 	require.Error(t, err)
 	require.Equal(t, "test_error", err.Error())
 }
@@ -143,7 +148,7 @@ func TestParseError(t *testing.T) {
 	metrics := "inBitRate    1.2"
 	setReadFileFunc(t, metrics, nil)
 	statCollector := New("test_path")
-	_, err := statCollector.CollectAndMarshal()
+	_, err := statCollector.CollectAndMarshal(context.Background())
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "strconv.ParseUint:")
 }
@@ -152,7 +157,7 @@ func TestParseFloatError(t *testing.T) {
 	metrics := "hashMetric   test"
 	setReadFileFunc(t, metrics, nil)
 	statCollector := New("test_path")
-	_, err := statCollector.CollectAndMarshal()
+	_, err := statCollector.CollectAndMarshal(context.Background())
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "strconv.ParseFloat")
 }
@@ -161,12 +166,30 @@ func TestParseErrorCpuMetricCount(t *testing.T) {
 	metrics := "cpu0 1 2 3 4 1.35 5 6 7\ncpu1 1 2 3 4 1.35 5 6 7 8 9 10"
 	setReadFileFunc(t, metrics, nil)
 	statCollector := New("test_path")
-	stat, err := statCollector.CollectAndMarshal()
+	stat, err := statCollector.CollectAndMarshal(context.Background())
 	require.NoError(t, err)
 	require.Len(t, stat.CPUStatList, 1)
 	testCPUStat(t, 1, stat.CPUStatList[0])
 }
 
+func TestParseErrorLogFields(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "exporter.log")
+	require.NoError(t, logger.Init(config.Logger{File: logFile, Level: "debug", Format: "json"}))
+	t.Cleanup(logger.SetDefaultDiscardLogger)
+	setReadFileFunc(t, "inFlows 3\ncpu0 1 2\nsock0 127.0.0.1:1234 1\nnewMetric 1", nil)
+	_, err := New("test_path").CollectAndMarshal(context.Background())
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	log := string(content)
+	require.Contains(t, log, `"msg":"error parse cpu stat","component":"StatCollector","error":"error parse fields count for cpu stat: must be 12, actual 3","file":"test_path","line":2,"cpu":"cpu0"`)
+	require.Contains(t, log, `"msg":"error parse socket stat","component":"StatCollector","error":`)
+	require.Contains(t, log, `"file":"test_path","line":3,"socket":"sock0"`)
+	require.Contains(t, log, `"msg":"found unsupported metric in ipt_NETFLOW stat file","component":"StatCollector","file":"test_path","line":4,"metric":"newMetric"`)
+	require.Contains(t, log, `"msg":"stat file parsed","component":"StatCollector","file":"test_path","sockets":0,"cpus":0,"duration":`)
+}
+
 func TestParseUint32(t *testing.T) {
 	_, err := getValueByType(reflect.ValueOf(uint32(0)), "5000000000")
 	require.Error(t, err)
@@ -184,3 +207,100 @@ func TestUnsupportedMetric(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, errNotFoundField)
 }
+
+func TestReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	prev := readFile
+	readFile = func(string) ([]byte, error) {
+		<-release
+
+		return []byte(fileContent), nil
+	}
+	t.Cleanup(func() { readFile = prev })
+	statCollector := New("test_path")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := statCollector.CollectAndMarshal(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	// hung read is shared by the next call
+	reading := statCollector.reading
+	require.NotNil(t, reading)
+
+	close(release)
+	stat, err := statCollector.CollectAndMarshal(context.Background())
+	require.NoError(t, err)
+	testDefaults(t, stat)
+	<-reading.done
+}
+
+func TestLastSnapshot(t *testing.T) {
+	setReadFileFunc(t, fileContent, nil)
+	statCollector := New("test_path")
+	_, ok := statCollector.LastSnapshot()
+	require.False(t, ok)
+
+	stat, err := statCollector.CollectAndMarshal(context.Background())
+	require.NoError(t, err)
+	snapshot, ok := statCollector.LastSnapshot()
+	require.True(t, ok)
+	require.Equal(t, "test_path", snapshot.File)
+	require.Equal(t, fileContent, string(snapshot.Raw))
+	require.Equal(t, stat, snapshot.Stat)
+	require.Empty(t, snapshot.Error)
+
+	setReadFileFunc(t, "", errors.New("test_error"))
+	_, err = statCollector.CollectAndMarshal(context.Background())
+	require.Error(t, err)
+	snapshot, ok = statCollector.LastSnapshot()
+	require.True(t, ok)
+	require.Equal(t, "test_error", snapshot.Error)
+}
+
+func TestRecentErrors(t *testing.T) {
+	setReadFileFunc(t, "inFlows 3\ncpu0 1 2\nsock0 127.0.0.1:1234 1", nil)
+	statCollector := New("test_path")
+	_, err := statCollector.CollectAndMarshal(context.Background())
+	require.NoError(t, err)
+	errs := statCollector.RecentErrors()
+	require.Len(t, errs, 2)
+	require.Equal(t, 2, errs[0].Line)
+	require.Equal(t, "error parse cpu0 stat: error parse fields count for cpu stat: must be 12, actual 3", errs[0].Message)
+	require.Equal(t, 3, errs[1].Line)
+
+	setReadFileFunc(t, "", errors.New("test_error"))
+	for range maxRecentErrors {
+		_, err = statCollector.CollectAndMarshal(context.Background())
+		require.Error(t, err)
+	}
+	errs = statCollector.RecentErrors()
+	require.Len(t, errs, maxRecentErrors)
+	require.Equal(t, ParseError{Time: errs[0].Time, Message: "test_error"}, errs[0])
+}
+
+func TestParse(t *testing.T) {
+	stat, err := New("test_path").Parse([]byte(fileContent))
+	require.NoError(t, err)
+	testDefaults(t, stat)
+
+	_, err = New("test_path").Parse([]byte("inBitRate    1.2"))
+	require.Error(t, err)
+}
+
+func TestIsCounter(t *testing.T) {
+	fields := map[string]bool{}
+	for _, value := range []any{Statistics{}, CPUStat{}, NFSockEntry{}} {
+		structType := reflect.TypeOf(value)
+		for index := range structType.NumField() {
+			fields[structType.Field(index).Name] = true
+		}
+	}
+	for field := range counterFields {
+		require.True(t, fields[field], "unknown counter field %s", field)
+	}
+	require.True(t, IsCounter("LostFlows"))
+	require.True(t, IsCounter("CPUInPackets"))
+	require.False(t, IsCounter("CPUInPacketRate"))
+	require.False(t, IsCounter("SockActive"))
+	require.False(t, IsCounter("SockSndbufFill"))
+}
@@ -0,0 +1,342 @@
+// Package top renders a refreshing terminal view of ipt_NETFLOW statistics
+// with rates computed between reads.
+package top
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statfmt"
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[31m"
+	colorDim   = "\033[2m"
+)
+
+// CPU sort orders, switched with the c key.
+const (
+	SortCPUByLoad = iota
+	SortCPUByDrops
+	SortCPUByName
+	cpuSortCount
+)
+
+// Socket sort orders, switched with the s key.
+const (
+	SortSocketByName = iota
+	SortSocketByErrors
+	SortSocketByFill
+	socketSortCount
+)
+
+var (
+	cpuSortNames    = []string{"load", "drops", "name"}
+	socketSortNames = []string{"name", "errors", "fill"}
+)
+
+// Sample is one read of the stat file.
+type Sample struct {
+	Time time.Time
+	Stat statparser.Statistics
+}
+
+// View keeps the last two samples and view settings changed by keys.
+// FrameStart is written before every frame, e.g. to clear the screen.
+type View struct {
+	File       string
+	Interval   time.Duration
+	Color      bool
+	FrameStart string
+	CPUSort    int
+	SocketSort int
+	Paused     bool
+	prev       *Sample
+	current    *Sample
+	err        error
+}
+
+type cpuRow struct {
+	statparser.CPUStat
+	packets float64
+	drops   float64
+	errors  float64
+	hasRate bool
+}
+
+type socketRow struct {
+	statparser.NFSockEntry
+	errors     uint64
+	errorsRate float64
+	fill       float64
+	hasRate    bool
+}
+
+// Update adds a sample, rates are computed against the previous one.
+func (v *View) Update(sample Sample) {
+	v.prev, v.current = v.current, &sample
+	v.err = nil
+}
+
+// SetError shows a failed read, the last sample stays on screen.
+func (v *View) SetError(err error) {
+	v.err = err
+}
+
+// HandleKey applies a key press and returns true when the view should quit.
+func (v *View) HandleKey(key byte) bool {
+	switch key {
+	case 'q', 'Q', 3: // Ctrl+C in raw mode
+		return true
+	case 'p', ' ':
+		v.Paused = !v.Paused
+	case 'c':
+		v.CPUSort = (v.CPUSort + 1) % cpuSortCount
+	case 's':
+		v.SocketSort = (v.SocketSort + 1) % socketSortCount
+	}
+
+	return false
+}
+
+// Render writes one frame of the view.
+func (v *View) Render(w io.Writer) error {
+	buf := bytes.NewBufferString(v.FrameStart)
+	state := "running"
+	if v.Paused {
+		state = v.paint(colorBold, "paused")
+	}
+	fmt.Fprintf(buf, "%s - ipt_NETFLOW %s, every %s, %s\n", time.Now().Format(time.TimeOnly), v.File, v.Interval, state)
+	fmt.Fprintf(buf, "%s\n", v.paint(colorDim, fmt.Sprintf("keys: q quit, p pause, c sort cpus (%s), s sort sockets (%s)",
+		cpuSortNames[v.CPUSort], socketSortNames[v.SocketSort])))
+	if v.err != nil {
+		fmt.Fprintf(buf, "%s\n", v.paint(colorRed, "Error read stat file: "+v.err.Error()))
+	}
+	if v.current == nil {
+		_, err := w.Write(buf.Bytes())
+
+		return err
+	}
+	buf.WriteByte('\n')
+	v.renderGlobal(buf)
+	buf.WriteByte('\n')
+	v.renderCPUs(buf)
+	buf.WriteByte('\n')
+	v.renderSockets(buf)
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+func (v *View) renderGlobal(buf *bytes.Buffer) {
+	stat := &v.current.Stat
+	fmt.Fprintf(buf, "In:   %s bit/s, %s pkt/s, flows %s/s, packets %s/s, bytes %s/s\n",
+		statfmt.Humanize(float64(stat.InBitRate)), statfmt.Humanize(float64(stat.InPacketRate)),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.InFlows }, false),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.InPackets }, false),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.InBytes }, false))
+	fmt.Fprintf(buf, "Out:  %s B/s, flows %s/s, packets %s/s, bytes %s/s\n",
+		statfmt.Humanize(float64(stat.OutByteRate)),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.OutFlows }, false),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.OutPackets }, false),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.OutBytes }, false))
+	fmt.Fprintf(buf, "Loss: flows %s/s, packets %s/s, drops %s pkt/s, errors %s/s\n",
+		v.rate(func(s *statparser.Statistics) uint64 { return s.LostFlows }, true),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.LostPackets }, true),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.DropPackets }, true),
+		v.rate(func(s *statparser.Statistics) uint64 { return s.ErrTotal }, true))
+	fmt.Fprintf(buf, "Hash: metric %.2f, memory %sB, flows %s, sndbuf peak %s\n",
+		stat.HashMetric, statfmt.Humanize(float64(stat.HashMemory)), statfmt.Humanize(float64(stat.HashFlows)), statfmt.Humanize(float64(stat.SndbufPeak)))
+}
+
+// rate formats the per second rate of a counter, warning rates above zero
+// are highlighted.
+func (v *View) rate(value func(stat *statparser.Statistics) uint64, warning bool) string {
+	if v.prev == nil {
+		return "-"
+	}
+	rate, ok := statfmt.CounterRate(value(&v.prev.Stat), value(&v.current.Stat), v.current.Time.Sub(v.prev.Time))
+	if !ok {
+		return "-"
+	}
+	if warning && rate > 0 {
+		return v.paint(colorRed, statfmt.Humanize(rate))
+	}
+
+	return statfmt.Humanize(rate)
+}
+
+func (v *View) renderCPUs(buf *bytes.Buffer) {
+	rows := [][]cell{{{text: "CPU"}, {text: "PKT RATE"}, {text: "PACKETS/S"}, {text: "DROPS/S"}, {text: "ERRORS/S"}, {text: "HASH METRIC"}}}
+	for _, row := range v.cpuRows() {
+		packets, drops, errors := cell{text: "-"}, cell{text: "-"}, cell{text: "-"}
+		if row.hasRate {
+			packets, drops, errors = cell{text: statfmt.Humanize(row.packets)}, warningCell(row.drops), warningCell(row.errors)
+		}
+		rows = append(rows, []cell{
+			{text: row.CPU}, {text: statfmt.Humanize(float64(row.CPUInPacketRate))}, packets, drops, errors,
+			{text: fmt.Sprintf("%.2f", row.CPUHashMetric)},
+		})
+	}
+	v.renderTable(buf, rows)
+}
+
+func (v *View) cpuRows() []cpuRow {
+	prevCPUs := map[string]statparser.CPUStat{}
+	if v.prev != nil {
+		for _, cpu := range v.prev.Stat.CPUStatList {
+			prevCPUs[cpu.CPU] = cpu
+		}
+	}
+	interval := v.interval()
+	rows := make([]cpuRow, 0, len(v.current.Stat.CPUStatList))
+	for _, cpu := range v.current.Stat.CPUStatList {
+		row := cpuRow{CPUStat: cpu}
+		if prev, ok := prevCPUs[cpu.CPU]; ok {
+			var okPackets, okDrops, okErrors bool
+			row.packets, okPackets = statfmt.CounterRate(prev.CPUInPackets, cpu.CPUInPackets, interval)
+			row.drops, okDrops = statfmt.CounterRate(prev.CPUDropPackets, cpu.CPUDropPackets, interval)
+			row.errors, okErrors = statfmt.CounterRate(statfmt.CPUErrors(&prev), statfmt.CPUErrors(&cpu), interval)
+			row.hasRate = okPackets && okDrops && okErrors
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b cpuRow) int {
+		switch v.CPUSort {
+		case SortCPUByDrops:
+			return cmp.Or(cmp.Compare(b.drops, a.drops), cmp.Compare(b.CPUDropPackets, a.CPUDropPackets))
+		case SortCPUByName:
+			return statfmt.CompareNames(a.CPU, b.CPU)
+		default:
+			return cmp.Or(cmp.Compare(b.CPUInPacketRate, a.CPUInPacketRate), cmp.Compare(b.packets, a.packets))
+		}
+	})
+
+	return rows
+}
+
+func (v *View) renderSockets(buf *bytes.Buffer) {
+	rows := [][]cell{{{text: "SOCKET"}, {text: "DESTINATION"}, {text: "STATE"}, {text: "ERRORS"}, {text: "ERRORS/S"}, {text: "FILL"}, {text: "PEAK"}}}
+	for _, row := range v.socketRows() {
+		state := cell{text: "active"}
+		if row.SockActive == 0 {
+			state = cell{text: "inactive", color: colorRed}
+		}
+		errorsRate := cell{text: "-"}
+		if row.hasRate {
+			errorsRate = warningCell(row.errorsRate)
+		}
+		fill := cell{text: fmt.Sprintf("%.0f%%", row.fill)}
+		if row.fill >= statfmt.SndbufFillWarning {
+			fill.color = colorRed
+		}
+		rows = append(rows, []cell{
+			{text: row.SockName}, {text: row.SockDestination}, state, {text: strconv.FormatUint(row.errors, 10)},
+			errorsRate, fill, {text: strconv.FormatUint(uint64(row.SockSndbufPeak), 10)},
+		})
+	}
+	v.renderTable(buf, rows)
+}
+
+func (v *View) socketRows() []socketRow {
+	prevSockets := map[string]statparser.NFSockEntry{}
+	if v.prev != nil {
+		for _, socket := range v.prev.Stat.SockStatList {
+			prevSockets[socket.SockName+" "+socket.SockDestination] = socket
+		}
+	}
+	rows := make([]socketRow, 0, len(v.current.Stat.SockStatList))
+	for _, socket := range v.current.Stat.SockStatList {
+		row := socketRow{NFSockEntry: socket, errors: statfmt.SocketErrors(&socket)}
+		row.fill = statfmt.SndbufFill(&socket)
+		if prev, ok := prevSockets[socket.SockName+" "+socket.SockDestination]; ok {
+			row.errorsRate, row.hasRate = statfmt.CounterRate(statfmt.SocketErrors(&prev), row.errors, v.interval())
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b socketRow) int {
+		switch v.SocketSort {
+		case SortSocketByErrors:
+			return cmp.Or(cmp.Compare(b.errorsRate, a.errorsRate), cmp.Compare(b.errors, a.errors))
+		case SortSocketByFill:
+			return cmp.Compare(b.fill, a.fill)
+		default:
+			return statfmt.CompareNames(a.SockName, b.SockName)
+		}
+	})
+
+	return rows
+}
+
+func (v *View) interval() time.Duration {
+	if v.prev == nil {
+		return 0
+	}
+
+	return v.current.Time.Sub(v.prev.Time)
+}
+
+// cell is a table cell, painted after padding so that colors do not break
+// column alignment.
+type cell struct {
+	text  string
+	color string
+}
+
+// warningCell formats an error rate, highlighted when above zero.
+func warningCell(rate float64) cell {
+	if rate > 0 {
+		return cell{text: statfmt.Humanize(rate), color: colorRed}
+	}
+
+	return cell{text: statfmt.Humanize(rate)}
+}
+
+// renderTable writes rows with the first column aligned left and others
+// aligned right.
+func (v *View) renderTable(buf *bytes.Buffer, rows [][]cell) {
+	widths := []int{}
+	for _, row := range rows {
+		for index, column := range row {
+			if index == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[index] = max(widths[index], len(column.text))
+		}
+	}
+	for _, row := range rows {
+		for index, column := range row {
+			if index > 0 {
+				buf.WriteString("  ")
+			}
+			padding := strings.Repeat(" ", widths[index]-len(column.text))
+			text := column.text
+			if column.color != "" {
+				text = v.paint(column.color, text)
+			}
+			if index == 0 {
+				buf.WriteString(text + padding)
+			} else {
+				buf.WriteString(padding + text)
+			}
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+func (v *View) paint(color, text string) string {
+	if !v.Color {
+		return text
+	}
+
+	return color + text + colorReset
+}
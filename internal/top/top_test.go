@@ -0,0 +1,138 @@
+package top
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/stretchr/testify/require"
+)
+
+func testSamples() (Sample, Sample) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := Sample{Time: start, Stat: statparser.Statistics{
+		InPackets: 1000,
+		ErrTotal:  5,
+		CPUStatList: []statparser.CPUStat{
+			{CPU: "cpu2", CPUInPacketRate: 10, CPUInPackets: 100, CPUDropPackets: 0},
+			{CPU: "cpu10", CPUInPacketRate: 50, CPUInPackets: 500, CPUDropPackets: 1},
+		},
+		SockStatList: []statparser.NFSockEntry{
+			{SockName: "sock0", SockDestination: "127.0.0.1:2055", SockActive: 1, SockErrFull: 1, SockSndbuf: 100, SockSndbufFill: 10},
+			{SockName: "sock1", SockDestination: "127.0.0.1:2056", SockActive: 0, SockSndbuf: 100, SockSndbufFill: 95},
+		},
+	}}
+	current := Sample{Time: start.Add(2 * time.Second), Stat: statparser.Statistics{
+		InPackets: 3000,
+		ErrTotal:  5,
+		CPUStatList: []statparser.CPUStat{
+			{CPU: "cpu2", CPUInPacketRate: 10, CPUInPackets: 120, CPUDropPackets: 8},
+			{CPU: "cpu10", CPUInPacketRate: 50, CPUInPackets: 600, CPUDropPackets: 1},
+		},
+		SockStatList: []statparser.NFSockEntry{
+			{SockName: "sock0", SockDestination: "127.0.0.1:2055", SockActive: 1, SockErrFull: 5, SockSndbuf: 100, SockSndbufFill: 10},
+			{SockName: "sock1", SockDestination: "127.0.0.1:2056", SockActive: 0, SockSndbuf: 100, SockSndbufFill: 95},
+		},
+	}}
+
+	return prev, current
+}
+
+func render(t *testing.T, view *View) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	require.NoError(t, view.Render(buf))
+
+	return buf.String()
+}
+
+// rowOrder returns the order of names in the rendered frame.
+func rowOrder(frame string, names ...string) []string {
+	order := []string{}
+	for _, line := range strings.Split(frame, "\n") {
+		for _, name := range names {
+			if strings.HasPrefix(line, name+" ") {
+				order = append(order, name)
+			}
+		}
+	}
+
+	return order
+}
+
+func TestRenderRates(t *testing.T) {
+	prev, current := testSamples()
+	view := &View{File: "test_path", Interval: time.Second}
+	require.Contains(t, render(t, view), "test_path")
+
+	view.Update(prev)
+	frame := render(t, view)
+	require.Contains(t, frame, "packets -/s")
+
+	view.Update(current)
+	frame = render(t, view)
+	require.Contains(t, frame, "packets 1.00k/s")
+	require.Contains(t, frame, "errors 0/s")
+	require.Regexp(t, `cpu2 +10 +10 +4 +0 +0\.00`, frame)
+	require.Regexp(t, `sock0 +127\.0\.0\.1:2055 +active +5 +2 +10% +0`, frame)
+	require.Regexp(t, `sock1 +127\.0\.0\.1:2056 +inactive +0 +0 +95% +0`, frame)
+	require.NotContains(t, frame, "\033[")
+
+	view.Color = true
+	require.Contains(t, render(t, view), colorRed+"inactive"+colorReset)
+
+	view.FrameStart = "\033[H\033[2J"
+	require.True(t, strings.HasPrefix(render(t, view), view.FrameStart))
+}
+
+func TestRenderError(t *testing.T) {
+	prev, _ := testSamples()
+	view := &View{File: "test_path", Interval: time.Second}
+	view.Update(prev)
+	view.SetError(errors.New("test_error"))
+	frame := render(t, view)
+	require.Contains(t, frame, "Error read stat file: test_error")
+	require.Contains(t, frame, "sock0")
+}
+
+func TestCounterReset(t *testing.T) {
+	prev, current := testSamples()
+	prev.Stat.InPackets = 5000
+	view := &View{}
+	view.Update(prev)
+	view.Update(current)
+	require.Contains(t, render(t, view), "packets -/s")
+}
+
+func TestHandleKey(t *testing.T) {
+	prev, current := testSamples()
+	view := &View{}
+	view.Update(prev)
+	view.Update(current)
+	require.Equal(t, []string{"cpu10", "cpu2"}, rowOrder(render(t, view), "cpu2", "cpu10"))
+	require.Equal(t, []string{"sock0", "sock1"}, rowOrder(render(t, view), "sock0", "sock1"))
+
+	require.False(t, view.HandleKey('c'))
+	require.Equal(t, []string{"cpu2", "cpu10"}, rowOrder(render(t, view), "cpu2", "cpu10"))
+	require.False(t, view.HandleKey('c'))
+	require.Equal(t, SortCPUByName, view.CPUSort)
+	require.False(t, view.HandleKey('c'))
+	require.Equal(t, SortCPUByLoad, view.CPUSort)
+
+	require.False(t, view.HandleKey('s'))
+	require.Equal(t, []string{"sock0", "sock1"}, rowOrder(render(t, view), "sock0", "sock1"))
+	require.False(t, view.HandleKey('s'))
+	require.Equal(t, []string{"sock1", "sock0"}, rowOrder(render(t, view), "sock0", "sock1"))
+
+	require.False(t, view.HandleKey('p'))
+	require.True(t, view.Paused)
+	require.Contains(t, render(t, view), "paused")
+	require.False(t, view.HandleKey(' '))
+	require.False(t, view.Paused)
+
+	require.True(t, view.HandleKey('q'))
+	require.True(t, view.HandleKey(3))
+}
@@ -0,0 +1,245 @@
+// Package zabbix maps ipt_NETFLOW statistics to Zabbix items with low-level
+// discovery of CPUs and sockets and pushes them with the sender protocol.
+package zabbix
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+)
+
+// KeyPrefix starts keys of all items.
+const KeyPrefix = "ipt_netflow"
+
+// Discovery kinds and their keys.
+const (
+	DiscoveryCPU     = "cpu"
+	DiscoverySocket  = "socket"
+	cpuDiscoveryKey  = KeyPrefix + ".cpu.discovery"
+	sockDiscoveryKey = KeyPrefix + ".sock.discovery"
+)
+
+// itemField maps an item name to a field of statparser.Statistics, CPUStat
+// or NFSockEntry.
+type itemField struct {
+	name  string
+	field string
+}
+
+var (
+	globalItems = []itemField{
+		{"in_bit_rate", "InBitRate"},
+		{"in_packet_rate", "InPacketRate"},
+		{"in_flows", "InFlows"},
+		{"in_packets", "InPackets"},
+		{"in_bytes", "InBytes"},
+		{"hash_metric", "HashMetric"},
+		{"hash_memory", "HashMemory"},
+		{"hash_flows", "HashFlows"},
+		{"hash_packets", "HashPackets"},
+		{"hash_bytes", "HashBytes"},
+		{"drop_packets", "DropPackets"},
+		{"drop_bytes", "DropBytes"},
+		{"out_byte_rate", "OutByteRate"},
+		{"out_flows", "OutFlows"},
+		{"out_packets", "OutPackets"},
+		{"out_bytes", "OutBytes"},
+		{"lost_flows", "LostFlows"},
+		{"lost_packets", "LostPackets"},
+		{"lost_bytes", "LostBytes"},
+		{"err_total", "ErrTotal"},
+		{"sndbuf_peak", "SndbufPeak"},
+	}
+	cpuItems = []itemField{
+		{"in_packet_rate", "CPUInPacketRate"},
+		{"in_flows", "CPUInFlows"},
+		{"in_packets", "CPUInPackets"},
+		{"in_bytes", "CPUInBytes"},
+		{"hash_metric", "CPUHashMetric"},
+		{"drop_packets", "CPUDropPackets"},
+		{"drop_bytes", "CPUuDropBytes"},
+		{"err_trunc", "CPUErrTrunc"},
+		{"err_frag", "CPUErrFrag"},
+		{"err_alloc", "CPUErrAlloc"},
+		{"err_maxflows", "CPUErrMaxflows"},
+	}
+	sockItems = []itemField{
+		{"active", "SockActive"},
+		{"err_connect", "SockErrConnect"},
+		{"err_full", "SockErrFull"},
+		{"err_cberr", "SockErrCberr"},
+		{"err_other", "SockErrOther"},
+		{"sndbuf", "SockSndbuf"},
+		{"sndbuf_fill", "SockSndbufFill"},
+		{"sndbuf_peak", "SockSndbufPeak"},
+	}
+)
+
+// Item is a Zabbix item key with its value.
+type Item struct {
+	Key   string
+	Value string
+}
+
+// Items returns discovery data and values of all global, CPU and socket
+// items.
+func Items(stat *statparser.Statistics) []Item {
+	items := []Item{
+		{Key: cpuDiscoveryKey, Value: string(discoveryJSON(stat, DiscoveryCPU))},
+		{Key: sockDiscoveryKey, Value: string(discoveryJSON(stat, DiscoverySocket))},
+	}
+	for _, item := range globalItems {
+		items = append(items, Item{Key: KeyPrefix + "." + item.name, Value: fieldValue(reflect.ValueOf(*stat), item.field)})
+	}
+	for _, cpu := range stat.CPUStatList {
+		for _, item := range cpuItems {
+			items = append(items, Item{
+				Key:   fmt.Sprintf("%s.cpu.%s[%s]", KeyPrefix, item.name, cpu.CPU),
+				Value: fieldValue(reflect.ValueOf(cpu), item.field),
+			})
+		}
+	}
+	for _, socket := range stat.SockStatList {
+		for _, item := range sockItems {
+			items = append(items, Item{
+				Key:   fmt.Sprintf("%s.sock.%s[%s]", KeyPrefix, item.name, socket.SockName),
+				Value: fieldValue(reflect.ValueOf(socket), item.field),
+			})
+		}
+	}
+
+	return items
+}
+
+// Discovery returns low-level discovery JSON of CPUs with {#CPU} or sockets
+// with {#SOCKET} and {#DEST}.
+func Discovery(stat *statparser.Statistics, kind string) ([]byte, error) {
+	if kind != DiscoveryCPU && kind != DiscoverySocket {
+		return nil, fmt.Errorf("error unknown discovery %s: must be %s or %s", kind, DiscoveryCPU, DiscoverySocket)
+	}
+
+	return discoveryJSON(stat, kind), nil
+}
+
+func discoveryJSON(stat *statparser.Statistics, kind string) []byte {
+	entities := []map[string]string{}
+	if kind == DiscoveryCPU {
+		for _, cpu := range stat.CPUStatList {
+			entities = append(entities, map[string]string{"{#CPU}": cpu.CPU})
+		}
+	} else {
+		for _, socket := range stat.SockStatList {
+			entities = append(entities, map[string]string{"{#SOCKET}": socket.SockName, "{#DEST}": socket.SockDestination})
+		}
+	}
+	// maps of strings always marshal
+	data, _ := json.Marshal(map[string]any{"data": entities})
+
+	return data
+}
+
+// Lookup returns the value of an item key, e.g. ipt_netflow.in_flows,
+// ipt_netflow.cpu.drop_packets[cpu0], ipt_netflow.sock.err_full[sock0] or
+// ipt_netflow.sock.discovery.
+func Lookup(stat *statparser.Statistics, key string) (string, error) {
+	name, params, err := parseKey(key)
+	if err != nil {
+		return "", err
+	}
+	switch name {
+	case cpuDiscoveryKey:
+		return string(discoveryJSON(stat, DiscoveryCPU)), nil
+	case sockDiscoveryKey:
+		return string(discoveryJSON(stat, DiscoverySocket)), nil
+	}
+	itemName, ok := strings.CutPrefix(name, KeyPrefix+".")
+	if !ok {
+		return "", fmt.Errorf("error unknown key %s", key)
+	}
+	if cpuItem, ok := strings.CutPrefix(itemName, "cpu."); ok {
+		field, err := findField(cpuItems, cpuItem, key)
+		if err != nil {
+			return "", err
+		}
+		if len(params) != 1 {
+			return "", fmt.Errorf("error incorrect key %s: CPU is required", key)
+		}
+		for _, cpu := range stat.CPUStatList {
+			if cpu.CPU == params[0] {
+				return fieldValue(reflect.ValueOf(cpu), field), nil
+			}
+		}
+
+		return "", fmt.Errorf("error unknown CPU %s", params[0])
+	}
+	if sockItem, ok := strings.CutPrefix(itemName, "sock."); ok {
+		field, err := findField(sockItems, sockItem, key)
+		if err != nil {
+			return "", err
+		}
+		if len(params) != 1 && len(params) != 2 {
+			return "", fmt.Errorf("error incorrect key %s: socket and optional destination are required", key)
+		}
+		for _, socket := range stat.SockStatList {
+			if socket.SockName == params[0] && (len(params) == 1 || socket.SockDestination == params[1]) {
+				return fieldValue(reflect.ValueOf(socket), field), nil
+			}
+		}
+
+		return "", fmt.Errorf("error unknown socket %s", strings.Join(params, " "))
+	}
+	field, err := findField(globalItems, itemName, key)
+	if err != nil {
+		return "", err
+	}
+	if len(params) != 0 {
+		return "", fmt.Errorf("error incorrect key %s: no parameters expected", key)
+	}
+
+	return fieldValue(reflect.ValueOf(*stat), field), nil
+}
+
+func findField(items []itemField, name, key string) (string, error) {
+	for _, item := range items {
+		if item.name == name {
+			return item.field, nil
+		}
+	}
+
+	return "", fmt.Errorf("error unknown key %s", key)
+}
+
+// parseKey splits a key into name and parameters, parameters may be quoted.
+func parseKey(key string) (string, []string, error) {
+	name, rest, hasParams := strings.Cut(key, "[")
+	if !hasParams {
+		return key, nil, nil
+	}
+	rest, ok := strings.CutSuffix(rest, "]")
+	if !ok {
+		return "", nil, fmt.Errorf("error incorrect key %s: missing ]", key)
+	}
+	params := []string{}
+	for _, param := range strings.Split(rest, ",") {
+		param = strings.TrimSpace(param)
+		if unquoted, err := strconv.Unquote(param); err == nil {
+			param = unquoted
+		}
+		params = append(params, param)
+	}
+
+	return name, params, nil
+}
+
+func fieldValue(structValue reflect.Value, name string) string {
+	field := structValue.FieldByName(name)
+	if field.CanFloat() {
+		return strconv.FormatFloat(field.Float(), 'f', -1, 64)
+	}
+
+	return strconv.FormatUint(field.Uint(), 10)
+}
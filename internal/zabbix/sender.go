@@ -0,0 +1,123 @@
+package zabbix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultPort is the trapper port of Zabbix server and proxy.
+	DefaultPort = "10051"
+	// maxResponseSize limits responses read from the server.
+	maxResponseSize = 1 << 20
+	headerSize      = 13
+)
+
+// protocolHeader starts every message, followed by flags, the data length and
+// 4 reserved bytes.
+var protocolHeader = []byte("ZBXD")
+
+const protocolFlags = 0x01
+
+type senderData struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+}
+
+type senderRequest struct {
+	Request string       `json:"request"`
+	Data    []senderData `json:"data"`
+	Clock   int64        `json:"clock"`
+}
+
+// Response is the reply of the server to sent values.
+type Response struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+// Send pushes items of host to a Zabbix server or proxy at address with the
+// sender protocol. Values not matching a trapper item are counted as failed
+// in the response info, not as an error.
+func Send(ctx context.Context, address, host string, items []Item, clock time.Time) (Response, error) {
+	request := senderRequest{Request: "sender data", Data: make([]senderData, 0, len(items)), Clock: clock.Unix()}
+	for _, item := range items {
+		request.Data = append(request.Data, senderData{Host: host, Key: item.Key, Value: item.Value, Clock: clock.Unix()})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return Response{}, fmt.Errorf("error connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return Response{}, err
+		}
+	}
+	if _, err := conn.Write(encodeMessage(body)); err != nil {
+		return Response{}, fmt.Errorf("error send to %s: %w", address, err)
+	}
+	data, err := readMessage(conn)
+	if err != nil {
+		return Response{}, fmt.Errorf("error read response of %s: %w", address, err)
+	}
+	response := Response{}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return Response{}, fmt.Errorf("error parse response of %s: %w", address, err)
+	}
+	if response.Response != "success" {
+		return response, fmt.Errorf("error send to %s: response %s %s", address, response.Response, response.Info)
+	}
+
+	return response, nil
+}
+
+// encodeMessage frames data with the protocol header.
+func encodeMessage(data []byte) []byte {
+	message := make([]byte, headerSize, headerSize+len(data))
+	copy(message, protocolHeader)
+	message[4] = protocolFlags
+	binary.LittleEndian.PutUint64(message[5:headerSize], uint64(len(data)))
+
+	return append(message, data...)
+}
+
+// readMessage reads one framed message and returns its data.
+func readMessage(reader io.Reader) ([]byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], protocolHeader) {
+		return nil, errors.New("error incorrect protocol header")
+	}
+	// compressed and large packets are not sent by servers unless requested
+	if header[4] != protocolFlags {
+		return nil, fmt.Errorf("error unsupported protocol flags %#x", header[4])
+	}
+	size := binary.LittleEndian.Uint64(header[5:headerSize])
+	if size > maxResponseSize {
+		return nil, fmt.Errorf("error message of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
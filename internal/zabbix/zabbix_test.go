@@ -0,0 +1,166 @@
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mythvcode/ipt-netflow-exporter/internal/statparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStat() statparser.Statistics {
+	return statparser.Statistics{
+		InFlows:    100,
+		HashMetric: 1.25,
+		CPUStatList: []statparser.CPUStat{
+			{CPU: "cpu0", CPUDropPackets: 3},
+			{CPU: "cpu1", CPUDropPackets: 4},
+		},
+		SockStatList: []statparser.NFSockEntry{
+			{SockName: "sock0", SockDestination: "127.0.0.1:2055", SockActive: 1, SockErrFull: 7},
+		},
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	stat := testStat()
+	cpus, err := Discovery(&stat, DiscoveryCPU)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":[{"{#CPU}":"cpu0"},{"{#CPU}":"cpu1"}]}`, string(cpus))
+	sockets, err := Discovery(&stat, DiscoverySocket)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":[{"{#SOCKET}":"sock0","{#DEST}":"127.0.0.1:2055"}]}`, string(sockets))
+	empty, err := Discovery(&statparser.Statistics{}, DiscoverySocket)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":[]}`, string(empty))
+	_, err = Discovery(&stat, "disk")
+	require.Error(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	stat := testStat()
+	for key, expected := range map[string]string{
+		"ipt_netflow.in_flows":                               "100",
+		"ipt_netflow.hash_metric":                            "1.25",
+		"ipt_netflow.cpu.drop_packets[cpu1]":                 "4",
+		"ipt_netflow.sock.err_full[sock0]":                   "7",
+		`ipt_netflow.sock.active["sock0", "127.0.0.1:2055"]`: "1",
+	} {
+		value, err := Lookup(&stat, key)
+		require.NoError(t, err, key)
+		require.Equal(t, expected, value, key)
+	}
+	value, err := Lookup(&stat, "ipt_netflow.cpu.discovery")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":[{"{#CPU}":"cpu0"},{"{#CPU}":"cpu1"}]}`, value)
+	for _, key := range []string{
+		"ipt_netflow.unknown",
+		"other.in_flows",
+		"ipt_netflow.in_flows[cpu0]",
+		"ipt_netflow.cpu.drop_packets",
+		"ipt_netflow.cpu.drop_packets[cpu9]",
+		"ipt_netflow.sock.err_full[sock0,10.0.0.1:2055]",
+		"ipt_netflow.sock.err_full[sock0",
+	} {
+		_, err := Lookup(&stat, key)
+		require.Error(t, err, key)
+	}
+}
+
+func TestItems(t *testing.T) {
+	stat := testStat()
+	items := Items(&stat)
+	require.Len(t, items, 2+len(globalItems)+2*len(cpuItems)+len(sockItems))
+	for _, item := range items {
+		value, err := Lookup(&stat, item.Key)
+		require.NoError(t, err, item.Key)
+		require.Equal(t, value, item.Value, item.Key)
+	}
+}
+
+// serveTrapper accepts one sender connection, passes the request to check
+// and replies with response.
+func serveTrapper(t *testing.T, response string, check func(request senderRequest)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, err := readMessage(conn)
+		if !assert.NoError(t, err) {
+			return
+		}
+		request := senderRequest{}
+		assert.NoError(t, json.Unmarshal(data, &request))
+		check(request)
+		_, err = conn.Write(encodeMessage([]byte(response)))
+		assert.NoError(t, err)
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestSend(t *testing.T) {
+	stat := testStat()
+	items := Items(&stat)
+	clock := time.Unix(1760000000, 0)
+	received := make(chan senderRequest, 1)
+	address := serveTrapper(t, fmt.Sprintf(`{"response":"success","info":"processed: %d; failed: 0; total: %d"}`, len(items), len(items)),
+		func(request senderRequest) { received <- request })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := Send(ctx, address, "netflow1", items, clock)
+	require.NoError(t, err)
+	require.Equal(t, "success", response.Response)
+	require.Contains(t, response.Info, "failed: 0")
+
+	request := <-received
+	require.Equal(t, "sender data", request.Request)
+	require.Len(t, request.Data, len(items))
+	require.Contains(t, request.Data, senderData{Host: "netflow1", Key: "ipt_netflow.sock.err_full[sock0]", Value: "7", Clock: clock.Unix()})
+	require.Equal(t, clock.Unix(), request.Clock)
+}
+
+func TestSendFailed(t *testing.T) {
+	address := serveTrapper(t, `{"response":"failed","info":"host not found"}`, func(senderRequest) {})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := Send(ctx, address, "netflow1", []Item{{Key: "ipt_netflow.in_flows", Value: "1"}}, time.Now())
+	require.ErrorContains(t, err, "response failed host not found")
+}
+
+func TestReadMessage(t *testing.T) {
+	message := encodeMessage([]byte(`{"a":1}`))
+	require.Equal(t, []byte{'Z', 'B', 'X', 'D', 1, 7, 0, 0, 0, 0, 0, 0, 0}, message[:headerSize])
+	server, client := net.Pipe()
+	defer server.Close()
+	go func() {
+		defer client.Close()
+		_, _ = client.Write(message)
+	}()
+	data, err := readMessage(server)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a":1}`, string(data))
+
+	compressed := encodeMessage([]byte("x"))
+	compressed[4] = 0x03
+	server, client = net.Pipe()
+	defer server.Close()
+	go func() {
+		defer client.Close()
+		_, _ = client.Write(compressed)
+	}()
+	_, err = readMessage(server)
+	require.ErrorContains(t, err, "unsupported protocol flags")
+}